@@ -90,10 +90,14 @@ app.post('/registerRawMaterial', async (req, res) => {
         return;
     }
     try {
-        const { supplierID, name, quantity } = req.body;
+        const { supplierID, name, quantity, emissionFactor = 0, emissionSource = '', origin } = req.body;
         const { contract, gateway } = await connectToNetwork('org1', 1);
 
-        const result = await contract.submitTransaction('registerRawMaterial', supplierID, name, quantity);
+        // quantity는 단위를 포함한 문자열 (예: '12.5 kg', 단위는 g, kg, t)
+        // 배출 계수(kgCO2e/kg)와 출처는 선택 입력 (없으면 배출 계수 미신고 로트로 등록)
+        // origin: { country, mineOrRefineryID, certificationScheme, certificateRef, certificateHash } (선택 입력)
+        const originJSON = origin ? JSON.stringify(origin) : '';
+        const result = await contract.submitTransaction('RegisterRawMaterial', supplierID, name, quantity.toString(), emissionFactor.toString(), emissionSource, originJSON);
         await gateway.disconnect();
 
         res.status(200).json({ message: 'Raw material registered successfully', result: result.toString() });
@@ -203,7 +207,7 @@ app.get('/queryExtractedMaterial/:materialID', async (req, res) => {
 
 // 배터리 생성 API (org2만 호출 가능)
 app.post('/createBattery', async (req, res) => {
-    const { rawMaterialsJSON, weight, capacity, voltage, category, totalLifeCycle } = req.body;
+    const { rawMaterialsJSON, weight, capacity, voltage, category, totalLifeCycle, manufacturingEnergy } = req.body;
     if (req.headers.org !== 'org2') {
         res.status(403).json({ error: 'permission denied: only Battery Manufacturer ORG can create batteries' });
         return;
@@ -215,7 +219,11 @@ app.post('/createBattery', async (req, res) => {
     console.log(capacity.toString())
     console.log(totalLifeCycle.toString())    
         // rawMaterialsJSON과 weight, capacity, category, totalLifeCycle를 포함하여 트랜잭션을 호출
-        const result = await contract.submitTransaction('CreateBattery', rawMaterialsJSON, weight.toString(), capacity.toString(), voltage.toString(), category, totalLifeCycle.toString());
+        // rawMaterialsJSON 항목의 quantity는 단위를 포함 (예: { materialID, materialType, quantity: '20 kg', Status })
+        // 생산 오더 예약에서 소비하려면 항목에 reservationID를 지정 (지정하지 않으면 예약되지 않은 수량만 사용)
+        // manufacturingEnergy: { energyKWh, emissionFactor, emissionSource } (없으면 제조 배출량 미신고)
+        const manufacturingEnergyJSON = manufacturingEnergy ? JSON.stringify(manufacturingEnergy) : '';
+        const result = await contract.submitTransaction('CreateBattery', rawMaterialsJSON, weight.toString(), capacity.toString(), voltage.toString(), category, totalLifeCycle.toString(), manufacturingEnergyJSON);
         await gateway.disconnect();
 
         console.log(result)
@@ -227,6 +235,29 @@ app.post('/createBattery', async (req, res) => {
     }
 });
 
+// 생산 오더 일괄 생산 API (org2만 호출 가능)
+app.post('/createBatteryBatch', async (req, res) => {
+    const { orderID, model, billOfMaterials, units, manufacturingEnergy } = req.body;
+    if (req.headers.org !== 'org2') {
+        res.status(403).json({ error: 'permission denied: only Battery Manufacturer ORG can create batteries' });
+        return;
+    }
+
+    try {
+        const { contract, gateway } = await connectToNetwork('org2', 2);
+        // model: { modelID, category, weight, capacity, voltage, totalLifeCycle }
+        // billOfMaterials: 배터리 한 개당 BOM (createBattery의 rawMaterialsJSON과 같은 형식)
+        const manufacturingEnergyJSON = manufacturingEnergy ? JSON.stringify(manufacturingEnergy) : '';
+        const result = await contract.submitTransaction('CreateBatteryBatch', orderID, JSON.stringify(model), JSON.stringify(billOfMaterials), units.toString(), manufacturingEnergyJSON);
+        await gateway.disconnect();
+
+        res.status(200).json({ message: 'Batteries created successfully', order: JSON.parse(result.toString()) });
+    } catch (error) {
+        console.error(`Failed to create battery batch: ${error}`);
+        res.status(500).json({ error: error.message });
+    }
+});
+
 
 app.get('/queryBatteryDetails/:batteryID', async (req, res) => {
     const { batteryID } = req.params;
@@ -323,6 +354,7 @@ app.post('/requestMaintenance', async (req, res) => {
     }
     try {
         const { contract, gateway } = await connectToNetwork(org);
+        // 배터리의 현재 소유 조직만 요청할 수 있음 (소유권은 /offerTransfer, /acceptTransfer로 이전)
         const result = await contract.submitTransaction('RequestMaintenance', batteryID);
         await gateway.disconnect();
 
@@ -347,6 +379,186 @@ app.post('/requestMaintenance', async (req, res) => {
     }
 });
 
+// 배터리 소유권 이전 제안 API (현재 소유 조직만 호출 가능)
+app.post('/offerTransfer', async (req, res) => {
+    const { batteryID, newOwner } = req.body;
+    const org = req.headers.org;
+    try {
+        const { contract, gateway } = await connectToNetwork(org);
+        const result = await contract.submitTransaction('OfferTransfer', batteryID, newOwner);
+        await gateway.disconnect();
+
+        res.status(200).json({ message: 'Transfer offered successfully', transfer: JSON.parse(result.toString()) });
+    } catch (error) {
+        console.error(`Failed to offer transfer: ${error}`);
+        res.status(500).json({ error: error.message });
+    }
+});
+
+// 배터리 소유권 이전 수락 API (제안받은 조직만 호출 가능)
+app.post('/acceptTransfer', async (req, res) => {
+    const { batteryID } = req.body;
+    const org = req.headers.org;
+    try {
+        const { contract, gateway } = await connectToNetwork(org);
+        const result = await contract.submitTransaction('AcceptTransfer', batteryID);
+        await gateway.disconnect();
+
+        res.status(200).json({ message: 'Transfer accepted successfully', transfer: JSON.parse(result.toString()) });
+    } catch (error) {
+        console.error(`Failed to accept transfer: ${error}`);
+        res.status(500).json({ error: error.message });
+    }
+});
+
+app.get('/queryCustodyHistory/:batteryID', async (req, res) => {
+    const { batteryID } = req.params;
+    const org = req.headers.org || 'org2';
+    try {
+        const { contract, gateway } = await connectToNetwork(org);
+        const result = await contract.evaluateTransaction('QueryCustodyHistory', batteryID);
+        await gateway.disconnect();
+
+        res.status(200).json(JSON.parse(result.toString()));
+    } catch (error) {
+        console.error(`Failed to query custody history: ${error}`);
+        res.status(500).json({ error: error.message });
+    }
+});
+
+// 배터리 누적 사용량 보고 API (EV ORG, 소유 조직만 호출 가능)
+app.post('/reportUsage', async (req, res) => {
+    const { batteryID, cycleCount, energyThroughputKWh, odometerKm, reportedAt } = req.body;
+    const org = req.headers.org;
+    try {
+        const { contract, gateway } = await connectToNetwork(org);
+        const usageJSON = JSON.stringify({ cycleCount, energyThroughputKWh, odometerKm, reportedAt });
+        const result = await contract.submitTransaction('ReportUsage', batteryID, usageJSON);
+        await gateway.disconnect();
+
+        res.status(200).json({ message: 'Usage reported successfully', report: JSON.parse(result.toString()) });
+    } catch (error) {
+        console.error(`Failed to report usage: ${error}`);
+        res.status(500).json({ error: error.message });
+    }
+});
+
+app.get('/queryUsageSeries/:batteryID', async (req, res) => {
+    const { batteryID } = req.params;
+    const org = req.headers.org || 'org3';
+    try {
+        const { contract, gateway } = await connectToNetwork(org);
+        const result = await contract.evaluateTransaction('QueryUsageSeries', batteryID);
+        await gateway.disconnect();
+
+        res.status(200).json(JSON.parse(result.toString()));
+    } catch (error) {
+        console.error(`Failed to query usage series: ${error}`);
+        res.status(500).json({ error: error.message });
+    }
+});
+
+// BMS 텔레메트리 배치 Merkle 루트 고정 API (EV ORG, 정비 ORG)
+app.post('/anchorTelemetryBatch', async (req, res) => {
+    const { batteryID, source, merkleRoot, windowStart, windowEnd, recordCount, stats } = req.body;
+    const org = req.headers.org;
+    try {
+        const { contract, gateway } = await connectToNetwork(org);
+        const batchJSON = JSON.stringify({ source, merkleRoot, windowStart, windowEnd, recordCount, stats });
+        const result = await contract.submitTransaction('AnchorTelemetryBatch', batteryID, batchJSON);
+        await gateway.disconnect();
+
+        res.status(200).json({ message: 'Telemetry batch anchored successfully', anchor: JSON.parse(result.toString()) });
+    } catch (error) {
+        console.error(`Failed to anchor telemetry batch: ${error}`);
+        res.status(500).json({ error: error.message });
+    }
+});
+
+// 오프체인 텔레메트리 레코드의 포함 증명 검증 API (record는 보관한 문자열 그대로 전달)
+app.post('/verifyTelemetryRecord', async (req, res) => {
+    const { batteryID, anchorID, record, proof } = req.body;
+    const org = req.headers.org || 'org5';
+    try {
+        const { contract, gateway } = await connectToNetwork(org);
+        const result = await contract.evaluateTransaction('VerifyTelemetryRecord', batteryID, anchorID, record, JSON.stringify(proof));
+        await gateway.disconnect();
+
+        res.status(200).json(JSON.parse(result.toString()));
+    } catch (error) {
+        console.error(`Failed to verify telemetry record: ${error}`);
+        res.status(500).json({ error: error.message });
+    }
+});
+
+app.post('/placeInService', async (req, res) => {
+    const { batteryID } = req.body;
+
+    const org = req.headers.org;
+    if (org !== 'org3') {
+        res.status(403).json({ error: 'permission denied: only EV ORG can place batteries in service' });
+        return;
+    }
+    try {
+        const { contract, gateway } = await connectToNetwork(org);
+        const result = await contract.submitTransaction('PlaceInService', batteryID);
+        await gateway.disconnect();
+
+        const response = {
+            message: "Battery placed in service successfully."
+        };
+
+        if (result.toString()) {
+            response.result = result.toString();  // Only include 'result' if it's not empty
+        }
+
+        res.status(200).json(response);
+    } catch (error) {
+        console.error(`Failed to place battery in service: ${error}`);
+
+        // 에러 메시지에 'battery not found'가 포함되어 있는지 확인하여 상태 코드 499 반환
+        if (error.message.includes('battery not found')) {
+            res.status(499).json({ error: 'battery not found' });
+        } else {
+            res.status(500).json({ error: error.message });
+        }
+    }
+});
+
+app.post('/completeMaintenance', async (req, res) => {
+    const { batteryID } = req.body;
+
+    const org = req.headers.org;
+    if (org !== 'org4') {
+        res.status(403).json({ error: 'permission denied: only Maintenance ORG can complete maintenance' });
+        return;
+    }
+    try {
+        const { contract, gateway } = await connectToNetwork(org);
+        const result = await contract.submitTransaction('CompleteMaintenance', batteryID);
+        await gateway.disconnect();
+
+        const response = {
+            message: "Maintenance completed successfully."
+        };
+
+        if (result.toString()) {
+            response.result = result.toString();  // Only include 'result' if it's not empty
+        }
+
+        res.status(200).json(response);
+    } catch (error) {
+        console.error(`Failed to complete Maintenance: ${error}`);
+
+        // 에러 메시지에 'battery not found'가 포함되어 있는지 확인하여 상태 코드 499 반환
+        if (error.message.includes('battery not found')) {
+            res.status(499).json({ error: 'battery not found' });
+        } else {
+            res.status(500).json({ error: error.message });
+        }
+    }
+});
+
 app.post('/requestAnalysis', async (req, res) => {
     const { batteryID } = req.body;
     const org = req.headers.org;
@@ -381,7 +593,9 @@ app.post('/requestAnalysis', async (req, res) => {
 });
 
 app.post('/verifyMaterial', async (req, res) => {
-    const { materialID } = req.body;
+    // finding: { result: 'CONFORMANT' | 'NON_CONFORMANT', findings, certificateHash, highRisk,
+    //            method, evidenceHashes, scope, validFrom, validUntil }
+    const { materialID, finding } = req.body;
     const org = req.headers.org;
     if (org !== 'org7') {
         res.status(403).json({ error: 'permission denied: only Verify ORG can verify material' });
@@ -389,7 +603,7 @@ app.post('/verifyMaterial', async (req, res) => {
     }
     try {
         const { contract, gateway } = await connectToNetwork(org);
-        const result = await contract.submitTransaction('VerifyMaterial', materialID);
+        const result = await contract.submitTransaction('VerifyMaterial', materialID, JSON.stringify(finding || {}));
         await gateway.disconnect();
         res.status(200).json({ message: 'Material verified successfully', result: result.toString() });
     } catch (error) {
@@ -404,7 +618,8 @@ app.post('/verifyMaterial', async (req, res) => {
 });
 
 app.post('/verifyBattery', async (req, res) => {
-    const { batteryID } = req.body;
+    // verification: { method, evidenceHashes, scope, validFrom, validUntil }
+    const { batteryID, verification } = req.body;
     const org = req.headers.org;
     if (org !== 'org7') {
         res.status(403).json({ error: 'permission denied: only Verify ORG can verify battery' });
@@ -412,7 +627,7 @@ app.post('/verifyBattery', async (req, res) => {
     }
     try {
         const { contract, gateway } = await connectToNetwork(org);
-        const result = await contract.submitTransaction('VerifyBattery', batteryID);
+        const result = await contract.submitTransaction('VerifyBattery', batteryID, JSON.stringify(verification || {}));
         await gateway.disconnect();
         res.status(200).json({ message: 'Battery verified successfully', result: result.toString() });
     } catch (error) {
@@ -596,6 +811,7 @@ app.post('/extractMaterials', async (req, res) => {
         const { contract, gateway } = await connectToNetwork(org);
 
         // Serialize extractedQuantities as a JSON string
+        // e.g. { Lithium: '20 kg' } or { Lithium: { recovered: '20 kg', waste: '500 g' } }
         const extractedQuantitiesStr = JSON.stringify(extractedQuantities);
 
         // Submit the transaction to extract materials
@@ -141,14 +141,19 @@ func (s *BatteryChaincode) storeRawMaterialLocally(ctx contractapi.TransactionCo
 	return ctx.GetStub().PutState(rawMaterial.MaterialID, rawMaterialAsBytes)
 }
 
-func (s *BatteryChaincode) CreateBatteryPassport(batteryID string, recycledRatio map[string]float64, containsHazardous bool) (*BatteryPassport, error) {
+func (s *BatteryChaincode) CreateBatteryPassport(ctx contractapi.TransactionContextInterface, batteryID string, recycledRatio map[string]float64, containsHazardous bool) (*BatteryPassport, error) {
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	passportID := fmt.Sprintf("PASS-%s", batteryID)
 	passport := &BatteryPassport{
 		BatteryID:             batteryID,
 		PassportID:            passportID,
 		RecycledMaterialRatio: recycledRatio, // map 형태의 비율 정보
 		ContainsHazardous:     containsHazardous,
-		ManufactureDate:       time.Now(),
+		ManufactureDate:       storedManufactureDate(now),
 	}
 	return passport, nil
 }
@@ -191,11 +196,18 @@ func (s *BatteryChaincode) ManufactureBattery(ctx contractapi.TransactionContext
 	}
 
 	// 배터리 생성
-	batteryID := fmt.Sprintf("BATTERY-%d", time.Now().UnixNano())
+	batteryID, err := newID(ctx, "BATTERY")
+	if err != nil {
+		return "", err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
 	battery := Battery{
 		BatteryID:          batteryID,
 		RawMaterials:       rawMaterials, // 수정된 부분
-		ManufactureDate:    time.Now(),
+		ManufactureDate:    storedManufactureDate(now),
 		Capacity:           capacity,
 		TotalLifeCycle:     totalLifeCycle,
 		SOCE:               100,
@@ -224,7 +236,7 @@ func (s *BatteryChaincode) ManufactureBattery(ctx contractapi.TransactionContext
 	}
 
 	// 배터리 여권 생성
-	passport, err := s.CreateBatteryPassport(batteryID, recycledMaterialRatios, containsHazardous)
+	passport, err := s.CreateBatteryPassport(ctx, batteryID, recycledMaterialRatios, containsHazardous)
 	if err != nil {
 		return "", fmt.Errorf("failed to create battery passport: %v", err)
 	}
@@ -254,6 +266,11 @@ func (s *BatteryChaincode) UseRawMaterials(ctx contractapi.TransactionContextInt
 		return fmt.Errorf("failed to unmarshal raw materials: %v", err)
 	}
 
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
 	for materialID, quantity := range rawMaterials {
 		rawMaterial, err := s.QueryRawMaterial(ctx, materialID)
 		if err != nil {
@@ -266,7 +283,7 @@ func (s *BatteryChaincode) UseRawMaterials(ctx contractapi.TransactionContextInt
 			if rawMaterial.Quantity == 0 {
 				rawMaterial.Status = "used"
 			}
-			rawMaterial.Timestamp = time.Now().Format(time.RFC3339)
+			rawMaterial.Timestamp = now.Format(time.RFC3339)
 
 			rawMaterialAsBytes, err := json.Marshal(rawMaterial)
 			if err != nil {
@@ -428,6 +445,7 @@ func (s *BatteryChaincode) UpdateBatteryDetails(ctx contractapi.TransactionConte
 	}
 
 	// 변경된 배터리 정보 저장
+	battery.ManufactureDate = storedManufactureDate(battery.ManufactureDate)
 	batteryAsBytes, err = json.Marshal(battery)
 	if err != nil {
 		return fmt.Errorf("failed to marshal battery: %v", err)
@@ -457,6 +475,7 @@ func (s *BatteryChaincode) SyncFromUpdateChannel(ctx contractapi.TransactionCont
 
 	// 조회된 배터리 정보를 현재 채널에 저장
 	for _, battery := range batteries {
+		battery.ManufactureDate = storedManufactureDate(battery.ManufactureDate)
 		batteryAsBytes, err := json.Marshal(battery)
 		if err != nil {
 			return fmt.Errorf("failed to marshal battery: %v", err)
@@ -497,6 +516,7 @@ func (s *BatteryChaincode) SyncBatteriesFromBatteryUpdateChannel(ctx contractapi
 		if battery.MaintenanceLogs == nil {
 			battery.MaintenanceLogs = []string{}
 		}
+		battery.ManufactureDate = storedManufactureDate(battery.ManufactureDate)
 
 		batteryAsBytes, err := json.Marshal(battery)
 		if err != nil {
@@ -512,7 +532,10 @@ func (s *BatteryChaincode) SyncBatteriesFromBatteryUpdateChannel(ctx contractapi
 }
 
 func main() {
-	chaincode, err := contractapi.NewChaincode(new(BatteryChaincode))
+	batteryChaincode := new(BatteryChaincode)
+	batteryChaincode.TransactionContextHandler = new(TxContext)
+
+	chaincode, err := contractapi.NewChaincode(batteryChaincode)
 	if err != nil {
 		fmt.Printf("Error creating battery chaincode: %v\n", err)
 		return
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 한 페이지에서 조회할 수 있는 최대 레코드 수
+const maxPageSize = 200
+
+// PaginatedBatteries : 배터리 페이지 조회 결과
+type PaginatedBatteries struct {
+	Records             []Battery `json:"records"`
+	FetchedRecordsCount int32     `json:"fetchedRecordsCount"`
+	Bookmark            string    `json:"bookmark"`
+}
+
+// BatteryFilter : 배터리 페이지 조회 필터 (빈 값은 조건에서 제외)
+type BatteryFilter struct {
+	ManufacturerName    string   `json:"manufacturerName"`
+	SOHMin              *float64 `json:"sohMin"`
+	SOHMax              *float64 `json:"sohMax"`
+	ManufacturedFrom    string   `json:"manufacturedFrom"` // RFC3339
+	ManufacturedTo      string   `json:"manufacturedTo"`   // RFC3339
+	MaintenanceRequest  *bool    `json:"maintenanceRequest"`
+	AnalysisRequest     *bool    `json:"analysisRequest"`
+	RecycleAvailability *bool    `json:"recycleAvailability"`
+}
+
+// batterySelector : 필터를 CouchDB selector로 변환
+func batterySelector(filter BatteryFilter) (map[string]interface{}, error) {
+	selector := map[string]interface{}{"batteryID": map[string]interface{}{"$exists": true}}
+	if filter.ManufacturerName != "" {
+		selector["ManufacturerName"] = filter.ManufacturerName
+	}
+
+	soh := map[string]interface{}{}
+	if filter.SOHMin != nil {
+		soh["$gte"] = *filter.SOHMin
+	}
+	if filter.SOHMax != nil {
+		soh["$lte"] = *filter.SOHMax
+	}
+	if len(soh) > 0 {
+		selector["soh"] = soh
+	}
+
+	// 제조일은 UTC 초 단위의 고정 폭 RFC3339 문자열로 저장되므로 경계도 같은 형식으로 맞추어 사전순 비교
+	manufactured := map[string]interface{}{}
+	if filter.ManufacturedFrom != "" {
+		from, err := time.Parse(time.RFC3339, filter.ManufacturedFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manufacturedFrom: %v", err)
+		}
+		// 소수 초가 있는 시작 경계는 다음 초부터 포함
+		start := storedManufactureDate(from)
+		if start.Before(from) {
+			start = start.Add(manufactureDatePrecision)
+		}
+		manufactured["$gte"] = start.Format(time.RFC3339)
+	}
+	if filter.ManufacturedTo != "" {
+		to, err := time.Parse(time.RFC3339, filter.ManufacturedTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manufacturedTo: %v", err)
+		}
+		manufactured["$lte"] = storedManufactureDate(to).Format(time.RFC3339)
+	}
+	if len(manufactured) > 0 {
+		selector["manufactureDate"] = manufactured
+	}
+
+	if filter.MaintenanceRequest != nil {
+		selector["maintenanceRequest"] = *filter.MaintenanceRequest
+	}
+	if filter.AnalysisRequest != nil {
+		selector["analysisRequest"] = *filter.AnalysisRequest
+	}
+	if filter.RecycleAvailability != nil {
+		selector["recycleAvailability"] = *filter.RecycleAvailability
+	}
+
+	return selector, nil
+}
+
+// QueryAllBatteriesPaginated : 필터 조건(SOH 범위, 제조일 범위, 요청 상태)에 맞는 배터리를 페이지 단위로 조회
+func (s *BatteryChaincode) QueryAllBatteriesPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+	if pageSize <= 0 || pageSize > maxPageSize {
+		return nil, fmt.Errorf("page size must be between 1 and %d: %d", maxPageSize, pageSize)
+	}
+
+	var filter BatteryFilter
+	if filterJSON != "" {
+		if err := json.Unmarshal([]byte(filterJSON), &filter); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal filter: %v", err)
+		}
+	}
+
+	selector, err := batterySelector(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	queryBytes, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(queryBytes), pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batteries: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	batteries := []Battery{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var battery Battery
+		err = json.Unmarshal(queryResponse.Value, &battery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal battery: %v", err)
+		}
+
+		// nil 필드 초기화
+		if battery.RawMaterials == nil {
+			battery.RawMaterials = make(map[string]RawMaterialDetail)
+		}
+		if battery.AccidentLogs == nil {
+			battery.AccidentLogs = []string{}
+		}
+		if battery.MaintenanceLogs == nil {
+			battery.MaintenanceLogs = []string{}
+		}
+		batteries = append(batteries, battery)
+	}
+
+	return &PaginatedBatteries{
+		Records:             batteries,
+		FetchedRecordsCount: metadata.FetchedRecordsCount,
+		Bookmark:            metadata.Bookmark,
+	}, nil
+}
+
+// manufactureDatePrecision : 저장하는 제조일의 정밀도 (소수 초 없이 고정 폭 문자열이 되도록)
+const manufactureDatePrecision = time.Second
+
+// storedManufactureDate : 제조일을 저장 형식(UTC, 초 단위)으로 정규화
+func storedManufactureDate(date time.Time) time.Time {
+	return date.UTC().Truncate(manufactureDatePrecision)
+}
+
+// MigrateManufactureDates : 소수 초나 UTC가 아닌 오프셋으로 저장된 배터리와 여권의 제조일을 고정 폭 형식으로 다시 저장
+func (s *BatteryChaincode) MigrateManufactureDates(ctx contractapi.TransactionContextInterface) (int, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query documents: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	migrated := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return migrated, err
+		}
+
+		// 배터리와 여권만 대상 (여권은 passportID로 구분)
+		var stored struct {
+			BatteryID       string    `json:"batteryID"`
+			PassportID      string    `json:"passportID"`
+			ManufactureDate time.Time `json:"manufactureDate"`
+		}
+		if json.Unmarshal(queryResponse.Value, &stored) != nil || stored.BatteryID == "" {
+			continue
+		}
+		normalized := storedManufactureDate(stored.ManufactureDate)
+		if normalized.Equal(stored.ManufactureDate) && stored.ManufactureDate.Location() == time.UTC {
+			continue
+		}
+
+		var document interface{}
+		if stored.PassportID != "" {
+			var passport BatteryPassport
+			if err := json.Unmarshal(queryResponse.Value, &passport); err != nil {
+				return migrated, fmt.Errorf("failed to unmarshal battery passport: %v", err)
+			}
+			passport.ManufactureDate = normalized
+			document = passport
+		} else {
+			var battery Battery
+			if err := json.Unmarshal(queryResponse.Value, &battery); err != nil {
+				return migrated, fmt.Errorf("failed to unmarshal battery: %v", err)
+			}
+			battery.ManufactureDate = normalized
+			document = battery
+		}
+
+		documentAsBytes, err := json.Marshal(document)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to marshal %s: %v", queryResponse.Key, err)
+		}
+		err = ctx.GetStub().PutState(queryResponse.Key, documentAsBytes)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to store %s: %v", queryResponse.Key, err)
+		}
+		migrated++
+	}
+
+	return migrated, nil
+}
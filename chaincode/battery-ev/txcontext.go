@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TxContext : 트랜잭션마다 새로 생성되는 컨텍스트
+// 모든 보증 피어가 같은 읽기/쓰기 집합을 만들도록 ID와 시각은 트랜잭션 정보에서만 파생한다.
+type TxContext struct {
+	contractapi.TransactionContext
+	idSeq int
+}
+
+// NextID : txID와 트랜잭션 내 발급 순번으로 결정적 ID를 생성
+func (c *TxContext) NextID(prefix string) string {
+	c.idSeq++
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", c.GetStub().GetTxID(), c.idSeq)))
+	h := hex.EncodeToString(sum[:16])
+	return fmt.Sprintf("%s-%s-%s-%s-%s-%s", prefix, h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
+
+// newID : 컨텍스트에서 결정적 ID를 발급 (TxContext가 아니면 오류)
+func newID(ctx contractapi.TransactionContextInterface, prefix string) (string, error) {
+	txCtx, ok := ctx.(*TxContext)
+	if !ok {
+		return "", fmt.Errorf("failed to generate ID: unsupported transaction context %T", ctx)
+	}
+	return txCtx.NextID(prefix), nil
+}
+
+// txTime : 트랜잭션 제안에 기록된 타임스탬프 (모든 피어에서 동일, UTC)
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return ts.AsTime().UTC(), nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 한 페이지에서 조회할 수 있는 최대 레코드 수
+const maxPageSize = 200
+
+// PaginatedBatteries : 배터리 페이지 조회 결과
+type PaginatedBatteries struct {
+	Records             []Battery `json:"records"`
+	FetchedRecordsCount int32     `json:"fetchedRecordsCount"`
+	Bookmark            string    `json:"bookmark"`
+}
+
+// BatteryFilter : 배터리 페이지 조회 필터 (빈 값은 조건에서 제외)
+type BatteryFilter struct {
+	SOHMin              *float64 `json:"sohMin"`
+	SOHMax              *float64 `json:"sohMax"`
+	RecycleAvailability *bool    `json:"recycleAvailability"`
+}
+
+// queryBatteriesWithPagination : 필터와 추가 조건으로 배터리를 페이지 단위 조회
+func (s *BatteryUpdateChaincode) queryBatteriesWithPagination(ctx contractapi.TransactionContextInterface, filterJSON string, conditions map[string]interface{}, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+	if pageSize <= 0 || pageSize > maxPageSize {
+		return nil, fmt.Errorf("page size must be between 1 and %d: %d", maxPageSize, pageSize)
+	}
+
+	var filter BatteryFilter
+	if filterJSON != "" {
+		if err := json.Unmarshal([]byte(filterJSON), &filter); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal filter: %v", err)
+		}
+	}
+
+	selector := map[string]interface{}{"batteryID": map[string]interface{}{"$exists": true}}
+	soh := map[string]interface{}{}
+	if filter.SOHMin != nil {
+		soh["$gte"] = *filter.SOHMin
+	}
+	if filter.SOHMax != nil {
+		soh["$lte"] = *filter.SOHMax
+	}
+	if len(soh) > 0 {
+		selector["soh"] = soh
+	}
+	if filter.RecycleAvailability != nil {
+		selector["recycleAvailability"] = *filter.RecycleAvailability
+	}
+	for field, condition := range conditions {
+		selector[field] = condition
+	}
+
+	queryBytes, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(queryBytes), pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batteries: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	batteries := []Battery{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var battery Battery
+		err = json.Unmarshal(queryResponse.Value, &battery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal battery: %v", err)
+		}
+
+		// nil 필드 초기화
+		if battery.RawMaterials == nil {
+			battery.RawMaterials = make(map[string]RawMaterialDetail)
+		}
+		if battery.AccidentLogs == nil {
+			battery.AccidentLogs = []string{}
+		}
+		if battery.MaintenanceLogs == nil {
+			battery.MaintenanceLogs = []string{}
+		}
+		batteries = append(batteries, battery)
+	}
+
+	return &PaginatedBatteries{
+		Records:             batteries,
+		FetchedRecordsCount: metadata.FetchedRecordsCount,
+		Bookmark:            metadata.Bookmark,
+	}, nil
+}
+
+// QueryAllPaginated : 필터 조건(SOH 범위, 재활용 가능 여부)에 맞는 배터리를 페이지 단위로 조회
+func (s *BatteryUpdateChaincode) QueryAllPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+	return s.queryBatteriesWithPagination(ctx, filterJSON, nil, pageSize, bookmark)
+}
+
+// QueryBatteriesWithMaintenanceRequestPaginated : MaintenanceRequest = true인 배터리를 페이지 단위로 조회
+func (s *BatteryUpdateChaincode) QueryBatteriesWithMaintenanceRequestPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+	return s.queryBatteriesWithPagination(ctx, filterJSON, map[string]interface{}{"maintenanceRequest": true}, pageSize, bookmark)
+}
+
+// QueryBatteriesWithAnalysisRequestPaginated : AnalysisRequest = true인 배터리를 페이지 단위로 조회
+func (s *BatteryUpdateChaincode) QueryBatteriesWithAnalysisRequestPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+	return s.queryBatteriesWithPagination(ctx, filterJSON, map[string]interface{}{"analysisRequest": true}, pageSize, bookmark)
+}
+
+// QueryAllSyncedBatteriesPaginated : battery-update-channel에 동기화된 배터리를 페이지 단위로 조회
+// 페이지 조회는 쓰기가 없는 트랜잭션에서만 가능하므로 동기화하지 않으며, 최신 상태가 필요하면 먼저 SyncBatteriesFromEVChannel을 제출한다.
+func (s *BatteryUpdateChaincode) QueryAllSyncedBatteriesPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+	return s.queryBatteriesWithPagination(ctx, filterJSON, nil, pageSize, bookmark)
+}
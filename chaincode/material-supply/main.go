@@ -18,7 +18,7 @@ type RawMaterial struct {
 	Name       string `json:"name"`
 	Quantity   int    `json:"quantity"`
 	Status     string `json:"status"` // new or recycle
-	Available  string `json:available`
+	Available  string `json:"Available"`
 	VerifiedBy string `json:"verifiedBy"`
 	Timestamp  string `json:"timestamp"`
 }
@@ -31,6 +31,11 @@ func (s *RawMaterialChaincode) RegisterRawMaterial(ctx contractapi.TransactionCo
 		return fmt.Errorf("failed to read raw material: %v", err)
 	}
 
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
 	// 원자재가 존재하면 수량을 증가시킴
 	if existingRawMaterialAsBytes != nil {
 		existingRawMaterial := new(RawMaterial)
@@ -41,7 +46,7 @@ func (s *RawMaterialChaincode) RegisterRawMaterial(ctx contractapi.TransactionCo
 
 		// 수량을 증가시키고 업데이트
 		existingRawMaterial.Quantity += quantity
-		existingRawMaterial.Timestamp = time.Now().Format(time.RFC3339)
+		existingRawMaterial.Timestamp = now.Format(time.RFC3339)
 
 		updatedRawMaterialAsBytes, err := json.Marshal(existingRawMaterial)
 		if err != nil {
@@ -59,7 +64,7 @@ func (s *RawMaterialChaincode) RegisterRawMaterial(ctx contractapi.TransactionCo
 		Quantity:   quantity,
 		Status:     "NEW",
 		Available:  "Available",
-		Timestamp:  time.Now().Format(time.RFC3339),
+		Timestamp:  now.Format(time.RFC3339),
 	}
 
 	rawMaterialAsBytes, err := json.Marshal(rawMaterial)
@@ -91,11 +96,16 @@ func (s *RawMaterialChaincode) UpdateRawMaterialQuantity(ctx contractapi.Transac
 		return fmt.Errorf("not enough raw material quantity")
 	}
 
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
 	rawMaterial.Quantity += changeAmount
 	if rawMaterial.Quantity == 0 {
 		rawMaterial.Available = "Not Available"
 	}
-	rawMaterial.Timestamp = time.Now().Format(time.RFC3339)
+	rawMaterial.Timestamp = now.Format(time.RFC3339)
 
 	rawMaterialAsBytes, err = json.Marshal(rawMaterial)
 	if err != nil {
@@ -153,7 +163,10 @@ func (s *RawMaterialChaincode) QueryAllRawMaterials(ctx contractapi.TransactionC
 }
 
 func main() {
-	chaincode, err := contractapi.NewChaincode(new(RawMaterialChaincode))
+	rawMaterialChaincode := new(RawMaterialChaincode)
+	rawMaterialChaincode.TransactionContextHandler = new(TxContext)
+
+	chaincode, err := contractapi.NewChaincode(rawMaterialChaincode)
 	if err != nil {
 		fmt.Printf("Error creating raw material chaincode: %v\n", err)
 		return
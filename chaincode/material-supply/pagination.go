@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 한 페이지에서 조회할 수 있는 최대 레코드 수
+const maxPageSize = 200
+
+// PaginatedRawMaterials : 원자재 페이지 조회 결과
+type PaginatedRawMaterials struct {
+	Records             []RawMaterial `json:"records"`
+	FetchedRecordsCount int32         `json:"fetchedRecordsCount"`
+	Bookmark            string        `json:"bookmark"`
+}
+
+// RawMaterialFilter : 원자재 페이지 조회 필터 (빈 값은 조건에서 제외)
+type RawMaterialFilter struct {
+	Status     string `json:"status"`
+	Name       string `json:"name"`
+	SupplierID string `json:"supplierID"`
+	Available  string `json:"available"`
+}
+
+// QueryAllRawMaterialsPaginated : 필터 조건(상태, 이름, 공급자, 가용 여부)에 맞는 원자재를 페이지 단위로 조회
+func (s *RawMaterialChaincode) QueryAllRawMaterialsPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedRawMaterials, error) {
+	if pageSize <= 0 || pageSize > maxPageSize {
+		return nil, fmt.Errorf("page size must be between 1 and %d: %d", maxPageSize, pageSize)
+	}
+
+	var filter RawMaterialFilter
+	if filterJSON != "" {
+		if err := json.Unmarshal([]byte(filterJSON), &filter); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal filter: %v", err)
+		}
+	}
+
+	selector := map[string]interface{}{"materialID": map[string]interface{}{"$exists": true}}
+	if filter.Status != "" {
+		selector["status"] = filter.Status
+	}
+	if filter.Name != "" {
+		selector["name"] = filter.Name
+	}
+	if filter.SupplierID != "" {
+		selector["supplierID"] = filter.SupplierID
+	}
+	if filter.Available != "" {
+		selector["Available"] = filter.Available
+	}
+
+	queryBytes, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(queryBytes), pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query raw materials: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	rawMaterials := []RawMaterial{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var rawMaterial RawMaterial
+		err = json.Unmarshal(queryResponse.Value, &rawMaterial)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal raw material: %v", err)
+		}
+
+		rawMaterials = append(rawMaterials, rawMaterial)
+	}
+
+	return &PaginatedRawMaterials{
+		Records:             rawMaterials,
+		FetchedRecordsCount: metadata.FetchedRecordsCount,
+		Bookmark:            metadata.Bookmark,
+	}, nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TxContext : 트랜잭션마다 새로 생성되는 컨텍스트
+// 모든 보증 피어가 같은 읽기/쓰기 집합을 만들도록 시각은 트랜잭션 정보에서만 파생한다.
+type TxContext struct {
+	contractapi.TransactionContext
+}
+
+// txTime : 트랜잭션 제안에 기록된 타임스탬프 (모든 피어에서 동일, UTC)
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return ts.AsTime().UTC(), nil
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeAccident = "accident"
+
+	// 사고 기록 키 (accident~배터리ID~기록ID)
+	accidentRecordObjectType = "accident"
+
+	accidentPolicyConfigName = "accidentPolicy"
+
+	// 이전 AddAccidentLog가 사고마다 감소시킨 SOH
+	legacyAccidentSOHImpact = 10
+)
+
+// 사고 유형
+const (
+	IncidentCollision       = "COLLISION"
+	IncidentFire            = "FIRE"
+	IncidentWaterIngress    = "WATER_INGRESS"
+	IncidentOverheating     = "OVERHEATING"
+	IncidentMechanicalShock = "MECHANICAL_SHOCK"
+	IncidentElectricalFault = "ELECTRICAL_FAULT"
+	IncidentOther           = "OTHER"
+)
+
+var incidentTypes = map[string]bool{
+	IncidentCollision:       true,
+	IncidentFire:            true,
+	IncidentWaterIngress:    true,
+	IncidentOverheating:     true,
+	IncidentMechanicalShock: true,
+	IncidentElectricalFault: true,
+	IncidentOther:           true,
+}
+
+// SeverityRule : 심각도 단계별 SOH 감소량과 분석 필요 여부
+type SeverityRule struct {
+	Severity         int     `json:"severity"`
+	Label            string  `json:"label"`
+	SOHImpact        float64 `json:"sohImpact"`
+	RequiresAnalysis bool    `json:"requiresAnalysis"`
+}
+
+// AccidentPolicy : 사고 심각도 표 (신고할 수 있는 조직은 AddAccidentLog의 역할 표로 결정)
+type AccidentPolicy struct {
+	SeverityRules []SeverityRule `json:"severityRules"`
+}
+
+// defaultAccidentPolicy : 설정이 없을 때 사용하는 기본 정책
+func defaultAccidentPolicy() *AccidentPolicy {
+	return &AccidentPolicy{
+		SeverityRules: []SeverityRule{
+			{Severity: 1, Label: "MINOR", SOHImpact: 0, RequiresAnalysis: false},
+			{Severity: 2, Label: "MODERATE", SOHImpact: 2, RequiresAnalysis: false},
+			{Severity: 3, Label: "SERIOUS", SOHImpact: 5, RequiresAnalysis: true},
+			{Severity: 4, Label: "SEVERE", SOHImpact: 10, RequiresAnalysis: true},
+			{Severity: 5, Label: "CRITICAL", SOHImpact: 20, RequiresAnalysis: true},
+		},
+	}
+}
+
+// rule : 심각도 단계에 해당하는 규칙
+func (p *AccidentPolicy) rule(severity int) (SeverityRule, bool) {
+	for _, rule := range p.SeverityRules {
+		if rule.Severity == severity {
+			return rule, true
+		}
+	}
+	return SeverityRule{}, false
+}
+
+// legacySeverity : 심각도 없이 AccidentLogs에 문자열로만 남은 이전 사고의 심각도
+// 이전 방식은 사고마다 SOH를 legacyAccidentSOHImpact만큼 감소시켰으므로 그 이상 감소시키는 가장 낮은 단계로 보고,
+// 그런 단계가 없으면 가장 높은 단계로 본다.
+func (p *AccidentPolicy) legacySeverity() int {
+	severity, highest := 0, 0
+	for _, rule := range p.SeverityRules {
+		if rule.SOHImpact >= legacyAccidentSOHImpact && (severity == 0 || rule.Severity < severity) {
+			severity = rule.Severity
+		}
+		if rule.Severity > highest {
+			highest = rule.Severity
+		}
+	}
+	if severity == 0 {
+		return highest
+	}
+	return severity
+}
+
+func (p *AccidentPolicy) validate() error {
+	if len(p.SeverityRules) == 0 {
+		return fmt.Errorf("accident policy must define at least one severity rule")
+	}
+	seen := make(map[int]bool)
+	for _, rule := range p.SeverityRules {
+		if rule.Severity <= 0 {
+			return fmt.Errorf("severity must be positive: %d", rule.Severity)
+		}
+		if seen[rule.Severity] {
+			return fmt.Errorf("duplicate severity rule: %d", rule.Severity)
+		}
+		if rule.SOHImpact < 0 || rule.SOHImpact > 100 {
+			return fmt.Errorf("SOH impact must be between 0 and 100: %v", rule.SOHImpact)
+		}
+		seen[rule.Severity] = true
+	}
+	return nil
+}
+
+// ImpactAssessment : 사고가 배터리에 미친 영향 평가
+type ImpactAssessment struct {
+	Summary           string   `json:"summary"`
+	DamagedComponents []string `json:"damagedComponents"`
+	ThermalEvent      bool     `json:"thermalEvent"`
+	Leakage           bool     `json:"leakage"`
+}
+
+// AccidentRecord : 배터리별 사고 기록
+type AccidentRecord struct {
+	DocType           string           `json:"docType"`
+	RecordID          string           `json:"recordID"`
+	BatteryID         string           `json:"batteryID"`
+	IncidentType      string           `json:"incidentType"`
+	IncidentDate      string           `json:"incidentDate"` // RFC3339 (UTC)
+	Severity          int              `json:"severity"`
+	SeverityLabel     string           `json:"severityLabel"`
+	ImpactAssessment  ImpactAssessment `json:"impactAssessment"`
+	ActionInformation string           `json:"actionInformation"`
+	ReportingOrg      string           `json:"reportingOrg"`
+	EvidenceHashes    []string         `json:"evidenceHashes"`
+	SOHImpact         float64          `json:"sohImpact"`
+	SOHBefore         float64          `json:"sohBefore"`
+	SOHAfter          float64          `json:"sohAfter"`
+	RequiresAnalysis  bool             `json:"requiresAnalysis"`
+	RecordedAt        string           `json:"recordedAt"`
+}
+
+// validateEvidenceHash : 증빙 파일 해시는 SHA-256 16진수 문자열 (선택적으로 "sha256:" 접두사)
+func validateEvidenceHash(hash string) (string, error) {
+	normalized := strings.ToLower(strings.TrimPrefix(hash, "sha256:"))
+	decoded, err := hex.DecodeString(normalized)
+	if err != nil || len(decoded) != 32 {
+		return "", fmt.Errorf("invalid evidence hash %q: expected hex-encoded SHA-256", hash)
+	}
+	return normalized, nil
+}
+
+// readAccidentPolicy : 원장에 저장된 사고 정책 (없으면 기본 정책)
+func readAccidentPolicy(ctx contractapi.TransactionContextInterface) (*AccidentPolicy, error) {
+	policyKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{accidentPolicyConfigName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	policyAsBytes, err := ctx.GetStub().GetState(policyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accident policy: %v", err)
+	}
+	if policyAsBytes == nil {
+		return defaultAccidentPolicy(), nil
+	}
+
+	policy := new(AccidentPolicy)
+	err = json.Unmarshal(policyAsBytes, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal accident policy: %v", err)
+	}
+
+	return policy, nil
+}
+
+// SetAccidentPolicy : 사고 심각도 표를 설정 (신고 가능 조직은 역할 표의 AddAccidentLog 항목으로 결정)
+func (s *PublicContract) SetAccidentPolicy(ctx contractapi.TransactionContextInterface, policyJSON string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "SetAccidentPolicy")
+	if err != nil {
+		return err
+	}
+
+	var policy AccidentPolicy
+	err = json.Unmarshal([]byte(policyJSON), &policy)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal accident policy: %v", err)
+	}
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	sort.Slice(policy.SeverityRules, func(i, j int) bool {
+		return policy.SeverityRules[i].Severity < policy.SeverityRules[j].Severity
+	})
+
+	policyKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{accidentPolicyConfigName})
+	if err != nil {
+		return fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	policyAsBytes, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal accident policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(policyKey, policyAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put accident policy: %v", err)
+	}
+
+	return emitEvent(ctx, EventPolicyUpdated, eventAssetConfig, accidentPolicyConfigName, PolicyUpdatedPayload{Policy: accidentPolicyConfigName, Value: policy})
+}
+
+// QueryAccidentPolicy : 현재 적용 중인 사고 정책 조회
+func (s *PublicContract) QueryAccidentPolicy(ctx contractapi.TransactionContextInterface) (*AccidentPolicy, error) {
+	return readAccidentPolicy(ctx)
+}
+
+// AddAccidentLog : 사고 기록을 저장하고 심각도 표에 따라 SOH 감소 및 분석 요청 여부를 결정
+func (s *PublicContract) AddAccidentLog(ctx contractapi.TransactionContextInterface, batteryID string, incidentDataJSON string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인 (EV ORG, Maintenance ORG)
+	caller, err := authorize(ctx, "AddAccidentLog")
+	if err != nil {
+		return err
+	}
+
+	policy, err := readAccidentPolicy(ctx)
+	if err != nil {
+		return err
+	}
+
+	battery, err := loadBattery(ctx, batteryID)
+	if err != nil {
+		return err
+	}
+
+	// 수명이 끝났거나 해체된 배터리에는 사고를 기록할 수 없음
+	err = requireBatteryStatus(battery, "RECORD_ACCIDENT", statusManufactured, statusInService, statusUnderMaintenance, statusUnderAnalysis, statusSecondLife)
+	if err != nil {
+		return err
+	}
+
+	var incidentData struct {
+		IncidentDate      string           `json:"incidentDate"`
+		IncidentType      string           `json:"incidentType"`
+		Severity          int              `json:"severity"`
+		ImpactAssessment  ImpactAssessment `json:"impactAssessment"`
+		ActionInformation string           `json:"actionInformation"`
+		EvidenceHashes    []string         `json:"evidenceHashes"`
+	}
+
+	err = json.Unmarshal([]byte(incidentDataJSON), &incidentData)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal incident data: %v", err)
+	}
+
+	if !incidentTypes[incidentData.IncidentType] {
+		return fmt.Errorf("unknown incident type: %s", incidentData.IncidentType)
+	}
+
+	rule, ok := policy.rule(incidentData.Severity)
+	if !ok {
+		return fmt.Errorf("unknown accident severity: %d", incidentData.Severity)
+	}
+
+	evidenceHashes := []string{}
+	for _, hash := range incidentData.EvidenceHashes {
+		normalized, err := validateEvidenceHash(hash)
+		if err != nil {
+			return err
+		}
+		evidenceHashes = append(evidenceHashes, normalized)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 사고 일자가 없으면 트랜잭션 시각을 사용
+	incidentDate := now
+	if incidentData.IncidentDate != "" {
+		incidentDate, err = parseRecordDate(incidentData.IncidentDate)
+		if err != nil {
+			return err
+		}
+	}
+
+	if incidentData.ImpactAssessment.DamagedComponents == nil {
+		incidentData.ImpactAssessment.DamagedComponents = []string{}
+	}
+
+	sohBefore := battery.SOH
+	battery.SOH -= rule.SOHImpact
+	if battery.SOH < 0 {
+		battery.SOH = 0
+	}
+
+	// 사고 이력이 바뀌었으므로 분석 중에 받은 2차 사용 등급은 다시 평가해야 함
+	if battery.Status == statusUnderAnalysis {
+		battery.Grade = ""
+		battery.GradeID = ""
+	}
+
+	// 분석이 필요한 사고는 배터리를 분석 상태로 전이 (이미 분석 중이면 유지)
+	if rule.RequiresAnalysis && battery.Status != statusUnderAnalysis {
+		err = transitionBattery(ctx, battery, actionReportAccident)
+		if err != nil {
+			return err
+		}
+	}
+
+	recordID, err := newID(ctx, "ACCIDENT")
+	if err != nil {
+		return err
+	}
+
+	record := AccidentRecord{
+		DocType:           docTypeAccident,
+		RecordID:          recordID,
+		BatteryID:         battery.BatteryID,
+		IncidentType:      incidentData.IncidentType,
+		IncidentDate:      incidentDate.Format(time.RFC3339),
+		Severity:          rule.Severity,
+		SeverityLabel:     rule.Label,
+		ImpactAssessment:  incidentData.ImpactAssessment,
+		ActionInformation: incidentData.ActionInformation,
+		ReportingOrg:      caller.MSPID,
+		EvidenceHashes:    evidenceHashes,
+		SOHImpact:         rule.SOHImpact,
+		SOHBefore:         sohBefore,
+		SOHAfter:          battery.SOH,
+		RequiresAnalysis:  rule.RequiresAnalysis,
+		RecordedAt:        now.Format(time.RFC3339),
+	}
+
+	recordKey, err := ctx.GetStub().CreateCompositeKey(accidentRecordObjectType, []string{record.BatteryID, record.RecordID})
+	if err != nil {
+		return fmt.Errorf("failed to create accident record key: %v", err)
+	}
+
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal accident record: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(recordKey, recordAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store accident record: %v", err)
+	}
+
+	err = s.saveBattery(ctx, battery)
+	if err != nil {
+		return err
+	}
+
+	return emitEvent(ctx, EventAccidentRecorded, eventAssetBattery, battery.BatteryID, AccidentRecordedPayload{Record: &record, Battery: battery})
+}
+
+// QueryAccidentRecords : 특정 배터리의 사고 기록 조회 (사고 일자 순)
+func (s *PublicContract) QueryAccidentRecords(ctx contractapi.TransactionContextInterface, batteryID string) ([]AccidentRecord, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(accidentRecordObjectType, []string{batteryID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query accident records: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	records := []AccidentRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record AccidentRecord
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal accident record: %v", err)
+		}
+		if record.EvidenceHashes == nil {
+			record.EvidenceHashes = []string{}
+		}
+		if record.ImpactAssessment.DamagedComponents == nil {
+			record.ImpactAssessment.DamagedComponents = []string{}
+		}
+
+		records = append(records, record)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].IncidentDate != records[j].IncidentDate {
+			return records[i].IncidentDate < records[j].IncidentDate
+		}
+		return records[i].RecordedAt < records[j].RecordedAt
+	})
+
+	return records, nil
+}
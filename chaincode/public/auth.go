@@ -0,0 +1,406 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 역할 (조직 단위 권한은 원장의 역할 매핑, 개인 단위 권한은 인증서 role 속성에서 결정)
+const (
+	roleSupplier     = "supplier"     // 원자재 공급 (Org1)
+	roleManufacturer = "manufacturer" // 배터리 제조 (Org2)
+	roleOperator     = "operator"     // 전기차 운행 (Org3)
+	roleTechnician   = "technician"   // 유지보수 (Org4)
+	roleAnalyst      = "analyst"      // 성능 분석 (Org5)
+	roleRecycler     = "recycler"     // 재활용 (Org6)
+	roleVerifier     = "verifier"     // 검증 (Org7)
+	roleRepurposer   = "repurposer"   // 2차 사용 운영 (기본 Org3, 역할 매핑으로 변경)
+	roleOEM          = "oem"          // 완성차 제조 (기본 없음, 역할 매핑으로 추가)
+	roleAdmin        = "admin"        // 역할 매핑 및 마이그레이션 관리
+)
+
+// 인증서 속성 이름 (값은 쉼표로 구분된 역할 목록, 예: role=verifier)
+const roleAttribute = "role"
+
+const roleMappingConfigName = "roleMapping"
+
+// functionRoles : 함수별로 호출에 필요한 역할 (하나라도 가지면 허용)
+// 표에도 openFunctions에도 없는 함수는 authorize에서 거부된다. 수명 주기 전이는 lifecycleRules의 역할로 검사한다.
+var functionRoles = map[string][]string{
+	"RegisterRawMaterial": {roleSupplier},
+	"VerifyMaterial":      {roleVerifier},
+	"CreateBattery":       {roleManufacturer},
+	"CreateBatteryBatch":  {roleManufacturer},
+	"VerifyBattery":       {roleVerifier},
+	"RevokeVerification":  {roleVerifier, roleAdmin},
+	"ExtractMaterials":    {roleRecycler},
+	"AddMaintenanceLog":   {roleTechnician},
+	"GradeBattery":        {roleAnalyst},
+	"AddAccidentLog":      {roleOperator, roleTechnician},
+
+	"SplitLot":    {roleSupplier, roleManufacturer, roleRecycler},
+	"MergeLots":   {roleSupplier, roleManufacturer, roleRecycler},
+	"TransferLot": {roleSupplier, roleManufacturer, roleRecycler},
+
+	"OfferTransfer": {roleManufacturer, roleOEM, roleOperator, roleRepurposer, roleRecycler},
+
+	"ReserveMaterials":           {roleManufacturer},
+	"ReleaseReservation":         {roleManufacturer},
+	"ReleaseExpiredReservations": {roleManufacturer, roleAdmin},
+
+	"SetMaterialOrigin":          {roleSupplier},
+	"SetMaterialCommercialTerms": {roleSupplier},
+	"SetBatteryPlantDetails":     {roleManufacturer},
+	"RecordTelemetry":            {roleOperator, roleTechnician},
+	"AnchorTelemetryBatch":       {roleOperator, roleTechnician},
+	"ReportUsage":                {roleOperator, roleRepurposer},
+
+	"QueryPerformance":                               {roleOperator, roleTechnician, roleAnalyst},
+	"QueryBatterySOCEAndLifeCycle":                   {roleOperator, roleAnalyst},
+	"QueryBatteriesWithMaintenanceRequest":           {roleOperator, roleTechnician},
+	"QueryBatteriesWithMaintenanceRequestPaginated":  {roleOperator, roleTechnician},
+	"QueryBatteriesWithAnalysisRequest":              {roleOperator, roleAnalyst},
+	"QueryBatteriesWithAnalysisRequestPaginated":     {roleOperator, roleAnalyst},
+	"QueryBatteriesWithRecycleAvailability":          {roleOperator, roleRecycler},
+	"QueryBatteriesWithRecycleAvailabilityPaginated": {roleOperator, roleRecycler},
+
+	"SetAccidentPolicy":         {roleVerifier},
+	"SetRecoveryYieldPolicy":    {roleVerifier},
+	"SetRecycledContentProfile": {roleVerifier},
+	"SetDueDiligencePolicy":     {roleVerifier},
+	"SetGradingPolicy":          {roleVerifier},
+
+	"SetRoleMapping":          {roleAdmin},
+	"InitMaterials":           {roleAdmin},
+	"InitBatteries":           {roleAdmin},
+	"MigrateIndexes":          {roleAdmin},
+	"MigrateMaintenanceLogs":  {roleAdmin},
+	"MigrateBatteryLifecycle": {roleAdmin},
+	"MigrateLineage":          {roleAdmin},
+	"MigrateQuantities":       {roleAdmin},
+	"MigrateLotOwners":        {roleAdmin},
+	"MigrateBatteryOwners":    {roleAdmin},
+	"MigrateManufactureDates": {roleAdmin},
+}
+
+// openFunctions : 역할 표 없이 누구나 호출할 수 있는 트랜잭션
+// 원장을 바꾸지 않는 조회이거나, 함수 안에서 수명 주기 규칙, 이전 당사자, 접근 기관으로 권한을 확인한다.
+// 새 트랜잭션은 functionRoles나 이 목록 중 한 곳에 반드시 등록해야 한다.
+var openFunctions = map[string]bool{
+	// 수명 주기 규칙(lifecycleRules)의 역할과 소유자로 확인
+	"PlaceInService":         true,
+	"RequestMaintenance":     true,
+	"CompleteMaintenance":    true,
+	"RequestAnalysis":        true,
+	"ApproveSecondLife":      true,
+	"SetRecycleAvailability": true,
+	"RepurposeBattery":       true,
+
+	// 이전 제안의 당사자로 확인
+	"AcceptTransfer": true,
+	"CancelTransfer": true,
+
+	// 여권 속성별 접근 기관으로 확인
+	"ExportPassport": true,
+
+	// 조회 및 검증
+	"QueryAccidentPolicy":                true,
+	"QueryAccidentRecords":               true,
+	"QueryAllBatteries":                  true,
+	"QueryAllBatteriesPaginated":         true,
+	"QueryAllMaterials":                  true,
+	"QueryAllMaterialsPaginated":         true,
+	"QueryAllRawMaterials":               true,
+	"QueryAllRawMaterialsPaginated":      true,
+	"QueryBatteryAtTime":                 true,
+	"QueryBatteryDetails":                true,
+	"QueryBatteryGrades":                 true,
+	"QueryBatteryHistory":                true,
+	"QueryBatteryLifecycleHistory":       true,
+	"QueryBatteryPlantDetails":           true,
+	"QueryCallerRoles":                   true,
+	"QueryCarbonFootprint":               true,
+	"QueryCustodyHistory":                true,
+	"QueryDueDiligencePolicy":            true,
+	"QueryExtractedMaterial":             true,
+	"QueryExtractionRecord":              true,
+	"QueryFleetRecycledContent":          true,
+	"QueryGradingPolicy":                 true,
+	"QueryMaintenanceRecords":            true,
+	"QueryMaintenanceRecordsByCompany":   true,
+	"QueryMaintenanceRecordsByDateRange": true,
+	"QueryMaterial":                      true,
+	"QueryMaterialAtTime":                true,
+	"QueryMaterialAvailability":          true,
+	"QueryMaterialCommercialTerms":       true,
+	"QueryMaterialHistory":               true,
+	"QueryNewMaterials":                  true,
+	"QueryNewMaterialsPaginated":         true,
+	"QueryPassportSchema":                true,
+	"QueryPendingTransfer":               true,
+	"QueryPrivateDataReferences":         true,
+	"QueryProductionOrder":               true,
+	"QueryRecoveryYieldPolicy":           true,
+	"QueryRecycledContentCompliance":     true,
+	"QueryRecycledContentProfile":        true,
+	"QueryRecycledMaterials":             true,
+	"QueryRecycledMaterialsPaginated":    true,
+	"QueryRepurposeHistory":              true,
+	"QueryReservation":                   true,
+	"QueryReservationsByOrder":           true,
+	"QueryRoleMapping":                   true,
+	"QueryTelemetry":                     true,
+	"QueryTelemetryAnchors":              true,
+	"QueryUsageSeries":                   true,
+	"QueryVerification":                  true,
+	"QueryVerificationHistory":           true,
+	"TraceBatteryUpstream":               true,
+	"TraceMaterialDownstream":            true,
+	"VerifyPrivateData":                  true,
+	"VerifyTelemetryRecord":              true,
+}
+
+// RoleMapping : 역할 → 조직(MSP) 매핑
+// RoleMSPs의 조직은 모든 구성원이 해당 역할을 가지며,
+// AttributeRoleMSPs의 조직은 인증서에 role 속성으로 해당 역할이 발급된 구성원만 역할을 가진다.
+type RoleMapping struct {
+	RoleMSPs          map[string][]string `json:"roleMSPs"`
+	AttributeRoleMSPs map[string][]string `json:"attributeRoleMSPs"`
+}
+
+// defaultRoleMapping : 설정이 없을 때 사용하는 기본 매핑 (네트워크 구성의 Org1~Org7)
+// 관리자는 검증 역할과 분리하여, Org7MSP 중 인증서에 role=admin 속성이 발급된 구성원만 가진다.
+func defaultRoleMapping() *RoleMapping {
+	return &RoleMapping{
+		RoleMSPs: map[string][]string{
+			roleSupplier:     {"Org1MSP"},
+			roleManufacturer: {"Org2MSP"},
+			roleOperator:     {"Org3MSP"},
+			roleTechnician:   {"Org4MSP"},
+			roleAnalyst:      {"Org5MSP"},
+			roleRecycler:     {"Org6MSP"},
+			roleVerifier:     {"Org7MSP"},
+			roleRepurposer:   {"Org3MSP"},
+		},
+		AttributeRoleMSPs: map[string][]string{
+			roleAdmin: {"Org7MSP"},
+		},
+	}
+}
+
+func (m *RoleMapping) validate() error {
+	if len(m.RoleMSPs[roleAdmin]) == 0 && len(m.AttributeRoleMSPs[roleAdmin]) == 0 {
+		return fmt.Errorf("role mapping must assign the %s role to at least one MSP", roleAdmin)
+	}
+	// 검증 조직 구성원 전체가 관리자가 되면 검증자가 역할 매핑과 정책을 바꿀 수 있으므로 거부
+	for _, msp := range m.RoleMSPs[roleAdmin] {
+		if containsString(m.RoleMSPs[roleVerifier], msp) || containsString(m.AttributeRoleMSPs[roleVerifier], msp) {
+			return fmt.Errorf("role mapping must not grant the %s role to every member of %s, which also holds the %s role: use attributeRoleMSPs", roleAdmin, msp, roleVerifier)
+		}
+	}
+	for _, mapping := range []map[string][]string{m.RoleMSPs, m.AttributeRoleMSPs} {
+		for role, msps := range mapping {
+			if strings.TrimSpace(role) == "" {
+				return fmt.Errorf("role name must not be empty")
+			}
+			for _, msp := range msps {
+				if strings.TrimSpace(msp) == "" {
+					return fmt.Errorf("MSP ID for role %s must not be empty", role)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// PermissionError : 호출자가 함수에 필요한 역할을 갖지 않았을 때의 오류
+type PermissionError struct {
+	Function string
+	MSPID    string
+	Required []string
+	Held     []string
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("permission denied: %s requires one of roles [%s] but caller from %s holds [%s]",
+		e.Function, strings.Join(e.Required, ", "), e.MSPID, strings.Join(e.Held, ", "))
+}
+
+// Caller : 권한 검사를 통과한 호출자 정보
+type Caller struct {
+	MSPID string   `json:"mspID"`
+	Roles []string `json:"roles"`
+}
+
+func (c *Caller) hasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		for _, held := range c.Roles {
+			if held == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readRoleMapping : 원장에 저장된 역할 매핑 (없으면 기본 매핑)
+func readRoleMapping(ctx contractapi.TransactionContextInterface) (*RoleMapping, error) {
+	mappingKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{roleMappingConfigName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	mappingAsBytes, err := ctx.GetStub().GetState(mappingKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role mapping: %v", err)
+	}
+	if mappingAsBytes == nil {
+		return defaultRoleMapping(), nil
+	}
+
+	mapping := new(RoleMapping)
+	err = json.Unmarshal(mappingAsBytes, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal role mapping: %v", err)
+	}
+	if mapping.RoleMSPs == nil {
+		mapping.RoleMSPs = make(map[string][]string)
+	}
+	if mapping.AttributeRoleMSPs == nil {
+		mapping.AttributeRoleMSPs = make(map[string][]string)
+	}
+
+	return mapping, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ownerOrSoleRoleMSP : 지정한 조직이 없으면 역할에 매핑된 유일한 조직 (소유자 부여용, 여러 조직이면 오류)
+func ownerOrSoleRoleMSP(mapping *RoleMapping, owner string, role string) (string, error) {
+	if owner != "" {
+		return owner, nil
+	}
+	msps := mapping.RoleMSPs[role]
+	if len(msps) != 1 {
+		return "", fmt.Errorf("owner must be specified: role %s is mapped to %d MSPs", role, len(msps))
+	}
+	return msps[0], nil
+}
+
+// resolveCaller : 호출자의 MSP와 인증서 속성으로 역할을 결정
+func resolveCaller(ctx contractapi.TransactionContextInterface) (*Caller, error) {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	mapping, err := readRoleMapping(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]bool)
+	for role, msps := range mapping.RoleMSPs {
+		if containsString(msps, clientMSPID) {
+			roles[role] = true
+		}
+	}
+
+	attributeValue, found, err := ctx.GetClientIdentity().GetAttributeValue(roleAttribute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s attribute: %v", roleAttribute, err)
+	}
+	if found {
+		for _, role := range strings.Split(attributeValue, ",") {
+			role = strings.TrimSpace(role)
+			if role != "" && containsString(mapping.AttributeRoleMSPs[role], clientMSPID) {
+				roles[role] = true
+			}
+		}
+	}
+
+	caller := &Caller{MSPID: clientMSPID, Roles: make([]string, 0, len(roles))}
+	for role := range roles {
+		caller.Roles = append(caller.Roles, role)
+	}
+	sort.Strings(caller.Roles)
+
+	return caller, nil
+}
+
+// authorize : 함수별 역할 표에 따라 호출자의 권한을 확인
+func authorize(ctx contractapi.TransactionContextInterface, function string) (*Caller, error) {
+	caller, err := resolveCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if openFunctions[function] {
+		return caller, nil
+	}
+
+	// 역할 표에 없는 함수는 등록 누락으로 보고 거부
+	required, guarded := functionRoles[function]
+	if guarded && caller.hasAnyRole(required...) {
+		return caller, nil
+	}
+
+	return nil, &PermissionError{Function: function, MSPID: caller.MSPID, Required: required, Held: caller.Roles}
+}
+
+// SetRoleMapping : 역할 → 조직 매핑 설정 (새 조직 추가 시 코드 변경 없이 매핑만 갱신)
+func (s *PublicContract) SetRoleMapping(ctx contractapi.TransactionContextInterface, mappingJSON string) error {
+	if _, err := authorize(ctx, "SetRoleMapping"); err != nil {
+		return err
+	}
+
+	var mapping RoleMapping
+	err := json.Unmarshal([]byte(mappingJSON), &mapping)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal role mapping: %v", err)
+	}
+	if err := mapping.validate(); err != nil {
+		return err
+	}
+	if mapping.AttributeRoleMSPs == nil {
+		mapping.AttributeRoleMSPs = make(map[string][]string)
+	}
+
+	mappingKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{roleMappingConfigName})
+	if err != nil {
+		return fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	mappingAsBytes, err := json.Marshal(mapping)
+	if err != nil {
+		return fmt.Errorf("failed to marshal role mapping: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(mappingKey, mappingAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put role mapping: %v", err)
+	}
+
+	return emitEvent(ctx, EventPolicyUpdated, eventAssetConfig, roleMappingConfigName, PolicyUpdatedPayload{Policy: roleMappingConfigName, Value: mapping})
+}
+
+// QueryRoleMapping : 현재 적용 중인 역할 매핑 조회
+func (s *PublicContract) QueryRoleMapping(ctx contractapi.TransactionContextInterface) (*RoleMapping, error) {
+	return readRoleMapping(ctx)
+}
+
+// QueryCallerRoles : 호출자의 MSP와 보유 역할 조회
+func (s *PublicContract) QueryCallerRoles(ctx contractapi.TransactionContextInterface) (*Caller, error) {
+	return resolveCaller(ctx)
+}
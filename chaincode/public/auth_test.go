@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 모든 트랜잭션은 역할 표나 공개 목록 중 정확히 한 곳에 등록되어야 함
+func TestEveryTransactionIsRegistered(t *testing.T) {
+	embedded := reflect.TypeOf(&contractapi.Contract{})
+	contract := reflect.TypeOf(&PublicContract{})
+	transactions := map[string]bool{}
+	for i := 0; i < contract.NumMethod(); i++ {
+		name := contract.Method(i).Name
+		if _, ok := embedded.MethodByName(name); ok {
+			continue
+		}
+		transactions[name] = true
+		_, guarded := functionRoles[name]
+		if guarded == openFunctions[name] {
+			t.Errorf("%s: in role table %v, in open functions %v", name, guarded, openFunctions[name])
+		}
+	}
+	for name := range functionRoles {
+		if !transactions[name] {
+			t.Errorf("role table entry %s is not a transaction", name)
+		}
+	}
+	for name := range openFunctions {
+		if !transactions[name] {
+			t.Errorf("open function %s is not a transaction", name)
+		}
+	}
+}
+
+func TestRoleMappingSeparatesAdminFromVerifier(t *testing.T) {
+	if err := defaultRoleMapping().validate(); err != nil {
+		t.Fatalf("default mapping: %v", err)
+	}
+
+	m := defaultRoleMapping()
+	m.RoleMSPs[roleAdmin] = []string{"Org7MSP"}
+	if err := m.validate(); err == nil {
+		t.Error("admin granted to every member of the verifier MSP was accepted")
+	}
+
+	m = defaultRoleMapping()
+	m.AttributeRoleMSPs = map[string][]string{}
+	if err := m.validate(); err == nil {
+		t.Error("mapping without admin was accepted")
+	}
+}
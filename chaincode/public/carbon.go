@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeCarbonFootprint = "carbonFootprint"
+
+	// 탄소발자국 기록 키 (carbonFootprint~배터리ID) : 배터리당 한 건
+	carbonFootprintObjectType = "carbonFootprint"
+)
+
+// 탄소발자국 수명 주기 단계 (요람에서 출고까지)
+const (
+	// 신규 원자재 채굴·정제
+	stageRawMaterialAcquisition = "rawMaterialAcquisition"
+	// 재활용 원자재 회수 공정 (해체 시 재활용사가 신고한 공정 배출량)
+	stageRecycledMaterialProcessing = "recycledMaterialProcessing"
+	// 배터리 제조 공정의 에너지 사용
+	stageManufacturing = "manufacturing"
+)
+
+var carbonFootprintStages = []string{stageRawMaterialAcquisition, stageRecycledMaterialProcessing, stageManufacturing}
+
+// ManufacturingEmissions : 제조 공정 에너지 사용에 따른 배출량
+type ManufacturingEmissions struct {
+	EnergyKWh      float64 `json:"energyKWh"`
+	EmissionFactor float64 `json:"emissionFactor"` // kgCO2e/kWh
+	EmissionSource string  `json:"emissionSource"`
+	Emissions      float64 `json:"emissions"` // kgCO2e
+}
+
+// MaterialEmission : 배터리에 투입된 원자재 로트별 배출량
+type MaterialEmission struct {
+	MaterialID     string  `json:"materialID"`
+	MaterialType   string  `json:"materialType"`
+	Stage          string  `json:"stage"`
+	Quantity       float64 `json:"quantity"`       // kg
+	EmissionFactor float64 `json:"emissionFactor"` // kgCO2e/kg
+	EmissionSource string  `json:"emissionSource"` // 배출 계수가 없는 로트는 빈 값
+	Emissions      float64 `json:"emissions"`      // kgCO2e
+}
+
+// StageEmission : 수명 주기 단계별 배출량과 비중
+type StageEmission struct {
+	Stage        string  `json:"stage"`
+	Emissions    float64 `json:"emissions"` // kgCO2e
+	SharePercent float64 `json:"sharePercent"`
+}
+
+// CarbonFootprintRecord : 배터리 한 개의 요람에서 출고까지 탄소발자국 (제조 시 계산)
+// 배출 계수가 없는 로트나 제조 에너지 정보가 없으면 Complete가 false이며 해당 항목은 0으로 합산된다.
+type CarbonFootprintRecord struct {
+	DocType                string                 `json:"docType"`
+	BatteryID              string                 `json:"batteryID"`
+	Total                  float64                `json:"total"`  // kgCO2e
+	PerKWh                 float64                `json:"perKWh"` // kgCO2e/kWh (정격 에너지를 알 수 없으면 0)
+	Stages                 []StageEmission        `json:"stages"`
+	ByMaterialType         map[string]float64     `json:"byMaterialType"`
+	Materials              []MaterialEmission     `json:"materials"`
+	Manufacturing          ManufacturingEmissions `json:"manufacturing"`
+	MissingEmissionFactors []string               `json:"missingEmissionFactors"`
+	Complete               bool                   `json:"complete"`
+	TxID                   string                 `json:"txID"`
+	CalculatedAt           string                 `json:"calculatedAt"`
+}
+
+// roundEmissions : 배출량은 소수점 셋째 자리까지 반올림 (gCO2e 단위)
+func roundEmissions(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// validateEmissionFactor : 배출 계수는 음수일 수 없고, 0보다 크면 출처가 필요
+func validateEmissionFactor(subject string, factor float64, source string) error {
+	if factor < 0 || math.IsNaN(factor) || math.IsInf(factor, 0) {
+		return fmt.Errorf("emission factor for %s must be a non-negative number: %v", subject, factor)
+	}
+	if factor > 0 && strings.TrimSpace(source) == "" {
+		return fmt.Errorf("emission source is required for the emission factor of %s", subject)
+	}
+	return nil
+}
+
+// parseManufacturingEmissions : 제조 에너지 JSON 해석 ({"energyKWh": 350, "emissionFactor": 0.45, "emissionSource": "..."})
+// 빈 문자열이면 제조 에너지 정보 없이 nil 반환
+func parseManufacturingEmissions(manufacturingEnergyJSON string) (*ManufacturingEmissions, error) {
+	if strings.TrimSpace(manufacturingEnergyJSON) == "" {
+		return nil, nil
+	}
+
+	manufacturing := new(ManufacturingEmissions)
+	err := json.Unmarshal([]byte(manufacturingEnergyJSON), manufacturing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manufacturing energy: %v", err)
+	}
+	if manufacturing.EnergyKWh < 0 {
+		return nil, fmt.Errorf("manufacturing energy must not be negative: %v", manufacturing.EnergyKWh)
+	}
+	err = validateEmissionFactor("manufacturing energy", manufacturing.EmissionFactor, manufacturing.EmissionSource)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(manufacturing.EmissionSource) == "" {
+		return nil, fmt.Errorf("emission source is required for manufacturing energy")
+	}
+
+	manufacturing.Emissions = roundEmissions(manufacturing.EnergyKWh * manufacturing.EmissionFactor)
+	return manufacturing, nil
+}
+
+// newCarbonFootprint : 소비한 로트별 배출량과 제조 배출량으로 배터리 탄소발자국을 계산
+// lots는 BOM에서 참조한 원자재 로트 (MaterialID 기준), 로트별 투입량은 BOM 항목을 합산한다.
+func newCarbonFootprint(battery *Battery, lots map[string]*RawMaterial, manufacturing *ManufacturingEmissions) *CarbonFootprintRecord {
+	record := &CarbonFootprintRecord{
+		BatteryID:              battery.BatteryID,
+		Stages:                 []StageEmission{},
+		ByMaterialType:         make(map[string]float64),
+		Materials:              []MaterialEmission{},
+		MissingEmissionFactors: []string{},
+		Complete:               true,
+	}
+
+	quantities := make(map[string]int64) // mg
+	materialTypes := make(map[string]string)
+	for _, detail := range battery.RawMaterials {
+		quantities[detail.MaterialID] += detail.Quantity.milligrams()
+		materialTypes[detail.MaterialID] = detail.MaterialType
+	}
+	materialIDs := make([]string, 0, len(quantities))
+	for materialID := range quantities {
+		materialIDs = append(materialIDs, materialID)
+	}
+	sort.Strings(materialIDs)
+
+	stageTotals := make(map[string]float64)
+	for _, materialID := range materialIDs {
+		emission := MaterialEmission{
+			MaterialID:   materialID,
+			MaterialType: materialTypes[materialID],
+			Stage:        stageRawMaterialAcquisition,
+			Quantity:     milligramsToKilograms(quantities[materialID]),
+		}
+		if lot := lots[materialID]; lot != nil {
+			if lot.Status == "RECYCLED" {
+				emission.Stage = stageRecycledMaterialProcessing
+			}
+			emission.EmissionFactor = lot.EmissionFactor
+			emission.EmissionSource = lot.EmissionSource
+		}
+		if emission.EmissionSource == "" {
+			record.MissingEmissionFactors = append(record.MissingEmissionFactors, materialID)
+			record.Complete = false
+		}
+		emission.Emissions = roundEmissions(emission.Quantity * emission.EmissionFactor)
+
+		stageTotals[emission.Stage] += emission.Emissions
+		record.ByMaterialType[emission.MaterialType] = roundEmissions(record.ByMaterialType[emission.MaterialType] + emission.Emissions)
+		record.Materials = append(record.Materials, emission)
+	}
+
+	if manufacturing != nil {
+		record.Manufacturing = *manufacturing
+		stageTotals[stageManufacturing] += manufacturing.Emissions
+	} else {
+		record.Complete = false
+	}
+
+	for _, stage := range carbonFootprintStages {
+		record.Total += stageTotals[stage]
+	}
+	record.Total = roundEmissions(record.Total)
+
+	for _, stage := range carbonFootprintStages {
+		stageEmission := StageEmission{Stage: stage, Emissions: roundEmissions(stageTotals[stage])}
+		if record.Total > 0 {
+			stageEmission.SharePercent = math.Round(stageTotals[stage]/record.Total*10000) / 100
+		}
+		record.Stages = append(record.Stages, stageEmission)
+	}
+
+	// 기능 단위(정격 에너지 kWh = 용량 Ah × 전압 V / 1000)당 배출량
+	energyKWh := battery.Capacity * battery.Voltage / 1000
+	if energyKWh > 0 {
+		record.PerKWh = roundEmissions(record.Total / energyKWh)
+	}
+
+	return record
+}
+
+func carbonFootprintKey(ctx contractapi.TransactionContextInterface, batteryID string) (string, error) {
+	recordKey, err := ctx.GetStub().CreateCompositeKey(carbonFootprintObjectType, []string{batteryID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create carbon footprint key: %v", err)
+	}
+	return recordKey, nil
+}
+
+// saveCarbonFootprint : 탄소발자국 기록 저장
+func saveCarbonFootprint(ctx contractapi.TransactionContextInterface, record *CarbonFootprintRecord) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	record.DocType = docTypeCarbonFootprint
+	record.TxID = ctx.GetStub().GetTxID()
+	record.CalculatedAt = now.Format(time.RFC3339)
+
+	recordKey, err := carbonFootprintKey(ctx, record.BatteryID)
+	if err != nil {
+		return err
+	}
+
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal carbon footprint: %v", err)
+	}
+
+	return ctx.GetStub().PutState(recordKey, recordAsBytes)
+}
+
+// readCarbonFootprint : 배터리의 탄소발자국 기록 (없으면 nil)
+func readCarbonFootprint(ctx contractapi.TransactionContextInterface, batteryID string) (*CarbonFootprintRecord, error) {
+	recordKey, err := carbonFootprintKey(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAsBytes, err := ctx.GetStub().GetState(recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read carbon footprint: %v", err)
+	}
+	if recordAsBytes == nil {
+		return nil, nil
+	}
+
+	record := new(CarbonFootprintRecord)
+	err = json.Unmarshal(recordAsBytes, record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal carbon footprint: %v", err)
+	}
+
+	return record, nil
+}
+
+// QueryCarbonFootprint : 배터리 탄소발자국의 수명 주기 단계별, 원자재별 내역 조회
+func (s *PublicContract) QueryCarbonFootprint(ctx contractapi.TransactionContextInterface, batteryID string) (*CarbonFootprintRecord, error) {
+	record, err := readCarbonFootprint(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("no carbon footprint for battery %s", batteryID)
+	}
+
+	return record, nil
+}
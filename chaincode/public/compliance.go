@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeRecycledContentCompliance = "recycledContentCompliance"
+
+	// 재생 원료 함량 평가 결과 키 (recycledContentCompliance~배터리ID)
+	recycledContentComplianceObjectType = "recycledContentCompliance"
+
+	recycledContentProfileConfigName = "recycledContentProfile"
+)
+
+// 재생 원료 함량 평가 상태
+const (
+	complianceCompliant     = "COMPLIANT"
+	complianceNonCompliant  = "NON_COMPLIANT"
+	complianceNotApplicable = "NOT_APPLICABLE" // 제조일 기준으로 시행 중인 최소 함량이 없음
+)
+
+// 집계 기간 단위
+const (
+	periodYear    = "year"
+	periodQuarter = "quarter"
+	periodMonth   = "month"
+)
+
+// RecycledContentTarget : 원자재별 최소 재생 원료 함량과 시행일
+type RecycledContentTarget struct {
+	MaterialType   string  `json:"materialType"`
+	MinimumPercent float64 `json:"minimumPercent"`
+	EffectiveFrom  string  `json:"effectiveFrom"` // YYYY-MM-DD 또는 RFC3339
+}
+
+// RecycledContentProfile : 재생 원료 함량 준수 기준
+// 같은 원자재에 여러 기준이 있으면 제조일 기준으로 가장 최근에 시행된 기준을 적용한다.
+type RecycledContentProfile struct {
+	Name    string                  `json:"name"`
+	Targets []RecycledContentTarget `json:"targets"`
+}
+
+// defaultRecycledContentProfile : 설정이 없을 때 사용하는 기준
+// (Regulation (EU) 2023/1542 제8조: 2031년 코발트 16%, 리튬 6%, 니켈 6% / 2036년 26%, 12%, 15%)
+func defaultRecycledContentProfile() *RecycledContentProfile {
+	return &RecycledContentProfile{
+		Name: "EU 2023/1542 Article 8",
+		Targets: []RecycledContentTarget{
+			{MaterialType: "Cobalt", MinimumPercent: 16, EffectiveFrom: "2031-08-18"},
+			{MaterialType: "Lithium", MinimumPercent: 6, EffectiveFrom: "2031-08-18"},
+			{MaterialType: "Nickel", MinimumPercent: 6, EffectiveFrom: "2031-08-18"},
+			{MaterialType: "Cobalt", MinimumPercent: 26, EffectiveFrom: "2036-08-18"},
+			{MaterialType: "Lithium", MinimumPercent: 12, EffectiveFrom: "2036-08-18"},
+			{MaterialType: "Nickel", MinimumPercent: 15, EffectiveFrom: "2036-08-18"},
+		},
+	}
+}
+
+func (p *RecycledContentProfile) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("recycled content profile name must not be empty")
+	}
+	for _, target := range p.Targets {
+		if strings.TrimSpace(target.MaterialType) == "" {
+			return fmt.Errorf("recycled content target material type must not be empty")
+		}
+		if target.MinimumPercent < 0 || target.MinimumPercent > 100 {
+			return fmt.Errorf("minimum recycled content for %s must be between 0 and 100: %v", target.MaterialType, target.MinimumPercent)
+		}
+		if _, err := parseRecordDate(target.EffectiveFrom); err != nil {
+			return fmt.Errorf("invalid effective date for %s: %v", target.MaterialType, err)
+		}
+	}
+	return nil
+}
+
+// activeTargets : 기준 시점에 시행 중인 원자재별 기준 (원자재당 가장 최근 시행 기준)
+func (p *RecycledContentProfile) activeTargets(at time.Time) map[string]RecycledContentTarget {
+	active := make(map[string]RecycledContentTarget)
+	effective := make(map[string]time.Time)
+	for _, target := range p.Targets {
+		from, err := parseRecordDate(target.EffectiveFrom)
+		if err != nil || from.After(at) {
+			continue
+		}
+		if current, exists := effective[target.MaterialType]; !exists || from.After(current) {
+			active[target.MaterialType] = target
+			effective[target.MaterialType] = from
+		}
+	}
+	return active
+}
+
+// MaterialCompliance : 원자재별 재생 원료 함량 평가
+type MaterialCompliance struct {
+	MaterialType    string  `json:"materialType"`
+	RecycledPercent float64 `json:"recycledPercent"`
+	MinimumPercent  float64 `json:"minimumPercent"`
+	EffectiveFrom   string  `json:"effectiveFrom"`
+	Passed          bool    `json:"passed"`
+}
+
+// RecycledContentCompliance : 배터리의 재생 원료 함량 평가 결과 (제조 시 평가)
+type RecycledContentCompliance struct {
+	DocType     string               `json:"docType"`
+	BatteryID   string               `json:"batteryID"`
+	ProfileName string               `json:"profileName"`
+	Status      string               `json:"status"`
+	Materials   []MaterialCompliance `json:"materials"`
+	TxID        string               `json:"txID"`
+	EvaluatedAt string               `json:"evaluatedAt"`
+}
+
+// readRecycledContentProfile : 원장에 저장된 재생 원료 함량 기준 (없으면 기본 기준)
+func readRecycledContentProfile(ctx contractapi.TransactionContextInterface) (*RecycledContentProfile, error) {
+	profileKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{recycledContentProfileConfigName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	profileAsBytes, err := ctx.GetStub().GetState(profileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recycled content profile: %v", err)
+	}
+	if profileAsBytes == nil {
+		return defaultRecycledContentProfile(), nil
+	}
+
+	profile := new(RecycledContentProfile)
+	err = json.Unmarshal(profileAsBytes, profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal recycled content profile: %v", err)
+	}
+	if profile.Targets == nil {
+		profile.Targets = []RecycledContentTarget{}
+	}
+
+	return profile, nil
+}
+
+// evaluateRecycledContent : 배터리의 원자재별 재생 원료 함량을 제조일 기준 시행 중인 기준과 비교
+// 배터리에 포함되지 않은 원자재의 기준은 평가하지 않는다.
+func evaluateRecycledContent(battery *Battery, profile *RecycledContentProfile) *RecycledContentCompliance {
+	result := &RecycledContentCompliance{
+		BatteryID:   battery.BatteryID,
+		ProfileName: profile.Name,
+		Status:      complianceNotApplicable,
+		Materials:   []MaterialCompliance{},
+	}
+
+	contained := make(map[string]bool)
+	for _, detail := range battery.RawMaterials {
+		contained[detail.MaterialType] = true
+	}
+
+	active := profile.activeTargets(battery.ManufactureDate)
+	materialTypes := make([]string, 0, len(active))
+	for materialType := range active {
+		if contained[materialType] {
+			materialTypes = append(materialTypes, materialType)
+		}
+	}
+	sort.Strings(materialTypes)
+
+	for _, materialType := range materialTypes {
+		target := active[materialType]
+		material := MaterialCompliance{
+			MaterialType:    materialType,
+			RecycledPercent: battery.RecyclingRatesByMaterial[materialType],
+			MinimumPercent:  target.MinimumPercent,
+			EffectiveFrom:   target.EffectiveFrom,
+		}
+		material.Passed = material.RecycledPercent >= material.MinimumPercent
+
+		if result.Status != complianceNonCompliant {
+			result.Status = complianceCompliant
+		}
+		if !material.Passed {
+			result.Status = complianceNonCompliant
+		}
+		result.Materials = append(result.Materials, material)
+	}
+
+	return result
+}
+
+func recycledContentComplianceKey(ctx contractapi.TransactionContextInterface, batteryID string) (string, error) {
+	resultKey, err := ctx.GetStub().CreateCompositeKey(recycledContentComplianceObjectType, []string{batteryID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create compliance key: %v", err)
+	}
+	return resultKey, nil
+}
+
+// saveRecycledContentCompliance : 평가 결과 저장
+func saveRecycledContentCompliance(ctx contractapi.TransactionContextInterface, result *RecycledContentCompliance) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	result.DocType = docTypeRecycledContentCompliance
+	result.TxID = ctx.GetStub().GetTxID()
+	result.EvaluatedAt = now.Format(time.RFC3339)
+
+	resultKey, err := recycledContentComplianceKey(ctx, result.BatteryID)
+	if err != nil {
+		return err
+	}
+
+	resultAsBytes, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal compliance result: %v", err)
+	}
+
+	return ctx.GetStub().PutState(resultKey, resultAsBytes)
+}
+
+// SetRecycledContentProfile : 재생 원료 최소 함량 기준 설정 (이후 제조되는 배터리부터 적용)
+func (s *PublicContract) SetRecycledContentProfile(ctx contractapi.TransactionContextInterface, profileJSON string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "SetRecycledContentProfile")
+	if err != nil {
+		return err
+	}
+
+	var profile RecycledContentProfile
+	err = json.Unmarshal([]byte(profileJSON), &profile)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal recycled content profile: %v", err)
+	}
+	if err := profile.validate(); err != nil {
+		return err
+	}
+	if profile.Targets == nil {
+		profile.Targets = []RecycledContentTarget{}
+	}
+
+	profileKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{recycledContentProfileConfigName})
+	if err != nil {
+		return fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	profileAsBytes, err := json.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recycled content profile: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(profileKey, profileAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put recycled content profile: %v", err)
+	}
+
+	return emitEvent(ctx, EventPolicyUpdated, eventAssetConfig, recycledContentProfileConfigName, PolicyUpdatedPayload{Policy: recycledContentProfileConfigName, Value: profile})
+}
+
+// QueryRecycledContentProfile : 현재 적용 중인 재생 원료 함량 기준 조회
+func (s *PublicContract) QueryRecycledContentProfile(ctx contractapi.TransactionContextInterface) (*RecycledContentProfile, error) {
+	return readRecycledContentProfile(ctx)
+}
+
+// QueryRecycledContentCompliance : 배터리 제조 시 평가된 원자재별 재생 원료 함량 준수 결과 조회
+func (s *PublicContract) QueryRecycledContentCompliance(ctx contractapi.TransactionContextInterface, batteryID string) (*RecycledContentCompliance, error) {
+	resultKey, err := recycledContentComplianceKey(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+
+	resultAsBytes, err := ctx.GetStub().GetState(resultKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compliance result: %v", err)
+	}
+	if resultAsBytes == nil {
+		return nil, fmt.Errorf("no recycled content compliance result for battery %s", batteryID)
+	}
+
+	result := new(RecycledContentCompliance)
+	err = json.Unmarshal(resultAsBytes, result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal compliance result: %v", err)
+	}
+
+	return result, nil
+}
+
+// FleetMaterialContent : 원자재별 투입량 합계와 재생 원료 비중
+type FleetMaterialContent struct {
+	MaterialType     string  `json:"materialType"`
+	TotalQuantity    float64 `json:"totalQuantity"`    // kg
+	RecycledQuantity float64 `json:"recycledQuantity"` // kg
+	RecycledPercent  float64 `json:"recycledPercent"`
+}
+
+// FleetRecycledContent : 제조사·기간별 재생 원료 함량 집계
+type FleetRecycledContent struct {
+	Manufacturer  string                 `json:"manufacturer"`
+	Period        string                 `json:"period"`
+	BatteryCount  int                    `json:"batteryCount"`
+	Compliant     int                    `json:"compliant"`
+	NonCompliant  int                    `json:"nonCompliant"`
+	NotApplicable int                    `json:"notApplicable"`
+	NotEvaluated  int                    `json:"notEvaluated"` // 평가 도입 이전에 제조된 배터리
+	Materials     []FleetMaterialContent `json:"materials"`
+}
+
+// periodOf : 제조일이 속한 집계 기간 (2031, 2031-Q3, 2031-08)
+func periodOf(date time.Time, granularity string) string {
+	date = date.UTC()
+	switch granularity {
+	case periodQuarter:
+		return fmt.Sprintf("%d-Q%d", date.Year(), (int(date.Month())-1)/3+1)
+	case periodMonth:
+		return date.Format("2006-01")
+	default:
+		return fmt.Sprintf("%d", date.Year())
+	}
+}
+
+// QueryFleetRecycledContent : 제조사·기간별 재생 원료 함량(투입량 가중)과 준수 현황 집계
+// manufacturer가 비어 있으면 전체 제조사, granularity는 year(기본값), quarter, month
+func (s *PublicContract) QueryFleetRecycledContent(ctx contractapi.TransactionContextInterface, manufacturer string, granularity string) ([]FleetRecycledContent, error) {
+	if granularity == "" {
+		granularity = periodYear
+	}
+	if granularity != periodYear && granularity != periodQuarter && granularity != periodMonth {
+		return nil, fmt.Errorf("invalid period granularity %q: expected %s, %s or %s", granularity, periodYear, periodQuarter, periodMonth)
+	}
+
+	batteries, err := s.queryBatteriesByIndex(ctx, indexBatteryByStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	type fleetKey struct{ manufacturer, period string }
+	groups := make(map[fleetKey]*FleetRecycledContent)
+	totals := make(map[fleetKey]map[string]int64) // mg
+	recycled := make(map[fleetKey]map[string]float64)
+
+	for i := range batteries {
+		battery := &batteries[i]
+		if manufacturer != "" && battery.ManufacturerName != manufacturer {
+			continue
+		}
+
+		key := fleetKey{battery.ManufacturerName, periodOf(battery.ManufactureDate, granularity)}
+		group, exists := groups[key]
+		if !exists {
+			group = &FleetRecycledContent{Manufacturer: key.manufacturer, Period: key.period, Materials: []FleetMaterialContent{}}
+			groups[key] = group
+			totals[key] = make(map[string]int64)
+			recycled[key] = make(map[string]float64)
+		}
+
+		group.BatteryCount++
+		switch battery.ComplianceStatus {
+		case complianceCompliant:
+			group.Compliant++
+		case complianceNonCompliant:
+			group.NonCompliant++
+		case complianceNotApplicable:
+			group.NotApplicable++
+		default:
+			group.NotEvaluated++
+		}
+
+		contained := make(map[string]int64) // mg
+		for _, detail := range battery.RawMaterials {
+			contained[detail.MaterialType] += detail.Quantity.milligrams()
+		}
+		for materialType, quantity := range contained {
+			totals[key][materialType] += quantity
+			recycled[key][materialType] += milligramsToKilograms(quantity) * battery.RecyclingRatesByMaterial[materialType] / 100
+		}
+	}
+
+	keys := make([]fleetKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].manufacturer != keys[j].manufacturer {
+			return keys[i].manufacturer < keys[j].manufacturer
+		}
+		return keys[i].period < keys[j].period
+	})
+
+	results := make([]FleetRecycledContent, 0, len(keys))
+	for _, key := range keys {
+		group := groups[key]
+
+		materialTypes := make([]string, 0, len(totals[key]))
+		for materialType := range totals[key] {
+			materialTypes = append(materialTypes, materialType)
+		}
+		sort.Strings(materialTypes)
+
+		for _, materialType := range materialTypes {
+			content := FleetMaterialContent{
+				MaterialType:     materialType,
+				TotalQuantity:    milligramsToKilograms(totals[key][materialType]),
+				RecycledQuantity: math.Round(recycled[key][materialType]*1000) / 1000,
+			}
+			if content.TotalQuantity > 0 {
+				content.RecycledPercent = math.Round(recycled[key][materialType]/content.TotalQuantity*10000) / 100
+			}
+			group.Materials = append(group.Materials, content)
+		}
+		results = append(results, *group)
+	}
+
+	return results, nil
+}
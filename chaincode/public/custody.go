@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const docTypeCustodyTransfer = "custodyTransfer"
+
+// 소유권 이전 키
+const (
+	// 대기 중인 이전 제안 (custody~offer~배터리ID, 배터리당 하나)
+	custodyOfferObjectType = "custody~offer"
+	// 완료된 소유권 이전 기록 (custody~배터리ID~시각~이전ID)
+	custodyObjectType = "custody"
+)
+
+// 소유권 이전 상태
+const (
+	custodyOffered  = "OFFERED"
+	custodyAccepted = "ACCEPTED"
+)
+
+// CustodyTransfer : 배터리 소유권 이전 (From은 제조 시 빈 값)
+type CustodyTransfer struct {
+	DocType    string `json:"docType"`
+	TransferID string `json:"transferID"`
+	BatteryID  string `json:"batteryID"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Status     string `json:"status"`
+	OfferedAt  string `json:"offeredAt"`
+	AcceptedAt string `json:"acceptedAt"`
+	TxID       string `json:"txID"` // 이전이 완료된 트랜잭션
+}
+
+// requireBatteryOwner : 호출 조직이 배터리의 현재 소유자인지 확인
+// 소유자가 없는 배터리(소유자 도입 이전 배터리)는 MigrateBatteryOwners로 소유자를 채우기 전까지 아무도 처리할 수 없다.
+func requireBatteryOwner(caller *Caller, battery *Battery) error {
+	if battery.Owner == "" {
+		return fmt.Errorf("battery %s has no owner: run MigrateBatteryOwners first", battery.BatteryID)
+	}
+	if battery.Owner != caller.MSPID {
+		return fmt.Errorf("caller %s is not the owner of battery %s (owner: %s)", caller.MSPID, battery.BatteryID, battery.Owner)
+	}
+	return nil
+}
+
+// recordCustody : 완료된 소유권 이전을 배터리별 기록에 저장
+func recordCustody(ctx contractapi.TransactionContextInterface, transfer *CustodyTransfer) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	transfer.DocType = docTypeCustodyTransfer
+	transfer.Status = custodyAccepted
+	transfer.AcceptedAt = now.Format(time.RFC3339Nano)
+	transfer.TxID = ctx.GetStub().GetTxID()
+
+	// 키에 고정 길이 시각을 넣어 부분 키 조회 결과가 시간 순으로 정렬되도록 함
+	custodyKey, err := ctx.GetStub().CreateCompositeKey(custodyObjectType,
+		[]string{transfer.BatteryID, fmt.Sprintf("%020d", now.UnixNano()), transfer.TransferID})
+	if err != nil {
+		return fmt.Errorf("failed to create custody key: %v", err)
+	}
+
+	transferAsBytes, err := json.Marshal(transfer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal custody transfer: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(custodyKey, transferAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store custody transfer: %v", err)
+	}
+
+	return nil
+}
+
+// recordInitialCustody : 제조 조직을 첫 소유자로 기록
+func recordInitialCustody(ctx contractapi.TransactionContextInterface, battery *Battery) error {
+	transferID, err := newID(ctx, "CUSTODY")
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	return recordCustody(ctx, &CustodyTransfer{
+		TransferID: transferID,
+		BatteryID:  battery.BatteryID,
+		To:         battery.Owner,
+		OfferedAt:  now.Format(time.RFC3339Nano),
+	})
+}
+
+func custodyOfferKey(ctx contractapi.TransactionContextInterface, batteryID string) (string, error) {
+	offerKey, err := ctx.GetStub().CreateCompositeKey(custodyOfferObjectType, []string{batteryID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create custody offer key: %v", err)
+	}
+	return offerKey, nil
+}
+
+// readCustodyOffer : 배터리의 대기 중인 이전 제안 (없으면 nil)
+func readCustodyOffer(ctx contractapi.TransactionContextInterface, batteryID string) (*CustodyTransfer, error) {
+	offerKey, err := custodyOfferKey(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+
+	offerAsBytes, err := ctx.GetStub().GetState(offerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custody offer: %v", err)
+	}
+	if offerAsBytes == nil {
+		return nil, nil
+	}
+
+	offer := new(CustodyTransfer)
+	err = json.Unmarshal(offerAsBytes, offer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal custody offer: %v", err)
+	}
+
+	return offer, nil
+}
+
+// OfferTransfer : 현재 소유자가 배터리 소유권을 다른 조직(MSP)에 이전하겠다고 제안
+// 받는 조직이 AcceptTransfer를 호출해야 소유권이 바뀐다.
+func (s *PublicContract) OfferTransfer(ctx contractapi.TransactionContextInterface, batteryID string, newOwner string) (*CustodyTransfer, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "OfferTransfer")
+	if err != nil {
+		return nil, err
+	}
+
+	newOwner = strings.TrimSpace(newOwner)
+	if newOwner == "" {
+		return nil, fmt.Errorf("new owner must not be empty")
+	}
+
+	battery, err := s.QueryBatteryDetails(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireBatteryOwner(caller, battery); err != nil {
+		return nil, err
+	}
+	if battery.Status == statusDisassembled {
+		return nil, fmt.Errorf("battery %s is disassembled and cannot be transferred", batteryID)
+	}
+
+	currentOwner := battery.Owner
+	if currentOwner == newOwner {
+		return nil, fmt.Errorf("battery %s is already owned by %s", batteryID, newOwner)
+	}
+
+	pending, err := readCustodyOffer(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return nil, fmt.Errorf("battery %s already has a pending transfer to %s", batteryID, pending.To)
+	}
+
+	transferID, err := newID(ctx, "CUSTODY")
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	offer := &CustodyTransfer{
+		DocType:    docTypeCustodyTransfer,
+		TransferID: transferID,
+		BatteryID:  batteryID,
+		From:       currentOwner,
+		To:         newOwner,
+		Status:     custodyOffered,
+		OfferedAt:  now.Format(time.RFC3339Nano),
+	}
+
+	offerKey, err := custodyOfferKey(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	offerAsBytes, err := json.Marshal(offer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal custody offer: %v", err)
+	}
+	err = ctx.GetStub().PutState(offerKey, offerAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store custody offer: %v", err)
+	}
+
+	err = emitEvent(ctx, EventTransferOffered, eventAssetBattery, batteryID, CustodyEventPayload{Transfer: offer})
+	if err != nil {
+		return nil, err
+	}
+
+	return offer, nil
+}
+
+// AcceptTransfer : 이전 제안을 받은 조직이 수락하여 배터리 소유권을 넘겨받음
+func (s *PublicContract) AcceptTransfer(ctx contractapi.TransactionContextInterface, batteryID string) (*CustodyTransfer, error) {
+	caller, err := resolveCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	offer, err := readCustodyOffer(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if offer == nil {
+		return nil, fmt.Errorf("no pending transfer for battery %s", batteryID)
+	}
+	if offer.To != caller.MSPID {
+		return nil, fmt.Errorf("transfer of battery %s is offered to %s, not %s", batteryID, offer.To, caller.MSPID)
+	}
+
+	battery, err := loadBattery(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if battery.Owner != offer.From {
+		return nil, fmt.Errorf("transfer of battery %s was offered by %s, but the owner is now %s", batteryID, offer.From, battery.Owner)
+	}
+	// 제안 이후 해체된 배터리는 넘겨받을 수 없음
+	if battery.Status == statusDisassembled {
+		return nil, fmt.Errorf("battery %s is disassembled and cannot be transferred", batteryID)
+	}
+
+	battery.Owner = offer.To
+	err = s.saveBattery(ctx, battery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update battery: %v", err)
+	}
+
+	err = recordCustody(ctx, offer)
+	if err != nil {
+		return nil, err
+	}
+
+	offerKey, err := custodyOfferKey(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().DelState(offerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete custody offer: %v", err)
+	}
+
+	err = emitEvent(ctx, EventTransferAccepted, eventAssetBattery, batteryID, CustodyEventPayload{Transfer: offer, Battery: battery})
+	if err != nil {
+		return nil, err
+	}
+
+	return offer, nil
+}
+
+// CancelTransfer : 대기 중인 이전 제안을 철회 (제안한 조직) 또는 거절 (제안받은 조직)
+func (s *PublicContract) CancelTransfer(ctx contractapi.TransactionContextInterface, batteryID string) error {
+	caller, err := resolveCaller(ctx)
+	if err != nil {
+		return err
+	}
+
+	offer, err := readCustodyOffer(ctx, batteryID)
+	if err != nil {
+		return err
+	}
+	if offer == nil {
+		return fmt.Errorf("no pending transfer for battery %s", batteryID)
+	}
+	if caller.MSPID != offer.From && caller.MSPID != offer.To {
+		return fmt.Errorf("caller %s is not a party to the transfer of battery %s", caller.MSPID, batteryID)
+	}
+
+	offerKey, err := custodyOfferKey(ctx, batteryID)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(offerKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete custody offer: %v", err)
+	}
+
+	return emitEvent(ctx, EventTransferCancelled, eventAssetBattery, batteryID, CustodyEventPayload{Transfer: offer})
+}
+
+// QueryPendingTransfer : 배터리의 대기 중인 이전 제안 조회
+func (s *PublicContract) QueryPendingTransfer(ctx contractapi.TransactionContextInterface, batteryID string) (*CustodyTransfer, error) {
+	offer, err := readCustodyOffer(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if offer == nil {
+		return nil, fmt.Errorf("no pending transfer for battery %s", batteryID)
+	}
+
+	return offer, nil
+}
+
+// MigrateBatteryOwners : 소유자 도입 이전 배터리(소유자 빈 값)에 소유자를 부여하고 첫 소유자로 기록
+// ownerMSP가 빈 값이면 제조 역할에 매핑된 유일한 조직을 사용한다.
+func (s *PublicContract) MigrateBatteryOwners(ctx contractapi.TransactionContextInterface, ownerMSP string) (int, error) {
+	if _, err := authorize(ctx, "MigrateBatteryOwners"); err != nil {
+		return 0, err
+	}
+
+	mapping, err := readRoleMapping(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ownerMSP, err = ownerOrSoleRoleMSP(mapping, strings.TrimSpace(ownerMSP), roleManufacturer)
+	if err != nil {
+		return 0, err
+	}
+
+	batteries, err := s.loadBatteriesByIndex(ctx, indexBatteryByStatus)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for i := range batteries {
+		battery := &batteries[i]
+		if battery.Owner != "" {
+			continue
+		}
+
+		battery.Owner = ownerMSP
+		if err := recordInitialCustody(ctx, battery); err != nil {
+			return migrated, err
+		}
+		if err := s.saveBattery(ctx, battery); err != nil {
+			return migrated, fmt.Errorf("failed to update battery: %v", err)
+		}
+		migrated++
+	}
+
+	err = emitEvent(ctx, EventMigrationCompleted, eventAssetConfig, "batteryOwners", MigrationCompletedPayload{Migration: "MigrateBatteryOwners", Migrated: migrated})
+	if err != nil {
+		return migrated, err
+	}
+
+	return migrated, nil
+}
+
+// QueryCustodyHistory : 배터리의 소유권 이전 기록을 시간 순으로 조회 (제조 시 첫 소유자 포함)
+func (s *PublicContract) QueryCustodyHistory(ctx contractapi.TransactionContextInterface, batteryID string) ([]CustodyTransfer, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(custodyObjectType, []string{batteryID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query custody history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	transfers := []CustodyTransfer{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var transfer CustodyTransfer
+		err = json.Unmarshal(queryResponse.Value, &transfer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal custody transfer: %v", err)
+		}
+
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, nil
+}
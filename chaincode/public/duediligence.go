@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const dueDiligencePolicyConfigName = "dueDiligencePolicy"
+
+// 고위험 원산지 로트의 배터리 투입 처리 방식
+const (
+	highRiskRefuse          = "REFUSE"           // 투입 거부
+	highRiskRequireOverride = "REQUIRE_OVERRIDE" // BOM 항목에 사유(highRiskOverride)가 있을 때만 허용
+)
+
+// 실사 검증 결과
+const (
+	findingConformant    = "CONFORMANT"
+	findingNonConformant = "NON_CONFORMANT"
+)
+
+// DueDiligencePolicy : 공급망 실사 기준
+type DueDiligencePolicy struct {
+	HighRiskCountries    []string `json:"highRiskCountries"`    // ISO 3166-1 alpha-2
+	CertificationSchemes []string `json:"certificationSchemes"` // 인정하는 인증 제도
+	HighRiskHandling     string   `json:"highRiskHandling"`
+}
+
+// defaultDueDiligencePolicy : 설정이 없을 때 사용하는 기준
+// (분쟁 및 고위험 지역(CAHRA) 지표 목록을 참고한 기본값으로, 운영 시 SetDueDiligencePolicy로 갱신)
+func defaultDueDiligencePolicy() *DueDiligencePolicy {
+	return &DueDiligencePolicy{
+		HighRiskCountries:    []string{"AF", "CD", "CF", "LY", "ML", "MM", "SD", "SO", "SS", "VE", "YE"},
+		CertificationSchemes: []string{"ASI", "IRMA", "RMI"},
+		HighRiskHandling:     highRiskRequireOverride,
+	}
+}
+
+func (p *DueDiligencePolicy) validate() error {
+	if p.HighRiskHandling != highRiskRefuse && p.HighRiskHandling != highRiskRequireOverride {
+		return fmt.Errorf("invalid high-risk handling %q: expected %s or %s", p.HighRiskHandling, highRiskRefuse, highRiskRequireOverride)
+	}
+	for _, country := range p.HighRiskCountries {
+		if len(strings.TrimSpace(country)) != 2 {
+			return fmt.Errorf("invalid country code %q: expected ISO 3166-1 alpha-2", country)
+		}
+	}
+	if len(p.CertificationSchemes) == 0 {
+		return fmt.Errorf("due diligence policy must accept at least one certification scheme")
+	}
+	return nil
+}
+
+func (p *DueDiligencePolicy) isHighRiskCountry(country string) bool {
+	return containsString(p.HighRiskCountries, strings.ToUpper(country))
+}
+
+func (p *DueDiligencePolicy) acceptsScheme(scheme string) bool {
+	for _, accepted := range p.CertificationSchemes {
+		if strings.EqualFold(accepted, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaterialOrigin : 원자재 로트의 원산지와 인증 증빙
+type MaterialOrigin struct {
+	Country             string `json:"country"` // ISO 3166-1 alpha-2
+	MineOrRefineryID    string `json:"mineOrRefineryID"`
+	CertificationScheme string `json:"certificationScheme"`
+	CertificateRef      string `json:"certificateRef"`
+	CertificateHash     string `json:"certificateHash"` // 인증서 문서의 SHA-256
+	HighRisk            bool   `json:"highRisk"`        // 고위험 원산지 여부 (등록 시 기준 또는 검증 결과)
+}
+
+// DueDiligenceFinding : 검증 기관(Org7)의 실사 검증 결과
+type DueDiligenceFinding struct {
+	Result          string `json:"result"`
+	Findings        string `json:"findings"`
+	HighRisk        bool   `json:"highRisk"`
+	CertificateHash string `json:"certificateHash"` // 검증 시 대조한 인증서 해시 (재활용 로트는 빈 값)
+	VerifierMSP     string `json:"verifierMSP"`
+	TxID            string `json:"txID"`
+	VerifiedAt      string `json:"verifiedAt"`
+}
+
+// readDueDiligencePolicy : 원장에 저장된 실사 기준 (없으면 기본 기준)
+func readDueDiligencePolicy(ctx contractapi.TransactionContextInterface) (*DueDiligencePolicy, error) {
+	policyKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{dueDiligencePolicyConfigName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	policyAsBytes, err := ctx.GetStub().GetState(policyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read due diligence policy: %v", err)
+	}
+	if policyAsBytes == nil {
+		return defaultDueDiligencePolicy(), nil
+	}
+
+	policy := new(DueDiligencePolicy)
+	err = json.Unmarshal(policyAsBytes, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal due diligence policy: %v", err)
+	}
+	if policy.HighRiskCountries == nil {
+		policy.HighRiskCountries = []string{}
+	}
+
+	return policy, nil
+}
+
+// parseMaterialOrigin : 원산지 JSON을 검증하고 고위험 여부를 표시 (빈 문자열이면 nil)
+func parseMaterialOrigin(originJSON string, policy *DueDiligencePolicy) (*MaterialOrigin, error) {
+	if strings.TrimSpace(originJSON) == "" {
+		return nil, nil
+	}
+
+	origin := new(MaterialOrigin)
+	err := json.Unmarshal([]byte(originJSON), origin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal material origin: %v", err)
+	}
+
+	origin.Country = strings.ToUpper(strings.TrimSpace(origin.Country))
+	if len(origin.Country) != 2 {
+		return nil, fmt.Errorf("invalid origin country %q: expected ISO 3166-1 alpha-2", origin.Country)
+	}
+	if strings.TrimSpace(origin.MineOrRefineryID) == "" {
+		return nil, fmt.Errorf("mine or refinery identifier is required")
+	}
+	if !policy.acceptsScheme(origin.CertificationScheme) {
+		return nil, fmt.Errorf("certification scheme %q is not accepted: expected one of [%s]", origin.CertificationScheme, strings.Join(policy.CertificationSchemes, ", "))
+	}
+	if strings.TrimSpace(origin.CertificateRef) == "" {
+		return nil, fmt.Errorf("certificate reference is required")
+	}
+	origin.CertificateHash, err = validateEvidenceHash(origin.CertificateHash)
+	if err != nil {
+		return nil, err
+	}
+	origin.HighRisk = policy.isHighRiskCountry(origin.Country)
+
+	return origin, nil
+}
+
+// isHighRiskLot : 현재 실사 기준으로 로트가 고위험인지 확인
+func isHighRiskLot(material *RawMaterial, policy *DueDiligencePolicy) bool {
+	if material.DueDiligence != nil && material.DueDiligence.HighRisk {
+		return true
+	}
+	return material.Origin != nil && (material.Origin.HighRisk || policy.isHighRiskCountry(material.Origin.Country))
+}
+
+// checkHighRiskLots : 배터리에 투입되는 고위험 로트를 실사 기준에 따라 거부하거나 사유를 요구
+func checkHighRiskLots(rawMaterials map[string]RawMaterialDetail, lots map[string]*RawMaterial, policy *DueDiligencePolicy) error {
+	keys := make([]string, 0, len(rawMaterials))
+	for key := range rawMaterials {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		detail := rawMaterials[key]
+		lot := lots[detail.MaterialID]
+		if lot == nil || !isHighRiskLot(lot, policy) {
+			continue
+		}
+		if policy.HighRiskHandling == highRiskRefuse {
+			return fmt.Errorf("material %s is from a high-risk origin and cannot be used", detail.MaterialID)
+		}
+		if strings.TrimSpace(detail.HighRiskOverride) == "" {
+			return fmt.Errorf("material %s is from a high-risk origin: provide highRiskOverride with a justification", detail.MaterialID)
+		}
+	}
+
+	return nil
+}
+
+// SetMaterialOrigin : 원자재 로트의 원산지와 인증 증빙 등록 (변경 시 기존 실사 검증은 무효화)
+func (s *PublicContract) SetMaterialOrigin(ctx contractapi.TransactionContextInterface, materialID string, originJSON string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "SetMaterialOrigin")
+	if err != nil {
+		return err
+	}
+
+	material, err := loadMaterial(ctx, materialID)
+	if err != nil {
+		return err
+	}
+
+	policy, err := readDueDiligencePolicy(ctx)
+	if err != nil {
+		return err
+	}
+	origin, err := parseMaterialOrigin(originJSON, policy)
+	if err != nil {
+		return err
+	}
+	if origin == nil {
+		return fmt.Errorf("material origin must not be empty")
+	}
+
+	material.Origin = origin
+	material.DueDiligence = nil
+	material.Verified = "NOT VERIFIED"
+
+	err = s.saveMaterial(ctx, material)
+	if err != nil {
+		return fmt.Errorf("failed to update material: %v", err)
+	}
+
+	return emitEvent(ctx, EventMaterialOriginRecorded, eventAssetMaterial, materialID, MaterialEventPayload{Material: material})
+}
+
+// newDueDiligenceFinding : 검증 기관의 실사 결과를 해석하고 로트의 증빙과 대조
+// 신규 로트는 원산지 증빙이 있어야 하며 제출한 인증서 해시가 로트에 등록된 해시와 같아야 한다.
+// 재활용 로트는 원천 배터리의 해체 기록을 증빙으로 사용한다.
+func newDueDiligenceFinding(ctx contractapi.TransactionContextInterface, material *RawMaterial, findingJSON string) (*DueDiligenceFinding, error) {
+	finding := new(DueDiligenceFinding)
+	err := json.Unmarshal([]byte(findingJSON), finding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal due diligence finding: %v", err)
+	}
+	if finding.Result != findingConformant && finding.Result != findingNonConformant {
+		return nil, fmt.Errorf("invalid due diligence result %q: expected %s or %s", finding.Result, findingConformant, findingNonConformant)
+	}
+	if strings.TrimSpace(finding.Findings) == "" {
+		return nil, fmt.Errorf("due diligence findings must not be empty")
+	}
+
+	if material.SourceBatteryID != "" {
+		record, err := readExtractionRecord(ctx, material.SourceBatteryID)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			return nil, fmt.Errorf("recycled material %s has no extraction record for battery %s", material.MaterialID, material.SourceBatteryID)
+		}
+		finding.CertificateHash = ""
+	} else {
+		if material.Origin == nil {
+			return nil, fmt.Errorf("material %s has no origin evidence: register it with SetMaterialOrigin first", material.MaterialID)
+		}
+		finding.CertificateHash, err = validateEvidenceHash(finding.CertificateHash)
+		if err != nil {
+			return nil, err
+		}
+		if finding.CertificateHash != material.Origin.CertificateHash {
+			return nil, fmt.Errorf("certificate hash does not match the evidence registered for material %s", material.MaterialID)
+		}
+	}
+
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	finding.VerifierMSP = clientMSPID
+	finding.TxID = ctx.GetStub().GetTxID()
+	finding.VerifiedAt = now.Format(time.RFC3339)
+
+	return finding, nil
+}
+
+// SetDueDiligencePolicy : 고위험 원산지, 인정 인증 제도, 고위험 로트 처리 방식 설정
+func (s *PublicContract) SetDueDiligencePolicy(ctx contractapi.TransactionContextInterface, policyJSON string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "SetDueDiligencePolicy")
+	if err != nil {
+		return err
+	}
+
+	var policy DueDiligencePolicy
+	err = json.Unmarshal([]byte(policyJSON), &policy)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal due diligence policy: %v", err)
+	}
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	if policy.HighRiskCountries == nil {
+		policy.HighRiskCountries = []string{}
+	}
+	for i, country := range policy.HighRiskCountries {
+		policy.HighRiskCountries[i] = strings.ToUpper(strings.TrimSpace(country))
+	}
+
+	policyKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{dueDiligencePolicyConfigName})
+	if err != nil {
+		return fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	policyAsBytes, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal due diligence policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(policyKey, policyAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put due diligence policy: %v", err)
+	}
+
+	return emitEvent(ctx, EventPolicyUpdated, eventAssetConfig, dueDiligencePolicyConfigName, PolicyUpdatedPayload{Policy: dueDiligencePolicyConfigName, Value: policy})
+}
+
+// QueryDueDiligencePolicy : 현재 적용 중인 실사 기준 조회
+func (s *PublicContract) QueryDueDiligencePolicy(ctx contractapi.TransactionContextInterface) (*DueDiligencePolicy, error) {
+	return readDueDiligencePolicy(ctx)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Fabric은 트랜잭션당 하나의 이벤트만 허용하므로, 트랜잭션 안에서 발생한 이벤트를
+// 모아 AfterTransaction에서 EventEnvelope 하나로 내보낸다.
+const (
+	eventName          = "PublicContractEvents"
+	eventSchemaVersion = "1.0"
+)
+
+// 이벤트 유형
+const (
+	EventMaterialRegistered     = "MaterialRegistered"
+	EventMaterialVerified       = "MaterialVerified"
+	EventMaterialOriginRecorded = "MaterialOriginRecorded"
+	EventMaterialConsumed       = "MaterialConsumed"
+	EventLotSplit               = "LotSplit"
+	EventLotsMerged             = "LotsMerged"
+	EventLotTransferred         = "LotTransferred"
+	EventMaterialsReserved      = "MaterialsReserved"
+	EventReservationReleased    = "ReservationReleased"
+	EventReservationConsumed    = "ReservationConsumed"
+	EventBatteryCreated         = "BatteryCreated"
+	EventBatteryVerified        = "BatteryVerified"
+	EventBatteriesProduced      = "BatteriesProduced"
+	EventVerificationRevoked    = "VerificationRevoked"
+	EventTransferOffered        = "TransferOffered"
+	EventTransferAccepted       = "TransferAccepted"
+	EventTransferCancelled      = "TransferCancelled"
+	EventBatteryPlacedInService = "BatteryPlacedInService"
+	EventUsageReported          = "UsageReported"
+	EventMaintenanceRequested   = "MaintenanceRequested"
+	EventMaintenanceLogged      = "MaintenanceLogged"
+	EventMaintenanceCompleted   = "MaintenanceCompleted"
+	EventAnalysisRequested      = "AnalysisRequested"
+	EventAccidentRecorded       = "AccidentRecorded"
+	EventSecondLifeApproved     = "SecondLifeApproved"
+	EventBatteryGraded          = "BatteryGraded"
+	EventBatteryRepurposed      = "BatteryRepurposed"
+	EventRecycleAvailabilitySet = "RecycleAvailabilitySet"
+	EventMaterialsExtracted     = "MaterialsExtracted"
+	EventPrivateDataRecorded    = "PrivateDataRecorded"
+	EventTelemetryAnchored      = "TelemetryAnchored"
+	EventPolicyUpdated          = "PolicyUpdated"
+	EventMigrationCompleted     = "MigrationCompleted"
+)
+
+// 이벤트 대상 자산 종류
+const (
+	eventAssetMaterial = "material"
+	eventAssetBattery  = "battery"
+	eventAssetConfig   = "config"
+)
+
+// lifecycleEvents : 상태 전이 동작별 이벤트 유형 (changeBatteryStatus에서 사용)
+var lifecycleEvents = map[string]string{
+	actionPlaceInService:      EventBatteryPlacedInService,
+	actionRequestMaintenance:  EventMaintenanceRequested,
+	actionCompleteMaintenance: EventMaintenanceCompleted,
+	actionRequestAnalysis:     EventAnalysisRequested,
+	actionApproveSecondLife:   EventSecondLifeApproved,
+	actionReturnToService:     EventRecycleAvailabilitySet,
+	actionDeclareEndOfLife:    EventRecycleAvailabilitySet,
+}
+
+// ChaincodeEvent : 상태 변경 하나에 대한 이벤트 (Payload는 유형별 구조체의 JSON)
+type ChaincodeEvent struct {
+	Type      string          `json:"type"`
+	AssetType string          `json:"assetType"`
+	AssetID   string          `json:"assetID"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// EventEnvelope : 트랜잭션 하나에서 발생한 이벤트 묶음 (SetEvent의 payload)
+type EventEnvelope struct {
+	SchemaVersion string           `json:"schemaVersion"`
+	TxID          string           `json:"txID"`
+	Timestamp     string           `json:"timestamp"`
+	SubmittingMSP string           `json:"submittingMSP"`
+	Events        []ChaincodeEvent `json:"events"`
+}
+
+// MaterialEventPayload : MaterialRegistered, MaterialVerified, MaterialOriginRecorded, MaterialConsumed
+type MaterialEventPayload struct {
+	Material *RawMaterial `json:"material"`
+}
+
+// LotSplitPayload : LotSplit
+type LotSplitPayload struct {
+	Parent   *RawMaterial  `json:"parent"`
+	Children []RawMaterial `json:"children"`
+}
+
+// LotsMergedPayload : LotsMerged
+type LotsMergedPayload struct {
+	Parents []RawMaterial `json:"parents"`
+	Merged  *RawMaterial  `json:"merged"`
+}
+
+// LotTransferredPayload : LotTransferred
+type LotTransferredPayload struct {
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Material *RawMaterial `json:"material"`
+}
+
+// ReservationEventPayload : MaterialsReserved, ReservationReleased, ReservationConsumed
+type ReservationEventPayload struct {
+	OrderID      string        `json:"orderID"`
+	Reservations []Reservation `json:"reservations"`
+}
+
+// BatteryEventPayload : BatteryCreated, BatteryVerified
+type BatteryEventPayload struct {
+	Battery *Battery `json:"battery"`
+}
+
+// ProductionOrderPayload : BatteriesProduced
+type ProductionOrderPayload struct {
+	Order *ProductionOrder `json:"order"`
+}
+
+// CustodyEventPayload : TransferOffered, TransferAccepted, TransferCancelled (Battery는 수락 시에만)
+type CustodyEventPayload struct {
+	Transfer *CustodyTransfer `json:"transfer"`
+	Battery  *Battery         `json:"battery,omitempty"`
+}
+
+// VerificationEventPayload : VerificationRevoked
+type VerificationEventPayload struct {
+	Verification *Verification `json:"verification"`
+}
+
+// BatteryGradedPayload : BatteryGraded
+type BatteryGradedPayload struct {
+	Grade   *BatteryGrade `json:"grade"`
+	Battery *Battery      `json:"battery"`
+}
+
+// LifecycleEventPayload : 상태 전이 이벤트 (BatteryPlacedInService, MaintenanceRequested, BatteryRepurposed 등)
+type LifecycleEventPayload struct {
+	Action  string   `json:"action"`
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Battery *Battery `json:"battery"`
+}
+
+// UsageReportedPayload : UsageReported
+type UsageReportedPayload struct {
+	Report  *UsageReport `json:"report"`
+	Battery *Battery     `json:"battery"`
+}
+
+// MaintenanceLoggedPayload : MaintenanceLogged
+type MaintenanceLoggedPayload struct {
+	Record  *MaintenanceRecord `json:"record"`
+	Battery *Battery           `json:"battery"`
+}
+
+// AccidentRecordedPayload : AccidentRecorded
+type AccidentRecordedPayload struct {
+	Record  *AccidentRecord `json:"record"`
+	Battery *Battery        `json:"battery"`
+}
+
+// MaterialsExtractedPayload : MaterialsExtracted
+type MaterialsExtractedPayload struct {
+	Extraction        *ExtractionRecord `json:"extraction"`
+	Battery           *Battery          `json:"battery"`
+	RecycledMaterials []RawMaterial     `json:"recycledMaterials"`
+}
+
+// PrivateDataRecordedPayload : PrivateDataRecorded (값은 포함하지 않고 위치와 해시만 전달)
+type PrivateDataRecordedPayload struct {
+	Reference *PrivateDataReference `json:"reference"`
+}
+
+// TelemetryAnchoredPayload : TelemetryAnchored
+type TelemetryAnchoredPayload struct {
+	Anchor *TelemetryAnchor `json:"anchor"`
+}
+
+// PolicyUpdatedPayload : PolicyUpdated
+type PolicyUpdatedPayload struct {
+	Policy string      `json:"policy"`
+	Value  interface{} `json:"value"`
+}
+
+// MigrationCompletedPayload : MigrationCompleted
+type MigrationCompletedPayload struct {
+	Migration string `json:"migration"`
+	Migrated  int    `json:"migrated"`
+}
+
+// emitEvent : 이벤트를 트랜잭션 컨텍스트에 모아 둠 (payload는 호출 시점의 값으로 직렬화)
+func emitEvent(ctx contractapi.TransactionContextInterface, eventType string, assetType string, assetID string, payload interface{}) error {
+	txCtx, ok := ctx.(*TxContext)
+	if !ok {
+		return fmt.Errorf("failed to emit event: unsupported transaction context %T", ctx)
+	}
+
+	payloadAsBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %v", eventType, err)
+	}
+
+	txCtx.events = append(txCtx.events, ChaincodeEvent{
+		Type:      eventType,
+		AssetType: assetType,
+		AssetID:   assetID,
+		Payload:   payloadAsBytes,
+	})
+
+	return nil
+}
+
+// flushEvents : AfterTransaction 훅. 모아 둔 이벤트를 하나의 체인코드 이벤트로 설정
+func flushEvents(ctx *TxContext) error {
+	if len(ctx.events) == 0 {
+		return nil
+	}
+
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	envelope := EventEnvelope{
+		SchemaVersion: eventSchemaVersion,
+		TxID:          ctx.GetStub().GetTxID(),
+		Timestamp:     now.Format(time.RFC3339),
+		SubmittingMSP: clientMSPID,
+		Events:        ctx.events,
+	}
+
+	envelopeAsBytes, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal events: %v", err)
+	}
+
+	return ctx.GetStub().SetEvent(eventName, envelopeAsBytes)
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeExtraction = "extraction"
+
+	// 해체 기록 키 (extraction~배터리ID) : 배터리당 한 건만 존재
+	extractionRecordObjectType = "extraction"
+
+	recoveryYieldPolicyConfigName = "recoveryYieldPolicy"
+)
+
+// RecoveryYieldPolicy : 원자재별 최대 회수율 (0~1, 표에 없는 원자재는 DefaultMaxYield 적용)
+type RecoveryYieldPolicy struct {
+	MaxYields       map[string]float64 `json:"maxYields"`
+	DefaultMaxYield float64            `json:"defaultMaxYield"`
+}
+
+// defaultRecoveryYieldPolicy : 설정이 없을 때 사용하는 기본 최대 회수율
+// (습식 제련 공정 기준 코발트·니켈 95%, 리튬·망간 90%)
+func defaultRecoveryYieldPolicy() *RecoveryYieldPolicy {
+	return &RecoveryYieldPolicy{
+		MaxYields: map[string]float64{
+			"Cobalt":    0.95,
+			"Nickel":    0.95,
+			"Lithium":   0.90,
+			"Manganese": 0.90,
+		},
+		DefaultMaxYield: 0.90,
+	}
+}
+
+// maxYield : 원자재의 최대 회수율
+func (p *RecoveryYieldPolicy) maxYield(materialType string) float64 {
+	if yield, exists := p.MaxYields[materialType]; exists {
+		return yield
+	}
+	return p.DefaultMaxYield
+}
+
+func (p *RecoveryYieldPolicy) validate() error {
+	if p.DefaultMaxYield <= 0 || p.DefaultMaxYield > 1 {
+		return fmt.Errorf("default max yield must be greater than 0 and at most 1: %v", p.DefaultMaxYield)
+	}
+	for materialType, yield := range p.MaxYields {
+		if yield <= 0 || yield > 1 {
+			return fmt.Errorf("max yield for %s must be greater than 0 and at most 1: %v", materialType, yield)
+		}
+	}
+	return nil
+}
+
+// ExtractionQuantity : 원자재별 재활용사 신고 수량 (손실량은 투입량에서 회수량과 폐기량을 뺀 값)
+// ProcessEmissions는 해당 원자재 회수 공정의 배출량(kgCO2e)으로, 회수된 재활용 로트의 배출 계수가 된다.
+type ExtractionQuantity struct {
+	Recovered        Quantity `json:"recovered"`
+	Waste            Quantity `json:"waste"`
+	ProcessEmissions float64  `json:"processEmissions"`
+	EmissionSource   string   `json:"emissionSource"`
+}
+
+// MaterialBalance : 원자재별 물질 수지 (Contained = Recovered + Waste + Loss)
+// 수량은 환산 오차가 없도록 g 단위로 기록한다 (단위 도입 이전 기록은 kg).
+type MaterialBalance struct {
+	MaterialType    string   `json:"materialType"`
+	Contained       Quantity `json:"contained"`
+	Recovered       Quantity `json:"recovered"`
+	Waste           Quantity `json:"waste"`
+	Loss            Quantity `json:"loss"`
+	YieldPercent    float64  `json:"yieldPercent"`
+	MaxYieldPercent float64  `json:"maxYieldPercent"`
+	MaterialID      string   `json:"materialID"` // 회수된 재활용 로트 (회수량이 없으면 빈 값)
+
+	ProcessEmissions float64 `json:"processEmissions"` // 회수 공정 배출량 (kgCO2e)
+	EmissionSource   string  `json:"emissionSource"`
+}
+
+// ExtractionRecord : 배터리 해체 시 원자재별 물질 수지 기록
+type ExtractionRecord struct {
+	DocType        string            `json:"docType"`
+	BatteryID      string            `json:"batteryID"`
+	RecyclerMSP    string            `json:"recyclerMSP"`
+	Materials      []MaterialBalance `json:"materials"`
+	TotalContained Quantity          `json:"totalContained"`
+	TotalRecovered Quantity          `json:"totalRecovered"`
+	TotalWaste     Quantity          `json:"totalWaste"`
+	TotalLoss      Quantity          `json:"totalLoss"`
+	TxID           string            `json:"txID"`
+	ExtractedAt    string            `json:"extractedAt"`
+}
+
+// readRecoveryYieldPolicy : 원장에 저장된 최대 회수율 정책 (없으면 기본 정책)
+func readRecoveryYieldPolicy(ctx contractapi.TransactionContextInterface) (*RecoveryYieldPolicy, error) {
+	policyKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{recoveryYieldPolicyConfigName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	policyAsBytes, err := ctx.GetStub().GetState(policyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recovery yield policy: %v", err)
+	}
+	if policyAsBytes == nil {
+		return defaultRecoveryYieldPolicy(), nil
+	}
+
+	policy := new(RecoveryYieldPolicy)
+	err = json.Unmarshal(policyAsBytes, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal recovery yield policy: %v", err)
+	}
+	if policy.MaxYields == nil {
+		policy.MaxYields = make(map[string]float64)
+	}
+
+	return policy, nil
+}
+
+// parseExtractionQuantities : 추출 수량 JSON 해석
+// 원자재별 값은 단위가 있는 회수량({"Lithium": "20 kg"}) 또는
+// {"recovered": "20 kg", "waste": "3 kg", "processEmissions": 36.5, "emissionSource": "..."} 형식
+// 단위가 없거나 질량 단위가 아닌 수량은 거부한다.
+func parseExtractionQuantities(extractedQuantitiesJSON string) (map[string]ExtractionQuantity, error) {
+	var rawQuantities map[string]json.RawMessage
+	err := json.Unmarshal([]byte(extractedQuantitiesJSON), &rawQuantities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal extracted quantities: %v", err)
+	}
+
+	quantities := make(map[string]ExtractionQuantity)
+	for materialType, raw := range rawQuantities {
+		var quantity ExtractionQuantity
+		if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+			err = json.Unmarshal(raw, &quantity)
+		} else {
+			err = json.Unmarshal(raw, &quantity.Recovered)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid extracted quantity for %s: %v", materialType, err)
+		}
+		err = quantity.Recovered.validate(materialType + " recovered")
+		if err != nil {
+			return nil, err
+		}
+		if quantity.Waste == (Quantity{}) {
+			quantity.Waste = Quantity{Unit: quantity.Recovered.Unit} // 폐기량 생략
+		}
+		err = quantity.Waste.validate(materialType + " waste")
+		if err != nil {
+			return nil, err
+		}
+		err = validateEmissionFactor(materialType, quantity.ProcessEmissions, quantity.EmissionSource)
+		if err != nil {
+			return nil, err
+		}
+		if quantity.ProcessEmissions > 0 && quantity.Recovered.Milli == 0 {
+			return nil, fmt.Errorf("process emissions for %s require a recovered quantity", materialType)
+		}
+		quantities[materialType] = quantity
+	}
+
+	return quantities, nil
+}
+
+// balanceMaterials : 배터리 BOM과 신고 수량으로 원자재별 물질 수지를 계산하고 검증
+// 회수량+폐기량은 투입량을 넘을 수 없고, 회수율은 정책의 최대 회수율을 넘을 수 없다.
+func balanceMaterials(battery *Battery, quantities map[string]ExtractionQuantity, policy *RecoveryYieldPolicy) ([]MaterialBalance, error) {
+	contained := make(map[string]int64)
+	for _, detail := range battery.RawMaterials {
+		contained[detail.MaterialType] += detail.Quantity.milligrams()
+	}
+
+	for materialType := range quantities {
+		if _, exists := contained[materialType]; !exists {
+			return nil, fmt.Errorf("battery %s does not contain %s", battery.BatteryID, materialType)
+		}
+	}
+
+	materialTypes := make([]string, 0, len(contained))
+	for materialType := range contained {
+		materialTypes = append(materialTypes, materialType)
+	}
+	sort.Strings(materialTypes)
+
+	balances := make([]MaterialBalance, 0, len(materialTypes))
+	for _, materialType := range materialTypes {
+		// 투입량, 회수량, 폐기량을 mg으로 환산하여 비교
+		quantity := quantities[materialType]
+		containedMg := contained[materialType]
+		recoveredMg := quantity.Recovered.milligrams()
+		wasteMg := quantity.Waste.milligrams()
+		balance := MaterialBalance{
+			MaterialType:    materialType,
+			Contained:       gramsQuantity(containedMg),
+			Recovered:       gramsQuantity(recoveredMg),
+			Waste:           gramsQuantity(wasteMg),
+			MaxYieldPercent: math.Round(policy.maxYield(materialType)*10000) / 100,
+
+			ProcessEmissions: quantity.ProcessEmissions,
+			EmissionSource:   strings.TrimSpace(quantity.EmissionSource),
+		}
+
+		if recoveredMg+wasteMg > containedMg {
+			return nil, fmt.Errorf("mass balance violated for %s: recovered %s + waste %s exceeds contained %s", materialType, balance.Recovered, balance.Waste, balance.Contained)
+		}
+		if float64(recoveredMg) > float64(containedMg)*policy.maxYield(materialType)+1e-9 {
+			return nil, fmt.Errorf("recovered quantity for %s exceeds maximum yield: %s of %s contained (max %.2f%%)", materialType, balance.Recovered, balance.Contained, balance.MaxYieldPercent)
+		}
+
+		balance.Loss = gramsQuantity(containedMg - recoveredMg - wasteMg)
+		if containedMg > 0 {
+			balance.YieldPercent = math.Round(float64(recoveredMg)/float64(containedMg)*10000) / 100
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
+
+func extractionRecordKey(ctx contractapi.TransactionContextInterface, batteryID string) (string, error) {
+	recordKey, err := ctx.GetStub().CreateCompositeKey(extractionRecordObjectType, []string{batteryID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create extraction key: %v", err)
+	}
+	return recordKey, nil
+}
+
+// readExtractionRecord : 배터리의 해체 기록 (없으면 nil)
+func readExtractionRecord(ctx contractapi.TransactionContextInterface, batteryID string) (*ExtractionRecord, error) {
+	recordKey, err := extractionRecordKey(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAsBytes, err := ctx.GetStub().GetState(recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extraction record: %v", err)
+	}
+	if recordAsBytes == nil {
+		return nil, nil
+	}
+
+	record := new(ExtractionRecord)
+	err = json.Unmarshal(recordAsBytes, record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal extraction record: %v", err)
+	}
+	record.assumeLegacyUnits()
+
+	return record, nil
+}
+
+// hasLegacyQuantities : 단위 도입 이전에 저장된 수량이 있는지
+func (r *ExtractionRecord) hasLegacyQuantities() bool {
+	for _, quantity := range r.quantities() {
+		if quantity.Unit == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// assumeLegacyUnits : 단위 도입 이전 기록의 수량은 kg으로 간주
+func (r *ExtractionRecord) assumeLegacyUnits() {
+	for _, quantity := range r.quantities() {
+		quantity.assumeLegacyUnit()
+	}
+}
+
+func (r *ExtractionRecord) quantities() []*Quantity {
+	quantities := []*Quantity{&r.TotalContained, &r.TotalRecovered, &r.TotalWaste, &r.TotalLoss}
+	for i := range r.Materials {
+		balance := &r.Materials[i]
+		quantities = append(quantities, &balance.Contained, &balance.Recovered, &balance.Waste, &balance.Loss)
+	}
+	return quantities
+}
+
+// readStoredExtractionRecords : 저장된 그대로의 해체 기록 전체 (마이그레이션용)
+func readStoredExtractionRecords(ctx contractapi.TransactionContextInterface) ([]*ExtractionRecord, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(extractionRecordObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query extraction records: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	records := []*ExtractionRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		record := new(ExtractionRecord)
+		if err := json.Unmarshal(queryResponse.Value, record); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal extraction record: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func saveExtractionRecord(ctx contractapi.TransactionContextInterface, record *ExtractionRecord) error {
+	record.DocType = docTypeExtraction
+
+	recordKey, err := extractionRecordKey(ctx, record.BatteryID)
+	if err != nil {
+		return err
+	}
+
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal extraction record: %v", err)
+	}
+
+	return ctx.GetStub().PutState(recordKey, recordAsBytes)
+}
+
+// SetRecoveryYieldPolicy : 원자재별 최대 회수율 설정
+func (s *PublicContract) SetRecoveryYieldPolicy(ctx contractapi.TransactionContextInterface, policyJSON string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "SetRecoveryYieldPolicy")
+	if err != nil {
+		return err
+	}
+
+	var policy RecoveryYieldPolicy
+	err = json.Unmarshal([]byte(policyJSON), &policy)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal recovery yield policy: %v", err)
+	}
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	if policy.MaxYields == nil {
+		policy.MaxYields = make(map[string]float64)
+	}
+
+	policyKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{recoveryYieldPolicyConfigName})
+	if err != nil {
+		return fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	policyAsBytes, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recovery yield policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(policyKey, policyAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put recovery yield policy: %v", err)
+	}
+
+	return emitEvent(ctx, EventPolicyUpdated, eventAssetConfig, recoveryYieldPolicyConfigName, PolicyUpdatedPayload{Policy: recoveryYieldPolicyConfigName, Value: policy})
+}
+
+// QueryRecoveryYieldPolicy : 현재 적용 중인 최대 회수율 정책 조회
+func (s *PublicContract) QueryRecoveryYieldPolicy(ctx contractapi.TransactionContextInterface) (*RecoveryYieldPolicy, error) {
+	return readRecoveryYieldPolicy(ctx)
+}
+
+// QueryExtractionRecord : 배터리 해체 시 기록된 원자재별 물질 수지 조회
+func (s *PublicContract) QueryExtractionRecord(ctx contractapi.TransactionContextInterface, batteryID string) (*ExtractionRecord, error) {
+	record, err := readExtractionRecord(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("no extraction record for battery %s", batteryID)
+	}
+
+	return record, nil
+}
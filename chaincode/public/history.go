@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 변경 비교에서 제외하는 필드 (버전마다 SubmittingMSP로 따로 보고됨)
+const lastModifiedByField = "lastModifiedBy"
+
+// FieldChange : 연속된 두 버전 사이에서 바뀐 필드 하나 (중첩 필드는 점으로 연결된 경로)
+type FieldChange struct {
+	Field    string      `json:"field"`
+	Previous interface{} `json:"previous" metadata:",optional"`
+	Current  interface{} `json:"current" metadata:",optional"`
+}
+
+// BatteryHistoryEntry : 배터리 키의 버전 하나
+type BatteryHistoryEntry struct {
+	TxID          string        `json:"txID"`
+	Timestamp     string        `json:"timestamp"`
+	IsDelete      bool          `json:"isDelete"`
+	SubmittingMSP string        `json:"submittingMSP"`
+	Battery       *Battery      `json:"battery,omitempty" metadata:",optional"`
+	Changes       []FieldChange `json:"changes"`
+}
+
+// MaterialHistoryEntry : 원자재 키의 버전 하나
+type MaterialHistoryEntry struct {
+	TxID          string        `json:"txID"`
+	Timestamp     string        `json:"timestamp"`
+	IsDelete      bool          `json:"isDelete"`
+	SubmittingMSP string        `json:"submittingMSP"`
+	Material      *RawMaterial  `json:"material,omitempty" metadata:",optional"`
+	Changes       []FieldChange `json:"changes"`
+}
+
+// keyVersion : GetHistoryForKey 결과 한 건
+type keyVersion struct {
+	txID      string
+	timestamp time.Time
+	isDelete  bool
+	value     []byte
+}
+
+// readKeyHistory : 키의 모든 버전을 커밋 순서(오래된 것부터)로 반환
+// GetHistoryForKey는 최신 버전부터 커밋 순서대로 반환하므로 뒤집기만 한다.
+// 버전의 시각은 클라이언트가 제안 시 정한 값이라 커밋 순서와 다를 수 있으므로 정렬 기준으로 쓰지 않는다.
+func readKeyHistory(ctx contractapi.TransactionContextInterface, key string) ([]keyVersion, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history for %s: %v", key, err)
+	}
+	defer resultsIterator.Close()
+
+	versions := []keyVersion{}
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate history for %s: %v", key, err)
+		}
+
+		version := keyVersion{
+			txID:     modification.TxId,
+			isDelete: modification.IsDelete,
+			value:    modification.Value,
+		}
+		if modification.Timestamp != nil {
+			version.timestamp = modification.Timestamp.AsTime().UTC()
+		}
+		versions = append(versions, version)
+	}
+
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+
+	return versions, nil
+}
+
+// flattenDocument : JSON 문서를 "경로 → 값" 형태로 펼침 (중첩 객체는 점으로 연결)
+func flattenDocument(value []byte) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if len(value) == 0 {
+		return fields, nil
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(value, &document); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document: %v", err)
+	}
+	delete(document, lastModifiedByField)
+
+	flattenInto(fields, "", document)
+	return fields, nil
+}
+
+func flattenInto(fields map[string]interface{}, prefix string, document map[string]interface{}) {
+	for name, value := range document {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		nested, ok := value.(map[string]interface{})
+		if ok && len(nested) > 0 {
+			flattenInto(fields, path, nested)
+			continue
+		}
+		fields[path] = value
+	}
+}
+
+// diffVersions : 이전 버전과 현재 버전 사이에서 바뀐 필드 목록 (필드 이름순)
+func diffVersions(previous, current []byte) ([]FieldChange, error) {
+	previousFields, err := flattenDocument(previous)
+	if err != nil {
+		return nil, err
+	}
+	currentFields, err := flattenDocument(current)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]struct{})
+	for path := range previousFields {
+		paths[path] = struct{}{}
+	}
+	for path := range currentFields {
+		paths[path] = struct{}{}
+	}
+
+	sortedPaths := make([]string, 0, len(paths))
+	for path := range paths {
+		sortedPaths = append(sortedPaths, path)
+	}
+	sort.Strings(sortedPaths)
+
+	changes := []FieldChange{}
+	for _, path := range sortedPaths {
+		previousValue, hadPrevious := previousFields[path]
+		currentValue, hasCurrent := currentFields[path]
+		if hadPrevious == hasCurrent && reflect.DeepEqual(previousValue, currentValue) {
+			continue
+		}
+		changes = append(changes, FieldChange{
+			Field:    path,
+			Previous: previousValue,
+			Current:  currentValue,
+		})
+	}
+
+	return changes, nil
+}
+
+// submittingMSP : 버전 문서에 기록된 마지막 변경 조직 (기록 이전 문서는 빈 문자열)
+func submittingMSP(value []byte) string {
+	var document struct {
+		LastModifiedBy string `json:"lastModifiedBy"`
+	}
+	if len(value) == 0 || json.Unmarshal(value, &document) != nil {
+		return ""
+	}
+	return document.LastModifiedBy
+}
+
+// decodeBatteryVersion : 이력 버전을 배터리로 변환 (다른 문서 타입이면 오류)
+// 현재 상태 조회와 같은 보정을 적용하며, 검증 표시는 기준 시각(at)의 유효기간으로 평가한다.
+func decodeBatteryVersion(batteryID string, value []byte, at time.Time) (*Battery, error) {
+	battery := new(Battery)
+	if err := json.Unmarshal(value, battery); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal battery: %v", err)
+	}
+	if battery.DocType != "" && battery.DocType != docTypeBattery {
+		return nil, fmt.Errorf("%s is not a battery", batteryID)
+	}
+	initBatteryCollections(battery)
+	normalizeBattery(battery, at)
+	return battery, nil
+}
+
+// decodeMaterialVersion : 이력 버전을 원자재로 변환 (다른 문서 타입이면 오류)
+// 현재 상태 조회와 같은 보정을 적용하며, 검증 표시는 기준 시각(at)의 유효기간으로 평가한다.
+func decodeMaterialVersion(materialID string, value []byte, at time.Time) (*RawMaterial, error) {
+	material := new(RawMaterial)
+	if err := json.Unmarshal(value, material); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal raw material: %v", err)
+	}
+	if material.DocType != "" && material.DocType != docTypeMaterial {
+		return nil, fmt.Errorf("%s is not a raw material", materialID)
+	}
+	normalizeMaterial(material, at)
+	return material, nil
+}
+
+// versionAt : 주어진 시각 이전의 시각을 가진 버전 중 커밋 순서상 마지막 버전
+// (버전 시각이 커밋 순서와 어긋날 수 있으므로 중간에 멈추지 않고 끝까지 확인)
+func versionAt(versions []keyVersion, at time.Time) (keyVersion, bool) {
+	var found keyVersion
+	ok := false
+	for _, version := range versions {
+		if version.timestamp.After(at) {
+			continue
+		}
+		found = version
+		ok = true
+	}
+	return found, ok
+}
+
+// QueryBatteryHistory : 배터리의 모든 버전과 버전 간 필드 변경 내역 조회
+func (s *PublicContract) QueryBatteryHistory(ctx contractapi.TransactionContextInterface, batteryID string) ([]BatteryHistoryEntry, error) {
+	versions, err := readKeyHistory(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("battery not found: %s", batteryID)
+	}
+
+	entries := []BatteryHistoryEntry{}
+	var previous []byte
+	for _, version := range versions {
+		entry := BatteryHistoryEntry{
+			TxID:          version.txID,
+			Timestamp:     version.timestamp.Format(time.RFC3339Nano),
+			IsDelete:      version.isDelete,
+			SubmittingMSP: submittingMSP(version.value),
+		}
+		if !version.isDelete {
+			entry.Battery, err = decodeBatteryVersion(batteryID, version.value, version.timestamp)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		entry.Changes, err = diffVersions(previous, version.value)
+		if err != nil {
+			return nil, err
+		}
+		previous = version.value
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// QueryMaterialHistory : 원자재의 모든 버전과 버전 간 필드 변경 내역 조회
+func (s *PublicContract) QueryMaterialHistory(ctx contractapi.TransactionContextInterface, materialID string) ([]MaterialHistoryEntry, error) {
+	versions, err := readKeyHistory(ctx, materialID)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("raw material not found: %s", materialID)
+	}
+
+	entries := []MaterialHistoryEntry{}
+	var previous []byte
+	for _, version := range versions {
+		entry := MaterialHistoryEntry{
+			TxID:          version.txID,
+			Timestamp:     version.timestamp.Format(time.RFC3339Nano),
+			IsDelete:      version.isDelete,
+			SubmittingMSP: submittingMSP(version.value),
+		}
+		if !version.isDelete {
+			entry.Material, err = decodeMaterialVersion(materialID, version.value, version.timestamp)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		entry.Changes, err = diffVersions(previous, version.value)
+		if err != nil {
+			return nil, err
+		}
+		previous = version.value
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// QueryBatteryAtTime : 주어진 시각(RFC3339) 기준의 배터리 상태 조회
+func (s *PublicContract) QueryBatteryAtTime(ctx contractapi.TransactionContextInterface, batteryID string, timestamp string) (*Battery, error) {
+	at, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %q: expected RFC3339", timestamp)
+	}
+
+	versions, err := readKeyHistory(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+
+	version, ok := versionAt(versions, at)
+	if !ok {
+		return nil, fmt.Errorf("battery %s did not exist at %s", batteryID, timestamp)
+	}
+	if version.isDelete {
+		return nil, fmt.Errorf("battery %s was deleted at %s", batteryID, timestamp)
+	}
+
+	return decodeBatteryVersion(batteryID, version.value, at)
+}
+
+// QueryMaterialAtTime : 주어진 시각(RFC3339) 기준의 원자재 상태 조회
+func (s *PublicContract) QueryMaterialAtTime(ctx contractapi.TransactionContextInterface, materialID string, timestamp string) (*RawMaterial, error) {
+	at, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %q: expected RFC3339", timestamp)
+	}
+
+	versions, err := readKeyHistory(ctx, materialID)
+	if err != nil {
+		return nil, err
+	}
+
+	version, ok := versionAt(versions, at)
+	if !ok {
+		return nil, fmt.Errorf("raw material %s did not exist at %s", materialID, timestamp)
+	}
+	if version.isDelete {
+		return nil, fmt.Errorf("raw material %s was deleted at %s", materialID, timestamp)
+	}
+
+	return decodeMaterialVersion(materialID, version.value, at)
+}
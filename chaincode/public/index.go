@@ -0,0 +1,446 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 원장 문서 타입 구분자
+const (
+	docTypeMaterial = "material"
+	docTypeBattery  = "battery"
+)
+
+// 복합 키 보조 인덱스 이름 (objectType~속성~ID)
+const (
+	indexMaterialByStatus   = "material~status~id"
+	indexMaterialByName     = "material~name~id"
+	indexMaterialBySupplier = "material~supplier~id"
+
+	indexBatteryByStatus              = "battery~status~id"
+	indexBatteryByCategory            = "battery~category~id"
+	indexBatteryByMaintenanceRequest  = "battery~maintenanceRequest~id"
+	indexBatteryByAnalysisRequest     = "battery~analysisRequest~id"
+	indexBatteryByRecycleAvailability = "battery~recycleAvailability~id"
+)
+
+// 설정 문서 키 (config~이름)
+const configObjectType = "config"
+
+// 인덱스 항목의 값 (키만 의미가 있으므로 1바이트 placeholder 사용)
+var indexValue = []byte{0x00}
+
+type indexEntry struct {
+	name       string
+	attributes []string
+}
+
+// materialIndexEntries : 원자재가 등록되어야 하는 인덱스 항목 목록
+func materialIndexEntries(material *RawMaterial) []indexEntry {
+	if material == nil {
+		return nil
+	}
+	return []indexEntry{
+		{indexMaterialByStatus, []string{material.Status, material.MaterialID}},
+		{indexMaterialByName, []string{material.Name, material.MaterialID}},
+		{indexMaterialBySupplier, []string{material.SupplierID, material.MaterialID}},
+	}
+}
+
+// batteryIndexEntries : 배터리가 등록되어야 하는 인덱스 항목 목록
+func batteryIndexEntries(battery *Battery) []indexEntry {
+	if battery == nil {
+		return nil
+	}
+	return []indexEntry{
+		{indexBatteryByStatus, []string{battery.Status, battery.BatteryID}},
+		{indexBatteryByCategory, []string{battery.Category, battery.BatteryID}},
+		{indexBatteryByMaintenanceRequest, []string{strconv.FormatBool(battery.MaintenanceRequest), battery.BatteryID}},
+		{indexBatteryByAnalysisRequest, []string{strconv.FormatBool(battery.AnalysisRequest), battery.BatteryID}},
+		{indexBatteryByRecycleAvailability, []string{strconv.FormatBool(battery.RecycleAvailability), battery.BatteryID}},
+	}
+}
+
+// updateIndexes : 이전 인덱스 항목 중 더 이상 유효하지 않은 것은 삭제하고, 새 항목을 기록
+func updateIndexes(ctx contractapi.TransactionContextInterface, previous []indexEntry, current []indexEntry) error {
+	stub := ctx.GetStub()
+
+	currentKeys := make(map[string]bool)
+	for _, entry := range current {
+		key, err := stub.CreateCompositeKey(entry.name, entry.attributes)
+		if err != nil {
+			return fmt.Errorf("failed to create index key %s: %v", entry.name, err)
+		}
+		currentKeys[key] = true
+	}
+
+	previousKeys := make(map[string]bool)
+	for _, entry := range previous {
+		key, err := stub.CreateCompositeKey(entry.name, entry.attributes)
+		if err != nil {
+			return fmt.Errorf("failed to create index key %s: %v", entry.name, err)
+		}
+		previousKeys[key] = true
+		if !currentKeys[key] {
+			if err := stub.DelState(key); err != nil {
+				return fmt.Errorf("failed to delete index entry: %v", err)
+			}
+		}
+	}
+
+	for _, entry := range current {
+		key, _ := stub.CreateCompositeKey(entry.name, entry.attributes)
+		if previousKeys[key] {
+			continue
+		}
+		if err := stub.PutState(key, indexValue); err != nil {
+			return fmt.Errorf("failed to put index entry: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// readMaterialState : 원장의 원자재 문서를 조회용으로 읽음 (없거나 원자재가 아니면 nil)
+// 검증 표시는 조회 시각의 유효기간으로 평가하므로, 원자재를 수정하여 저장하는 함수는 loadMaterial을 사용한다.
+func readMaterialState(ctx contractapi.TransactionContextInterface, materialID string) (*RawMaterial, error) {
+	material, err := readStoredMaterial(ctx, materialID)
+	if err != nil || material == nil {
+		return material, err
+	}
+
+	// 유효기간이 지난 검증은 조회 시점에 미검증으로 표시
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	material.Verified = effectiveVerified(material.Verified, material.VerifiedUntil, now)
+
+	return material, nil
+}
+
+// readStoredMaterial : 원장의 원자재 문서를 읽고 이전 형식만 보정 (검증 표시는 저장된 그대로)
+func readStoredMaterial(ctx contractapi.TransactionContextInterface, materialID string) (*RawMaterial, error) {
+	materialAsBytes, err := ctx.GetStub().GetState(materialID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raw material: %v", err)
+	}
+	if materialAsBytes == nil {
+		return nil, nil
+	}
+
+	material := new(RawMaterial)
+	err = json.Unmarshal(materialAsBytes, material)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal raw material: %v", err)
+	}
+	if material.DocType != docTypeMaterial {
+		return nil, nil
+	}
+	upgradeMaterial(material)
+
+	return material, nil
+}
+
+// loadMaterial : 수정하여 저장할 원자재를 읽음 (없으면 오류)
+// 조회 시점의 검증 유효기간 평가가 다른 변경과 함께 저장되지 않도록 저장된 검증 표시를 유지한다.
+func loadMaterial(ctx contractapi.TransactionContextInterface, materialID string) (*RawMaterial, error) {
+	material, err := readStoredMaterial(ctx, materialID)
+	if err != nil {
+		return nil, err
+	}
+	if material == nil {
+		return nil, fmt.Errorf("raw material not found: %s", materialID)
+	}
+	return material, nil
+}
+
+// upgradeMaterial : 이전 형식으로 저장된 원자재 문서를 현재 형식으로 보정 (단위 도입 이전 로트의 수량은 kg으로 간주)
+func upgradeMaterial(material *RawMaterial) {
+	material.Quantity.assumeLegacyUnit()
+}
+
+// normalizeMaterial : 원자재 문서를 조회 결과 형식으로 보정 (이력 버전에도 같이 적용)
+// 이전 형식을 보정하고, 검증 표시는 기준 시각의 유효기간으로 평가한다.
+func normalizeMaterial(material *RawMaterial, at time.Time) {
+	upgradeMaterial(material)
+	material.Verified = effectiveVerified(material.Verified, material.VerifiedUntil, at)
+}
+
+// readBatteryState : 원장의 배터리 문서를 조회용으로 읽음 (없거나 배터리가 아니면 nil)
+// 상태 머신 도입 이전 문서는 수명 주기 상태로 변환하고 검증 표시는 조회 시각의 유효기간으로 평가한다.
+// 배터리를 수정하여 저장하는 함수는 loadBattery를 사용한다.
+func readBatteryState(ctx contractapi.TransactionContextInterface, batteryID string) (*Battery, error) {
+	battery, err := readStoredBattery(ctx, batteryID)
+	if err != nil || battery == nil {
+		return battery, err
+	}
+
+	// 유효기간이 지난 검증은 조회 시점에 미검증으로 표시
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	normalizeBattery(battery, now)
+
+	return battery, nil
+}
+
+// loadBattery : 수정하여 저장할 배터리를 읽음 (없으면 오류)
+// 이전 형식은 보정하지만, 조회 시점의 검증 유효기간 평가가 다른 변경과 함께 저장되지 않도록 저장된 검증 표시를 유지한다.
+func loadBattery(ctx contractapi.TransactionContextInterface, batteryID string) (*Battery, error) {
+	battery, err := readUpgradedBattery(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if battery == nil {
+		return nil, fmt.Errorf("battery not found: %s", batteryID)
+	}
+	return battery, nil
+}
+
+// readUpgradedBattery : 원장의 배터리 문서를 읽고 이전 형식만 보정 (검증 표시는 저장된 그대로, 없으면 nil)
+func readUpgradedBattery(ctx contractapi.TransactionContextInterface, batteryID string) (*Battery, error) {
+	battery, err := readStoredBattery(ctx, batteryID)
+	if err != nil || battery == nil {
+		return battery, err
+	}
+	upgradeBattery(battery)
+	return battery, nil
+}
+
+// upgradeBattery : 이전 형식으로 저장된 배터리 문서를 현재 형식으로 보정
+// 이전 상태 값은 수명 주기 상태로, 단위 없는 투입량은 kg으로 변환한다.
+func upgradeBattery(battery *Battery) {
+	battery.Status = legacyLifecycleStatus(battery)
+	applyLifecycleFlags(battery)
+	assumeLegacyDetailUnits(battery.RawMaterials)
+}
+
+// normalizeBattery : 배터리 문서를 조회 결과 형식으로 보정 (이력 버전에도 같이 적용)
+// 이전 형식을 보정하고, 검증 표시는 기준 시각의 유효기간으로 평가한다.
+func normalizeBattery(battery *Battery, at time.Time) {
+	upgradeBattery(battery)
+	battery.Verified = effectiveVerified(battery.Verified, battery.VerifiedUntil, at)
+}
+
+// readStoredBattery : 원장에 저장된 그대로의 배터리 문서를 읽음 (인덱스 갱신용)
+func readStoredBattery(ctx contractapi.TransactionContextInterface, batteryID string) (*Battery, error) {
+	batteryAsBytes, err := ctx.GetStub().GetState(batteryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read battery from state: %v", err)
+	}
+	if batteryAsBytes == nil {
+		return nil, nil
+	}
+
+	battery := new(Battery)
+	err = json.Unmarshal(batteryAsBytes, battery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal battery: %v", err)
+	}
+	if battery.DocType != docTypeBattery {
+		return nil, nil
+	}
+
+	initBatteryCollections(battery)
+
+	return battery, nil
+}
+
+// initBatteryCollections : 로그 및 맵 필드들이 nil이면 빈 값으로 초기화
+func initBatteryCollections(battery *Battery) {
+	if battery.AccidentLogs == nil {
+		battery.AccidentLogs = []string{}
+	}
+	if battery.MaintenanceLogs == nil {
+		battery.MaintenanceLogs = []string{}
+	}
+	if battery.RawMaterials == nil {
+		battery.RawMaterials = make(map[string]RawMaterialDetail)
+	}
+	if battery.RecyclingRatesByMaterial == nil {
+		battery.RecyclingRatesByMaterial = make(map[string]float64)
+	}
+}
+
+// saveMaterial : 원자재를 docType과 함께 저장하고 보조 인덱스를 갱신
+func (s *PublicContract) saveMaterial(ctx contractapi.TransactionContextInterface, material *RawMaterial) error {
+	material.DocType = docTypeMaterial
+	material.Quantity.assumeLegacyUnit()
+
+	// 이력 조회 시 제출 조직을 알 수 있도록 마지막 변경 조직을 기록
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	material.LastModifiedBy = clientMSPID
+
+	previous, err := readStoredMaterial(ctx, material.MaterialID)
+	if err != nil {
+		return err
+	}
+
+	materialAsBytes, err := json.Marshal(material)
+	if err != nil {
+		return fmt.Errorf("failed to marshal raw material: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(material.MaterialID, materialAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store raw material: %v", err)
+	}
+
+	return updateIndexes(ctx, materialIndexEntries(previous), materialIndexEntries(material))
+}
+
+// queryMaterialsByIndex : 인덱스 파티션에 속한 원자재만 조회
+func (s *PublicContract) queryMaterialsByIndex(ctx contractapi.TransactionContextInterface, index string, attributes ...string) ([]RawMaterial, error) {
+	return materialsByIndex(ctx, readMaterialState, index, attributes...)
+}
+
+// loadMaterialsByIndex : 인덱스 파티션에 속한 원자재를 수정하여 저장하기 위해 읽음 (저장된 검증 표시 유지)
+func (s *PublicContract) loadMaterialsByIndex(ctx contractapi.TransactionContextInterface, index string, attributes ...string) ([]RawMaterial, error) {
+	return materialsByIndex(ctx, readStoredMaterial, index, attributes...)
+}
+
+// materialsByIndex : 인덱스 파티션의 원자재 ID를 주어진 함수로 읽음
+func materialsByIndex(ctx contractapi.TransactionContextInterface, read func(contractapi.TransactionContextInterface, string) (*RawMaterial, error), index string, attributes ...string) ([]RawMaterial, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index %s: %v", index, err)
+	}
+	defer resultsIterator.Close()
+
+	rawMaterials := []RawMaterial{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split index key: %v", err)
+		}
+
+		material, err := read(ctx, keyParts[len(keyParts)-1])
+		if err != nil {
+			return nil, err
+		}
+		if material == nil {
+			continue
+		}
+
+		rawMaterials = append(rawMaterials, *material)
+	}
+
+	return rawMaterials, nil
+}
+
+// queryBatteriesByIndex : 인덱스 파티션에 속한 배터리만 조회
+func (s *PublicContract) queryBatteriesByIndex(ctx contractapi.TransactionContextInterface, index string, attributes ...string) ([]Battery, error) {
+	return batteriesByIndex(ctx, readBatteryState, index, attributes...)
+}
+
+// loadBatteriesByIndex : 인덱스 파티션에 속한 배터리를 수정하여 저장하기 위해 읽음 (저장된 검증 표시 유지)
+func (s *PublicContract) loadBatteriesByIndex(ctx contractapi.TransactionContextInterface, index string, attributes ...string) ([]Battery, error) {
+	return batteriesByIndex(ctx, readUpgradedBattery, index, attributes...)
+}
+
+// batteriesByIndex : 인덱스 파티션의 배터리 ID를 주어진 함수로 읽음
+func batteriesByIndex(ctx contractapi.TransactionContextInterface, read func(contractapi.TransactionContextInterface, string) (*Battery, error), index string, attributes ...string) ([]Battery, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index %s: %v", index, err)
+	}
+	defer resultsIterator.Close()
+
+	batteries := []Battery{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split index key: %v", err)
+		}
+
+		battery, err := read(ctx, keyParts[len(keyParts)-1])
+		if err != nil {
+			return nil, err
+		}
+		if battery == nil {
+			continue
+		}
+
+		batteries = append(batteries, *battery)
+	}
+
+	return batteries, nil
+}
+
+// MigrateIndexes : docType이 없는 기존 원자재/배터리 문서에 docType을 부여하고 보조 인덱스를 생성
+func (s *PublicContract) MigrateIndexes(ctx contractapi.TransactionContextInterface) (int, error) {
+	if _, err := authorize(ctx, "MigrateIndexes"); err != nil {
+		return 0, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	migrated := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return migrated, err
+		}
+
+		var probe struct {
+			DocType    string `json:"docType"`
+			BatteryID  string `json:"batteryID"`
+			MaterialID string `json:"materialID"`
+		}
+		if err := json.Unmarshal(queryResponse.Value, &probe); err != nil || probe.DocType != "" {
+			continue
+		}
+
+		switch {
+		case probe.BatteryID != "" && probe.BatteryID == queryResponse.Key:
+			var battery Battery
+			if err := json.Unmarshal(queryResponse.Value, &battery); err != nil {
+				return migrated, fmt.Errorf("failed to unmarshal battery %s: %v", queryResponse.Key, err)
+			}
+			if err := s.saveBattery(ctx, &battery); err != nil {
+				return migrated, err
+			}
+		case probe.MaterialID != "" && probe.MaterialID == queryResponse.Key:
+			var material RawMaterial
+			if err := json.Unmarshal(queryResponse.Value, &material); err != nil {
+				return migrated, fmt.Errorf("failed to unmarshal raw material %s: %v", queryResponse.Key, err)
+			}
+			if err := s.saveMaterial(ctx, &material); err != nil {
+				return migrated, err
+			}
+		default:
+			continue
+		}
+		migrated++
+	}
+
+	err = emitEvent(ctx, EventMigrationCompleted, eventAssetConfig, "indexes", MigrationCompletedPayload{Migration: "MigrateIndexes", Migrated: migrated})
+	if err != nil {
+		return migrated, err
+	}
+
+	return migrated, nil
+}
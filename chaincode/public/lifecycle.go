@@ -0,0 +1,365 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 배터리 수명 주기 상태
+const (
+	statusManufactured     = "MANUFACTURED"
+	statusInService        = "IN_SERVICE"
+	statusUnderMaintenance = "UNDER_MAINTENANCE"
+	statusUnderAnalysis    = "UNDER_ANALYSIS"
+	statusSecondLife       = "SECOND_LIFE"
+	statusEndOfLife        = "END_OF_LIFE"
+	statusDisassembled     = "DISASSEMBLED"
+)
+
+var lifecycleStatuses = map[string]bool{
+	statusManufactured:     true,
+	statusInService:        true,
+	statusUnderMaintenance: true,
+	statusUnderAnalysis:    true,
+	statusSecondLife:       true,
+	statusEndOfLife:        true,
+	statusDisassembled:     true,
+}
+
+// 상태 전이 동작
+const (
+	actionManufacture         = "MANUFACTURE"
+	actionPlaceInService      = "PLACE_IN_SERVICE"
+	actionRequestMaintenance  = "REQUEST_MAINTENANCE"
+	actionCompleteMaintenance = "COMPLETE_MAINTENANCE"
+	actionRequestAnalysis     = "REQUEST_ANALYSIS"
+	actionReportAccident      = "REPORT_ACCIDENT"
+	actionReturnToService     = "RETURN_TO_SERVICE"
+	actionApproveSecondLife   = "APPROVE_SECOND_LIFE"
+	actionRepurpose           = "REPURPOSE"
+	actionDeclareEndOfLife    = "DECLARE_END_OF_LIFE"
+	actionDisassemble         = "DISASSEMBLE"
+	actionMigrateLegacyStatus = "MIGRATE_LEGACY_STATUS"
+)
+
+// 상태 전이 기록 키 (transition~배터리ID~시각~트랜잭션 내 순번~전이ID)
+const lifecycleTransitionObjectType = "transition"
+
+const docTypeTransition = "transition"
+
+// lifecycleRule : 동작별로 허용되는 이전 상태, 다음 상태, 호출에 필요한 역할
+// roles가 비어 있으면 호출하는 함수가 권한을 직접 확인한다. (예: 사고 신고는 AddAccidentLog의 역할)
+// ownerOnly이면 역할과 함께 배터리의 현재 소유 조직이어야 한다.
+type lifecycleRule struct {
+	from      []string
+	to        string
+	roles     []string
+	ownerOnly bool
+}
+
+var lifecycleRules = map[string]lifecycleRule{
+	actionManufacture:         {from: []string{""}, to: statusManufactured, roles: []string{roleManufacturer}},
+	actionPlaceInService:      {from: []string{statusManufactured}, to: statusInService, roles: []string{roleOperator}},
+	actionRequestMaintenance:  {from: []string{statusInService}, to: statusUnderMaintenance, roles: []string{roleOperator, roleRepurposer}, ownerOnly: true},
+	actionCompleteMaintenance: {from: []string{statusUnderMaintenance}, to: statusInService, roles: []string{roleTechnician}},
+	actionRequestAnalysis:     {from: []string{statusInService, statusUnderMaintenance, statusSecondLife}, to: statusUnderAnalysis, roles: []string{roleOperator, roleRepurposer}},
+	actionReportAccident:      {from: []string{statusManufactured, statusInService, statusUnderMaintenance, statusSecondLife}, to: statusUnderAnalysis},
+	actionReturnToService:     {from: []string{statusUnderAnalysis}, to: statusInService, roles: []string{roleAnalyst}},
+	actionApproveSecondLife:   {from: []string{statusUnderAnalysis}, to: statusSecondLife, roles: []string{roleAnalyst}},
+	actionRepurpose:           {from: []string{statusSecondLife}, to: statusInService, roles: []string{roleRepurposer}, ownerOnly: true},
+	actionDeclareEndOfLife:    {from: []string{statusUnderAnalysis}, to: statusEndOfLife, roles: []string{roleAnalyst}},
+	actionDisassemble:         {from: []string{statusEndOfLife}, to: statusDisassembled, roles: []string{roleRecycler}},
+}
+
+// TransitionError : 현재 상태에서 허용되지 않는 동작을 요청했을 때의 오류
+type TransitionError struct {
+	BatteryID string
+	Action    string
+	From      string
+	Allowed   []string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("illegal lifecycle transition: action %s is not allowed for battery %s in state %s (allowed states: %s)",
+		e.Action, e.BatteryID, e.From, strings.Join(e.Allowed, ", "))
+}
+
+// LifecycleTransition : 배터리 상태 전이 기록
+type LifecycleTransition struct {
+	DocType      string `json:"docType"`
+	TransitionID string `json:"transitionID"`
+	BatteryID    string `json:"batteryID"`
+	Action       string `json:"action"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+	MSPID        string `json:"mspID"`
+	TxID         string `json:"txID"`
+	Timestamp    string `json:"timestamp"`
+}
+
+// applyLifecycleFlags : 요청 플래그는 상태에서 파생되므로 서로 모순될 수 없다
+func applyLifecycleFlags(battery *Battery) {
+	battery.MaintenanceRequest = battery.Status == statusUnderMaintenance
+	battery.AnalysisRequest = battery.Status == statusUnderAnalysis
+	battery.RecycleAvailability = battery.Status == statusEndOfLife
+}
+
+// legacyLifecycleStatus : 상태 머신 도입 이전 문서("ORIGINAL" 등)의 상태를 플래그로부터 추정
+func legacyLifecycleStatus(battery *Battery) string {
+	switch {
+	case lifecycleStatuses[battery.Status]:
+		return battery.Status
+	case battery.RecycleAvailability:
+		return statusEndOfLife
+	case battery.AnalysisRequest:
+		return statusUnderAnalysis
+	case battery.MaintenanceRequest:
+		return statusUnderMaintenance
+	default:
+		return statusInService
+	}
+}
+
+// requireBatteryStatus : 상태를 바꾸지 않는 변경 작업이 현재 상태에서 허용되는지 확인
+func requireBatteryStatus(battery *Battery, action string, allowed ...string) error {
+	for _, status := range allowed {
+		if battery.Status == status {
+			return nil
+		}
+	}
+	return &TransitionError{BatteryID: battery.BatteryID, Action: action, From: battery.Status, Allowed: allowed}
+}
+
+// transitionBattery : 규칙 표에 따라 호출자 역할과 이전 상태를 검사한 뒤 상태를 바꾸고 전이 기록을 남김 (배터리 저장은 호출자가 수행)
+func transitionBattery(ctx contractapi.TransactionContextInterface, battery *Battery, action string) error {
+	rule, ok := lifecycleRules[action]
+	if !ok {
+		return fmt.Errorf("unknown lifecycle action: %s", action)
+	}
+
+	if len(rule.roles) > 0 || rule.ownerOnly {
+		caller, err := resolveCaller(ctx)
+		if err != nil {
+			return err
+		}
+		if len(rule.roles) > 0 && !caller.hasAnyRole(rule.roles...) {
+			return &PermissionError{Function: action, MSPID: caller.MSPID, Required: rule.roles, Held: caller.Roles}
+		}
+		if rule.ownerOnly {
+			if err := requireBatteryOwner(caller, battery); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := requireBatteryStatus(battery, action, rule.from...); err != nil {
+		return err
+	}
+
+	from := battery.Status
+	battery.Status = rule.to
+	applyLifecycleFlags(battery)
+
+	// 이전 분석의 2차 사용 등급은 새 분석에 적용하지 않음
+	if battery.Status == statusUnderAnalysis {
+		battery.Grade = ""
+		battery.GradeID = ""
+	}
+
+	return recordTransition(ctx, battery, action, from)
+}
+
+// recordTransition : 배터리별 복합 키에 상태 전이 기록을 저장
+func recordTransition(ctx contractapi.TransactionContextInterface, battery *Battery, action string, from string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	transitionID, err := newID(ctx, "TRANSITION")
+	if err != nil {
+		return err
+	}
+
+	transition := LifecycleTransition{
+		DocType:      docTypeTransition,
+		TransitionID: transitionID,
+		BatteryID:    battery.BatteryID,
+		Action:       action,
+		From:         from,
+		To:           battery.Status,
+		MSPID:        clientMSPID,
+		TxID:         ctx.GetStub().GetTxID(),
+		Timestamp:    now.Format(time.RFC3339Nano),
+	}
+
+	seq, err := newKeySeq(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 키에 고정 길이 시각과 순번을 넣어 부분 키 조회 결과가 발생 순으로 정렬되도록 함
+	// (한 트랜잭션의 전이는 시각이 같으므로 순번으로 구분)
+	transitionKey, err := ctx.GetStub().CreateCompositeKey(lifecycleTransitionObjectType,
+		[]string{battery.BatteryID, fmt.Sprintf("%020d", now.UnixNano()), fmt.Sprintf("%06d", seq), transitionID})
+	if err != nil {
+		return fmt.Errorf("failed to create transition key: %v", err)
+	}
+
+	transitionAsBytes, err := json.Marshal(transition)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transition: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(transitionKey, transitionAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store transition: %v", err)
+	}
+
+	return nil
+}
+
+// changeBatteryStatus : 배터리를 조회하여 상태를 전이시키고 저장
+func (s *PublicContract) changeBatteryStatus(ctx contractapi.TransactionContextInterface, batteryID string, action string) error {
+	battery, err := loadBattery(ctx, batteryID)
+	if err != nil {
+		return err
+	}
+
+	from := battery.Status
+	err = transitionBattery(ctx, battery, action)
+	if err != nil {
+		return err
+	}
+
+	err = s.saveBattery(ctx, battery)
+	if err != nil {
+		return fmt.Errorf("failed to update battery: %v", err)
+	}
+
+	return emitEvent(ctx, lifecycleEvents[action], eventAssetBattery, batteryID, LifecycleEventPayload{
+		Action:  action,
+		From:    from,
+		To:      battery.Status,
+		Battery: battery,
+	})
+}
+
+// PlaceInService : 제조된 배터리를 차량에 장착하여 운행 상태로 전환 (EV ORG)
+func (s *PublicContract) PlaceInService(ctx contractapi.TransactionContextInterface, batteryID string) error {
+	return s.changeBatteryStatus(ctx, batteryID, actionPlaceInService)
+}
+
+// CompleteMaintenance : 유지보수를 마치고 배터리를 운행 상태로 되돌림 (Maintenance ORG)
+func (s *PublicContract) CompleteMaintenance(ctx contractapi.TransactionContextInterface, batteryID string) error {
+	return s.changeBatteryStatus(ctx, batteryID, actionCompleteMaintenance)
+}
+
+// ApproveSecondLife : 분석 결과 재사용이 가능한 배터리를 2차 사용 상태로 전환 (Analysis ORG)
+// 이번 분석에서 GradeBattery로 재사용 가능 등급을 받은 배터리만 승인할 수 있다.
+func (s *PublicContract) ApproveSecondLife(ctx contractapi.TransactionContextInterface, batteryID string) error {
+	battery, err := s.QueryBatteryDetails(ctx, batteryID)
+	if err != nil {
+		return err
+	}
+	policy, err := readGradingPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if !policy.repurposable(battery.Grade) {
+		return fmt.Errorf("battery %s is not graded for second life (grade: %s, repurposable grades: %s)", batteryID, battery.Grade, strings.Join(policy.RepurposableGrades, ", "))
+	}
+
+	return s.changeBatteryStatus(ctx, batteryID, actionApproveSecondLife)
+}
+
+// QueryBatteryLifecycleHistory : 배터리의 상태 전이 기록을 시간 순으로 조회
+func (s *PublicContract) QueryBatteryLifecycleHistory(ctx contractapi.TransactionContextInterface, batteryID string) ([]LifecycleTransition, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(lifecycleTransitionObjectType, []string{batteryID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lifecycle transitions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	transitions := []LifecycleTransition{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var transition LifecycleTransition
+		err = json.Unmarshal(queryResponse.Value, &transition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transition: %v", err)
+		}
+
+		transitions = append(transitions, transition)
+	}
+
+	return transitions, nil
+}
+
+// MigrateBatteryLifecycle : 상태 머신 도입 이전 상태("ORIGINAL" 등)로 저장된 배터리를 수명 주기 상태로 변환
+func (s *PublicContract) MigrateBatteryLifecycle(ctx contractapi.TransactionContextInterface) (int, error) {
+	if _, err := authorize(ctx, "MigrateBatteryLifecycle"); err != nil {
+		return 0, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(indexBatteryByStatus, []string{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query index %s: %v", indexBatteryByStatus, err)
+	}
+	defer resultsIterator.Close()
+
+	migrated := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return migrated, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to split index key: %v", err)
+		}
+		if lifecycleStatuses[keyParts[0]] {
+			continue
+		}
+
+		battery, err := readUpgradedBattery(ctx, keyParts[len(keyParts)-1])
+		if err != nil {
+			return migrated, err
+		}
+		if battery == nil {
+			continue
+		}
+
+		err = recordTransition(ctx, battery, actionMigrateLegacyStatus, keyParts[0])
+		if err != nil {
+			return migrated, err
+		}
+
+		err = s.saveBattery(ctx, battery)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to update battery: %v", err)
+		}
+		migrated++
+	}
+
+	err = emitEvent(ctx, EventMigrationCompleted, eventAssetConfig, "batteryLifecycle", MigrationCompletedPayload{Migration: "MigrateBatteryLifecycle", Migrated: migrated})
+	if err != nil {
+		return migrated, err
+	}
+
+	return migrated, nil
+}
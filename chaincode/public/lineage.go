@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 계보 그래프의 노드 종류
+const (
+	lineageNodeMaterial = "material"
+	lineageNodeBattery  = "battery"
+)
+
+// 계보 그래프의 관계 종류
+const (
+	// 원자재 로트가 배터리 제조에 투입됨 (material → battery)
+	lineageRelationConsumed = "CONSUMED"
+	// 배터리 해체로 재활용 로트가 회수됨 (battery → material)
+	lineageRelationRecovered = "RECOVERED"
+	// 로트가 하위 로트로 분할됨 (material → material)
+	lineageRelationSplit = "SPLIT"
+	// 로트가 다른 로트와 병합되어 새 로트가 됨 (material → material)
+	lineageRelationMerged = "MERGED"
+)
+
+// 계보 간선 복합 키 (간선 하나를 양방향으로 저장)
+const (
+	// lineage~down[출발ID, 도착ID] : 하류(소비/회수 방향) 탐색용
+	lineageDownObjectType = "lineage~down"
+	// lineage~up[도착ID, 출발ID] : 상류(원천 방향) 탐색용
+	lineageUpObjectType = "lineage~up"
+)
+
+// LineageEdge : 계보 그래프의 간선 하나
+type LineageEdge struct {
+	From      string   `json:"from"`
+	FromType  string   `json:"fromType"`
+	To        string   `json:"to"`
+	ToType    string   `json:"toType"`
+	Relation  string   `json:"relation"`
+	Quantity  Quantity `json:"quantity"`
+	TxID      string   `json:"txID"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// LineageNode : 계보 그래프의 노드 하나 (Depth는 시작점으로부터의 간선 수)
+type LineageNode struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Label  string `json:"label"`
+	Status string `json:"status"`
+	Depth  int    `json:"depth"`
+}
+
+// LineageGraph : 추적 결과 (노드는 탐색 순서, 간선은 발견 순서)
+type LineageGraph struct {
+	Root  string        `json:"root"`
+	Nodes []LineageNode `json:"nodes"`
+	Edges []LineageEdge `json:"edges"`
+}
+
+// putLineageEdge : 간선을 상류/하류 키 양쪽에 저장
+func putLineageEdge(ctx contractapi.TransactionContextInterface, edge LineageEdge) error {
+	edgeAsBytes, err := json.Marshal(edge)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lineage edge: %v", err)
+	}
+
+	downKey, err := ctx.GetStub().CreateCompositeKey(lineageDownObjectType, []string{edge.From, edge.To})
+	if err != nil {
+		return fmt.Errorf("failed to create lineage key: %v", err)
+	}
+	upKey, err := ctx.GetStub().CreateCompositeKey(lineageUpObjectType, []string{edge.To, edge.From})
+	if err != nil {
+		return fmt.Errorf("failed to create lineage key: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(downKey, edgeAsBytes); err != nil {
+		return fmt.Errorf("failed to put lineage edge: %v", err)
+	}
+	if err := ctx.GetStub().PutState(upKey, edgeAsBytes); err != nil {
+		return fmt.Errorf("failed to put lineage edge: %v", err)
+	}
+
+	return nil
+}
+
+func newLineageEdge(ctx contractapi.TransactionContextInterface, from, fromType, to, toType, relation string, quantity Quantity) (LineageEdge, error) {
+	now, err := txTime(ctx)
+	if err != nil {
+		return LineageEdge{}, err
+	}
+
+	return LineageEdge{
+		From:      from,
+		FromType:  fromType,
+		To:        to,
+		ToType:    toType,
+		Relation:  relation,
+		Quantity:  quantity,
+		TxID:      ctx.GetStub().GetTxID(),
+		Timestamp: now.Format(time.RFC3339),
+	}, nil
+}
+
+// recordConsumption : 배터리에 투입된 원자재 로트별 소비 간선 기록 (같은 로트는 수량 합산)
+func recordConsumption(ctx contractapi.TransactionContextInterface, batteryID string, rawMaterials map[string]RawMaterialDetail) error {
+	quantities := make(map[string]Quantity)
+	for _, detail := range rawMaterials {
+		if quantity, exists := quantities[detail.MaterialID]; exists {
+			quantities[detail.MaterialID] = sumQuantities(quantity, detail.Quantity)
+		} else {
+			quantities[detail.MaterialID] = detail.Quantity
+		}
+	}
+
+	materialIDs := make([]string, 0, len(quantities))
+	for materialID := range quantities {
+		materialIDs = append(materialIDs, materialID)
+	}
+	sort.Strings(materialIDs)
+
+	for _, materialID := range materialIDs {
+		edge, err := newLineageEdge(ctx, materialID, lineageNodeMaterial, batteryID, lineageNodeBattery, lineageRelationConsumed, quantities[materialID])
+		if err != nil {
+			return err
+		}
+		if err := putLineageEdge(ctx, edge); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordRecovery : 배터리 해체로 회수된 재활용 로트의 회수 간선 기록
+func recordRecovery(ctx contractapi.TransactionContextInterface, batteryID string, materialID string, quantity Quantity) error {
+	edge, err := newLineageEdge(ctx, batteryID, lineageNodeBattery, materialID, lineageNodeMaterial, lineageRelationRecovered, quantity)
+	if err != nil {
+		return err
+	}
+	return putLineageEdge(ctx, edge)
+}
+
+// readLineageEdges : 노드에 연결된 간선 조회 (objectType에 따라 상류 또는 하류)
+func readLineageEdges(ctx contractapi.TransactionContextInterface, objectType string, nodeID string) ([]LineageEdge, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{nodeID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lineage of %s: %v", nodeID, err)
+	}
+	defer resultsIterator.Close()
+
+	edges := []LineageEdge{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var edge LineageEdge
+		if err := json.Unmarshal(queryResponse.Value, &edge); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal lineage edge: %v", err)
+		}
+		edge.Quantity.assumeLegacyUnit()
+		edges = append(edges, edge)
+	}
+
+	return edges, nil
+}
+
+// readLineageEdgesByType : 한 방향의 간선 전체를 저장된 그대로 조회 (마이그레이션용)
+func readLineageEdgesByType(ctx contractapi.TransactionContextInterface, objectType string) ([]LineageEdge, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lineage edges: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	edges := []LineageEdge{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var edge LineageEdge
+		if err := json.Unmarshal(queryResponse.Value, &edge); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal lineage edge: %v", err)
+		}
+		edges = append(edges, edge)
+	}
+
+	return edges, nil
+}
+
+// lineageNode : 노드의 표시 정보를 원장에서 채움 (원장에 없는 로트/배터리는 이름과 상태가 빈 값)
+func lineageNode(ctx contractapi.TransactionContextInterface, nodeID string, nodeType string, depth int) (LineageNode, error) {
+	node := LineageNode{ID: nodeID, Type: nodeType, Depth: depth}
+
+	switch nodeType {
+	case lineageNodeMaterial:
+		material, err := readMaterialState(ctx, nodeID)
+		if err != nil {
+			return node, err
+		}
+		if material != nil {
+			node.Label = material.Name
+			node.Status = material.Status
+		}
+	case lineageNodeBattery:
+		battery, err := readBatteryState(ctx, nodeID)
+		if err != nil {
+			return node, err
+		}
+		if battery != nil {
+			node.Label = battery.Category
+			node.Status = battery.Status
+		}
+	}
+
+	return node, nil
+}
+
+// traceLineage : 시작 노드에서 한 방향으로 너비 우선 탐색하여 도달 가능한 모든 노드와 간선을 수집
+func traceLineage(ctx contractapi.TransactionContextInterface, rootID string, rootType string, objectType string) (*LineageGraph, error) {
+	root, err := lineageNode(ctx, rootID, rootType, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &LineageGraph{
+		Root:  rootID,
+		Nodes: []LineageNode{root},
+		Edges: []LineageEdge{},
+	}
+	visited := map[string]bool{rootID: true}
+
+	queue := []LineageNode{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		edges, err := readLineageEdges(ctx, objectType, current.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, edge := range edges {
+			graph.Edges = append(graph.Edges, edge)
+
+			nextID, nextType := edge.To, edge.ToType
+			if objectType == lineageUpObjectType {
+				nextID, nextType = edge.From, edge.FromType
+			}
+			if visited[nextID] {
+				continue
+			}
+			visited[nextID] = true
+
+			next, err := lineageNode(ctx, nextID, nextType, current.Depth+1)
+			if err != nil {
+				return nil, err
+			}
+			graph.Nodes = append(graph.Nodes, next)
+			queue = append(queue, next)
+		}
+	}
+
+	return graph, nil
+}
+
+// TraceBatteryUpstream : 배터리에서 투입된 모든 원자재 로트까지 거슬러 올라가며,
+// 재활용 로트는 회수된 이전 배터리를 거쳐 그 배터리의 원천 로트까지 재귀적으로 추적
+func (s *PublicContract) TraceBatteryUpstream(ctx contractapi.TransactionContextInterface, batteryID string) (*LineageGraph, error) {
+	battery, err := readBatteryState(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if battery == nil {
+		return nil, fmt.Errorf("battery not found: %s", batteryID)
+	}
+
+	return traceLineage(ctx, batteryID, lineageNodeBattery, lineageUpObjectType)
+}
+
+// TraceMaterialDownstream : 원자재 로트를 소비한 모든 배터리를 추적하며,
+// 그 배터리에서 회수된 재활용 로트와 이를 다시 소비한 배터리까지 재귀적으로 추적 (리콜 대상 파악용)
+func (s *PublicContract) TraceMaterialDownstream(ctx contractapi.TransactionContextInterface, materialID string) (*LineageGraph, error) {
+	material, err := readMaterialState(ctx, materialID)
+	if err != nil {
+		return nil, err
+	}
+	if material == nil {
+		return nil, fmt.Errorf("raw material not found: %s", materialID)
+	}
+
+	return traceLineage(ctx, materialID, lineageNodeMaterial, lineageDownObjectType)
+}
+
+// MigrateLineage : 계보 간선 도입 이전에 제조된 배터리의 원자재 소비 간선을 생성
+// (이전에 회수된 재활용 로트는 원천 배터리 정보가 없으므로 연결할 수 없음)
+func (s *PublicContract) MigrateLineage(ctx contractapi.TransactionContextInterface) (int, error) {
+	if _, err := authorize(ctx, "MigrateLineage"); err != nil {
+		return 0, err
+	}
+
+	batteries, err := s.queryBatteriesByIndex(ctx, indexBatteryByStatus)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, battery := range batteries {
+		edges, err := readLineageEdges(ctx, lineageUpObjectType, battery.BatteryID)
+		if err != nil {
+			return migrated, err
+		}
+		if len(edges) > 0 || len(battery.RawMaterials) == 0 {
+			continue
+		}
+
+		err = recordConsumption(ctx, battery.BatteryID, battery.RawMaterials)
+		if err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	err = emitEvent(ctx, EventMigrationCompleted, eventAssetConfig, "lineage", MigrationCompletedPayload{Migration: "MigrateLineage", Migrated: migrated})
+	if err != nil {
+		return migrated, err
+	}
+
+	return migrated, nil
+}
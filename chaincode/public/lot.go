@@ -0,0 +1,418 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 로트 가용 상태
+const (
+	availabilityAvailable = "AVAILABLE"
+	// 전량이 하위 로트로 분할됨
+	availabilitySplit = "SPLIT"
+	// 다른 로트와 병합되어 새 로트로 대체됨
+	availabilityMerged = "MERGED"
+)
+
+// requireLotOwner : 호출 조직이 로트의 현재 소유자인지 확인
+// 소유자가 없는 로트(소유자 도입 이전 로트)는 MigrateLotOwners로 소유자를 채우기 전까지 아무도 처리할 수 없다.
+func requireLotOwner(caller *Caller, material *RawMaterial) error {
+	if material.Owner == "" {
+		return fmt.Errorf("lot %s has no owner: run MigrateLotOwners first", material.MaterialID)
+	}
+	if material.Owner != caller.MSPID {
+		return fmt.Errorf("caller %s is not the owner of lot %s (owner: %s)", caller.MSPID, material.MaterialID, material.Owner)
+	}
+	return nil
+}
+
+// requireAvailableLot : 분할·병합·이전할 수 있는 로트인지 확인
+func requireAvailableLot(material *RawMaterial) error {
+	if material.Availability != availabilityAvailable {
+		return fmt.Errorf("lot %s is not available: %s", material.MaterialID, material.Availability)
+	}
+	if material.Quantity.Milli <= 0 {
+		return fmt.Errorf("lot %s has no remaining quantity", material.MaterialID)
+	}
+	return nil
+}
+
+// requireUnreservedLot : 유효한 예약이 남아 있는 로트인지 확인 (예약이 가리키는 로트를 병합·이전하지 않도록)
+func requireUnreservedLot(ctx contractapi.TransactionContextInterface, material *RawMaterial) error {
+	availability, err := materialAvailability(ctx, material)
+	if err != nil {
+		return err
+	}
+	if availability.Reserved.Milli > 0 {
+		return fmt.Errorf("lot %s has active reservations (reserved: %s): release them first", material.MaterialID, availability.Reserved)
+	}
+	return nil
+}
+
+// lotProvenance : 병합 가능 여부를 판단하는 출처 정보 (원자재, 신규/재활용, 검증, 원천 배터리, 원산지 증빙)
+func lotProvenance(material *RawMaterial) string {
+	certificateHash := ""
+	if material.Origin != nil {
+		certificateHash = material.Origin.CertificateHash
+	}
+	return strings.Join([]string{material.Name, material.Status, material.Verified, material.VerificationID, material.SourceBatteryID, certificateHash}, "|")
+}
+
+// SplitLot : 로트에서 주어진 수량만큼 하위 로트를 분할 (남은 수량과 예약된 수량은 원 로트에 유지)
+// quantitiesJSON은 하위 로트별 수량 목록 (예: ["20 kg", "500 g"]), 하위 로트는 원 로트의 단위로 기록한다.
+func (s *PublicContract) SplitLot(ctx contractapi.TransactionContextInterface, materialID string, quantitiesJSON string) ([]string, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "SplitLot")
+	if err != nil {
+		return nil, err
+	}
+
+	var quantities []Quantity
+	err = json.Unmarshal([]byte(quantitiesJSON), &quantities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal split quantities: %v", err)
+	}
+	if len(quantities) == 0 {
+		return nil, fmt.Errorf("split requires at least one quantity")
+	}
+
+	parent, err := loadMaterial(ctx, materialID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireLotOwner(caller, parent); err != nil {
+		return nil, err
+	}
+	if err := requireAvailableLot(parent); err != nil {
+		return nil, err
+	}
+
+	// 분할 수량은 원 로트 단위로 정확히 환산되어야 함
+	var total int64
+	for i, quantity := range quantities {
+		subject := fmt.Sprintf("split %d of lot %s", i+1, materialID)
+		if err := quantity.validate(subject); err != nil {
+			return nil, err
+		}
+		converted, err := quantity.convert(parent.Quantity.Unit)
+		if err != nil {
+			return nil, fmt.Errorf("unit mismatch for %s: %v", subject, err)
+		}
+		if converted.Milli == 0 {
+			return nil, fmt.Errorf("quantity for %s must be greater than 0", subject)
+		}
+		quantities[i] = converted
+		total += converted.Milli
+	}
+
+	// 유효한 예약량은 원 로트에 남겨야 하므로 예약되지 않은 수량만 분할할 수 있음
+	availability, err := materialAvailability(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	if total > availability.Available.Milli {
+		return nil, fmt.Errorf("not enough quantity to split lot %s (requested: %s, available: %s)", materialID, Quantity{Milli: total, Unit: parent.Quantity.Unit}, availability.Available)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 하위 로트는 원 로트의 출처, 검증, 배출 계수, 소유자를 그대로 이어받음
+	childIDs := []string{}
+	children := []RawMaterial{}
+	for _, quantity := range quantities {
+		childID, err := newID(ctx, "MATERIAL")
+		if err != nil {
+			return nil, err
+		}
+
+		child := *parent
+		child.MaterialID = childID
+		child.Quantity = quantity
+		child.Availability = availabilityAvailable
+		child.Timestamp = now.Format(time.RFC3339)
+		child.ParentLotIDs = []string{materialID}
+
+		err = s.saveMaterial(ctx, &child)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store split lot: %v", err)
+		}
+
+		// 원 로트 → 하위 로트 계보 기록
+		edge, err := newLineageEdge(ctx, materialID, lineageNodeMaterial, childID, lineageNodeMaterial, lineageRelationSplit, quantity)
+		if err != nil {
+			return nil, err
+		}
+		if err := putLineageEdge(ctx, edge); err != nil {
+			return nil, err
+		}
+
+		childIDs = append(childIDs, childID)
+		children = append(children, child)
+	}
+
+	parent.Quantity.Milli -= total
+	if parent.Quantity.Milli == 0 {
+		parent.Availability = availabilitySplit
+	}
+	parent.Timestamp = now.Format(time.RFC3339)
+	err = s.saveMaterial(ctx, parent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update raw material: %v", err)
+	}
+
+	err = emitEvent(ctx, EventLotSplit, eventAssetMaterial, materialID, LotSplitPayload{Parent: parent, Children: children})
+	if err != nil {
+		return nil, err
+	}
+
+	return childIDs, nil
+}
+
+// MergeLots : 같은 원자재·상태·검증의 로트들을 하나의 새 로트로 병합 (원 로트는 MERGED)
+// 원천 배터리와 원산지 증빙도 같아야 하며, 배출 계수는 질량 가중 평균으로 계산한다. 유효한 예약이 있는 로트는 병합할 수 없다.
+func (s *PublicContract) MergeLots(ctx contractapi.TransactionContextInterface, materialIDsJSON string) (string, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "MergeLots")
+	if err != nil {
+		return "", err
+	}
+
+	var materialIDs []string
+	err = json.Unmarshal([]byte(materialIDsJSON), &materialIDs)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal lot IDs: %v", err)
+	}
+	if len(materialIDs) < 2 {
+		return "", fmt.Errorf("merge requires at least two lots")
+	}
+
+	parents := make([]RawMaterial, 0, len(materialIDs))
+	quantities := make([]Quantity, 0, len(materialIDs))
+	sources := []string{}
+	var emissions float64
+	for _, materialID := range materialIDs {
+		if containsString(materialIDs[:len(parents)], materialID) {
+			return "", fmt.Errorf("lot %s is listed more than once", materialID)
+		}
+
+		parent, err := loadMaterial(ctx, materialID)
+		if err != nil {
+			return "", err
+		}
+		if err := requireLotOwner(caller, parent); err != nil {
+			return "", err
+		}
+		if err := requireAvailableLot(parent); err != nil {
+			return "", err
+		}
+		if err := requireUnreservedLot(ctx, parent); err != nil {
+			return "", err
+		}
+		if len(parents) > 0 && lotProvenance(parent) != lotProvenance(&parents[0]) {
+			return "", fmt.Errorf("lot %s cannot be merged with %s: material, status, verification and provenance must match", materialID, parents[0].MaterialID)
+		}
+
+		emissions += parent.Quantity.kilograms() * parent.EmissionFactor
+		if parent.EmissionSource != "" && !containsString(sources, parent.EmissionSource) {
+			sources = append(sources, parent.EmissionSource)
+		}
+		quantities = append(quantities, parent.Quantity)
+		parents = append(parents, *parent)
+	}
+	sort.Strings(sources)
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	mergedID, err := newID(ctx, "MATERIAL")
+	if err != nil {
+		return "", err
+	}
+
+	merged := parents[0]
+	merged.MaterialID = mergedID
+	merged.Quantity = sumQuantities(quantities...)
+	merged.Owner = caller.MSPID
+	merged.Availability = availabilityAvailable
+	merged.Timestamp = now.Format(time.RFC3339)
+	merged.ParentLotIDs = materialIDs
+	merged.EmissionFactor = 0
+	merged.EmissionSource = strings.Join(sources, "; ")
+	if kilograms := merged.Quantity.kilograms(); kilograms > 0 && len(sources) > 0 {
+		merged.EmissionFactor = math.Round(emissions/kilograms*1e6) / 1e6
+	}
+
+	err = s.saveMaterial(ctx, &merged)
+	if err != nil {
+		return "", fmt.Errorf("failed to store merged lot: %v", err)
+	}
+
+	for i := range parents {
+		parent := &parents[i]
+
+		// 원 로트 → 병합 로트 계보 기록
+		edge, err := newLineageEdge(ctx, parent.MaterialID, lineageNodeMaterial, mergedID, lineageNodeMaterial, lineageRelationMerged, parent.Quantity)
+		if err != nil {
+			return "", err
+		}
+		if err := putLineageEdge(ctx, edge); err != nil {
+			return "", err
+		}
+
+		parent.Quantity.Milli = 0
+		parent.Availability = availabilityMerged
+		parent.Timestamp = now.Format(time.RFC3339)
+		err = s.saveMaterial(ctx, parent)
+		if err != nil {
+			return "", fmt.Errorf("failed to update raw material: %v", err)
+		}
+	}
+
+	err = emitEvent(ctx, EventLotsMerged, eventAssetMaterial, mergedID, LotsMergedPayload{Parents: parents, Merged: &merged})
+	if err != nil {
+		return "", err
+	}
+
+	return mergedID, nil
+}
+
+// TransferLot : 로트의 소유권을 다른 조직(MSP)으로 이전 (현재 소유자만 호출 가능, 유효한 예약이 있으면 거부)
+func (s *PublicContract) TransferLot(ctx contractapi.TransactionContextInterface, materialID string, newOwner string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "TransferLot")
+	if err != nil {
+		return err
+	}
+
+	newOwner = strings.TrimSpace(newOwner)
+	if newOwner == "" {
+		return fmt.Errorf("new owner must not be empty")
+	}
+
+	material, err := loadMaterial(ctx, materialID)
+	if err != nil {
+		return err
+	}
+	if err := requireLotOwner(caller, material); err != nil {
+		return err
+	}
+	if err := requireAvailableLot(material); err != nil {
+		return err
+	}
+	if err := requireUnreservedLot(ctx, material); err != nil {
+		return err
+	}
+
+	previousOwner := material.Owner
+	if previousOwner == newOwner {
+		return fmt.Errorf("lot %s is already owned by %s", materialID, newOwner)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	material.Owner = newOwner
+	material.Timestamp = now.Format(time.RFC3339)
+
+	err = s.saveMaterial(ctx, material)
+	if err != nil {
+		return fmt.Errorf("failed to update raw material: %v", err)
+	}
+
+	return emitEvent(ctx, EventLotTransferred, eventAssetMaterial, materialID, LotTransferredPayload{From: previousOwner, To: newOwner, Material: material})
+}
+
+// MigrateLotOwners : 소유자 도입 이전 로트(소유자 빈 값)에 소유자를 부여
+// 신규 로트는 newLotOwner, 재활용 로트는 recycledLotOwner에게 부여하며,
+// 빈 값이면 공급(재활용) 역할에 매핑된 유일한 조직을 사용한다.
+func (s *PublicContract) MigrateLotOwners(ctx contractapi.TransactionContextInterface, newLotOwner string, recycledLotOwner string) (int, error) {
+	if _, err := authorize(ctx, "MigrateLotOwners"); err != nil {
+		return 0, err
+	}
+
+	mapping, err := readRoleMapping(ctx)
+	if err != nil {
+		return 0, err
+	}
+	newLotOwner, err = ownerOrSoleRoleMSP(mapping, strings.TrimSpace(newLotOwner), roleSupplier)
+	if err != nil {
+		return 0, err
+	}
+	recycledLotOwner, err = ownerOrSoleRoleMSP(mapping, strings.TrimSpace(recycledLotOwner), roleRecycler)
+	if err != nil {
+		return 0, err
+	}
+
+	materials, err := s.loadMaterialsByIndex(ctx, indexMaterialByStatus)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for i := range materials {
+		material := &materials[i]
+		if material.Owner != "" {
+			continue
+		}
+
+		material.Owner = newLotOwner
+		if material.Status == "RECYCLED" {
+			material.Owner = recycledLotOwner
+		}
+		if err := s.saveMaterial(ctx, material); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	err = emitEvent(ctx, EventMigrationCompleted, eventAssetConfig, "lotOwners", MigrationCompletedPayload{Migration: "MigrateLotOwners", Migrated: migrated})
+	if err != nil {
+		return migrated, err
+	}
+
+	return migrated, nil
+}
+
+// derivedLots : 분할·병합으로 로트에서 파생된 모든 하위 로트 ID (계보 간선 기준, 탐색 순서)
+func derivedLots(ctx contractapi.TransactionContextInterface, materialID string) ([]string, error) {
+	derived := []string{}
+	visited := map[string]bool{materialID: true}
+
+	queue := []string{materialID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		edges, err := readLineageEdges(ctx, lineageDownObjectType, current)
+		if err != nil {
+			return nil, err
+		}
+		for _, edge := range edges {
+			if edge.Relation != lineageRelationSplit && edge.Relation != lineageRelationMerged {
+				continue
+			}
+			if visited[edge.To] {
+				continue
+			}
+			visited[edge.To] = true
+			derived = append(derived, edge.To)
+			queue = append(queue, edge.To)
+		}
+	}
+
+	return derived, nil
+}
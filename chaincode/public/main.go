@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
@@ -17,367 +17,487 @@ type PublicContract struct {
 
 // RawMaterial 관련 구조체 및 함수
 type RawMaterial struct {
-	MaterialID   string `json:"materialID"`
-	SupplierID   string `json:"supplierID"`
-	Name         string `json:"name"`
-	Quantity     int    `json:"quantity"`
-	Status       string `json:"status"`
-	Availability string `json:"availability"`
-	Verified     string `json:"verified"`
-	Timestamp    string `json:"timestamp"`
+	DocType         string   `json:"docType"`
+	MaterialID      string   `json:"materialID"`
+	SupplierID      string   `json:"supplierID"`
+	Owner           string   `json:"owner"` // 현재 소유 조직 (MSP, 소유자 도입 이전 로트는 MigrateLotOwners 전까지 빈 값)
+	Name            string   `json:"name"`
+	Quantity        Quantity `json:"quantity"` // 단위가 있는 고정 소수점 수량
+	Status          string   `json:"status"`
+	Availability    string   `json:"availability"`
+	Verified        string   `json:"verified"`
+	VerificationID  string   `json:"verificationID"` // 현재 검증 기록 (검증 기록 도입 이전 로트는 빈 값)
+	VerifiedUntil   string   `json:"verifiedUntil"`  // 현재 검증의 유효기간 (지나면 조회 시 미검증)
+	Timestamp       string   `json:"timestamp"`
+	SourceBatteryID string   `json:"sourceBatteryID"` // 재활용 로트가 회수된 원천 배터리 (신규 로트는 빈 값)
+	YieldPercent    float64  `json:"yieldPercent"`    // 원천 배터리 투입량 대비 회수율 (신규 로트는 0)
+	EmissionFactor  float64  `json:"emissionFactor"`  // kgCO2e/kg (재활용 로트는 회수 공정 배출량 기준)
+	EmissionSource  string   `json:"emissionSource"`  // 배출 계수 출처 (빈 값이면 배출 계수 없음)
+	LastModifiedBy  string   `json:"lastModifiedBy"`
+
+	ParentLotIDs []string             `json:"parentLotIDs,omitempty" metadata:",optional"` // 분할·병합으로 만들어진 로트의 원 로트
+	Origin       *MaterialOrigin      `json:"origin,omitempty" metadata:",optional"`       // 원산지와 인증 증빙 (신규 로트)
+	DueDiligence *DueDiligenceFinding `json:"dueDiligence,omitempty" metadata:",optional"` // 검증 기관의 실사 결과
 }
 
 type RawMaterialDetail struct {
-	MaterialID   string `json:"materialID"`
-	MaterialType string `json:"materialType"`
-	Quantity     int    `json:"quantity"`
-	Status       string `json:"status`
+	MaterialID       string   `json:"materialID"`
+	MaterialType     string   `json:"materialType"`
+	Quantity         Quantity `json:"quantity"` // 로트 단위로 환산된 투입량
+	Status           string   `json:"Status"`
+	HighRiskOverride string   `json:"highRiskOverride"` // 고위험 원산지 로트 투입 사유 (실사 기준이 REQUIRE_OVERRIDE일 때)
+	ReservationID    string   `json:"reservationID"`    // 생산 오더 예약에서 소비한 경우 예약 ID
 }
 
-func (s *PublicContract) RegisterRawMaterial(ctx contractapi.TransactionContextInterface, supplierID string, name string, quantity int) (string, error) {
+func (s *PublicContract) RegisterRawMaterial(ctx contractapi.TransactionContextInterface, supplierID string, name string, quantityText string, emissionFactor float64, emissionSource string, originJSON string) (string, error) {
 
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "RegisterRawMaterial")
 	if err != nil {
-		return "", fmt.Errorf("failed to get MSPID: %v", err)
+		return "", err
 	}
 
-	// org1만이 이 함수를 호출할 수 있도록 제한
-	if clientMSPID != "Org1MSP" {
-		return "", fmt.Errorf("permission denied: only RawMaterial Supplier ORG can register raw materials")
+	// 수량은 단위와 함께 받음 (예: "12.5 kg")
+	quantity, err := parseQuantity(quantityText)
+	if err != nil {
+		return "", err
 	}
 
-	materialID := fmt.Sprintf("MATERIAL-%s", uuid.New().String())
+	// 로트의 배출 계수(kgCO2e/kg) 확인 (출처가 없으면 배출 계수 없음으로 기록)
+	err = validateEmissionFactor(name, emissionFactor, emissionSource)
+	if err != nil {
+		return "", err
+	}
 
-	// 기존 원자재가 있는지 확인
-	existingRawMaterialAsBytes, err := ctx.GetStub().GetState(materialID)
+	// 원산지와 인증 증빙 확인 (빈 문자열이면 이후 SetMaterialOrigin으로 등록)
+	policy, err := readDueDiligencePolicy(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to read raw material: %v", err)
+		return "", err
+	}
+	origin, err := parseMaterialOrigin(originJSON, policy)
+	if err != nil {
+		return "", err
 	}
 
-	// 기존에 동일 ID의 원자재가 있으면 수량을 증가
-	if existingRawMaterialAsBytes != nil {
-		existingRawMaterial := new(RawMaterial)
-		err := json.Unmarshal(existingRawMaterialAsBytes, existingRawMaterial)
-		if err != nil {
-			return "", fmt.Errorf("failed to unmarshal raw material: %v", err)
-		}
+	materialID, err := newID(ctx, "MATERIAL")
+	if err != nil {
+		return "", err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// 신규 원자재 등록 (같은 원자재의 로트를 합치려면 MergeLots 사용)
+	rawMaterial := RawMaterial{
+		MaterialID:     materialID,
+		SupplierID:     supplierID,
+		Owner:          caller.MSPID,
+		Name:           name,
+		Verified:       "NOT VERIFIED",
+		Quantity:       quantity,
+		Status:         "NEW",
+		Availability:   "AVAILABLE",
+		Timestamp:      now.Format(time.RFC3339),
+		EmissionFactor: emissionFactor,
+		EmissionSource: strings.TrimSpace(emissionSource),
+		Origin:         origin,
+	}
+
+	err = s.saveMaterial(ctx, &rawMaterial)
+	if err != nil {
+		return "", err
+	}
 
-		existingRawMaterial.Quantity += quantity
-		existingRawMaterial.Timestamp = time.Now().Format(time.RFC3339)
+	err = emitEvent(ctx, EventMaterialRegistered, eventAssetMaterial, materialID, MaterialEventPayload{Material: &rawMaterial})
+	if err != nil {
+		return "", err
+	}
 
-		updatedRawMaterialAsBytes, err := json.Marshal(existingRawMaterial)
+	// 단가·계약 조건은 transient "commercialTerms"(솔트는 "salt")로 받아 공급사 컬렉션에만 저장
+	hasTerms, err := hasTransient(ctx, transientCommercialTerms)
+	if err != nil {
+		return "", err
+	}
+	if hasTerms {
+		termsAsBytes, err := readTransient(ctx, transientCommercialTerms)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal updated raw material: %v", err)
+			return "", err
 		}
-
-		err = ctx.GetStub().PutState(materialID, updatedRawMaterialAsBytes)
+		terms, err := parseCommercialTerms(ctx, materialID, termsAsBytes)
 		if err != nil {
-			return "", fmt.Errorf("failed to update raw material: %v", err)
+			return "", err
+		}
+		_, err = putPrivateWithReference(ctx, materialID, collectionSupplierCommercial, materialID, terms)
+		if err != nil {
+			return "", err
 		}
-
-		return materialID, nil
 	}
 
-	// 신규 원자재 등록
-	rawMaterial := RawMaterial{
-		MaterialID:   materialID,
-		SupplierID:   supplierID,
-		Name:         name,
-		Verified:     "NOT VERIFIED",
-		Quantity:     quantity,
-		Status:       "NEW",
-		Availability: "AVAILABLE",
-		Timestamp:    time.Now().Format(time.RFC3339),
+	// 생성된 materialID 반환
+	return materialID, nil
+}
+
+func (s *PublicContract) VerifyMaterial(ctx contractapi.TransactionContextInterface, materialID string, verificationJSON string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "VerifyMaterial")
+	if err != nil {
+		return err
 	}
 
-	rawMaterialAsBytes, err := json.Marshal(rawMaterial)
+	// materialID로 원자재 조회
+	material, err := loadMaterial(ctx, materialID)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal raw material: %v", err)
+		return err
 	}
 
-	err = ctx.GetStub().PutState(materialID, rawMaterialAsBytes)
+	// 원산지 증빙과 대조한 실사 결과 기록 (부적합이면 "REJECTED")
+	finding, err := newDueDiligenceFinding(ctx, material, verificationJSON)
 	if err != nil {
-		return "", fmt.Errorf("failed to store raw material: %v", err)
+		return err
+	}
+	outcome := verificationOutcomeVerified
+	if finding.Result != findingConformant {
+		outcome = verificationOutcomeRejected
 	}
 
-	// 생성된 materialID 반환
-	return materialID, nil
-}
+	// 검증 방법, 증빙, 범위, 유효기간을 검증 기록으로 남김 (대조한 인증서 해시는 증빙에 포함)
+	verification, err := newVerification(ctx, eventAssetMaterial, materialID, outcome, verificationJSON, finding.CertificateHash)
+	if err != nil {
+		return err
+	}
+	err = saveVerification(ctx, verification)
+	if err != nil {
+		return err
+	}
 
-func (s *PublicContract) VerifyMaterial(ctx contractapi.TransactionContextInterface, materialID string) error {
+	material.DueDiligence = finding
+	material.Verified = verification.Outcome
+	material.VerificationID = verification.VerificationID
+	material.VerifiedUntil = verification.ValidUntil
 
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	// 업데이트된 원자재를 원장에 저장
+	err = s.saveMaterial(ctx, material)
 	if err != nil {
-		return fmt.Errorf("failed to get MSPID: %v", err)
+		return fmt.Errorf("failed to update material: %v", err)
 	}
 
-	if clientMSPID != "Org7MSP" {
-		return fmt.Errorf("permission denied: only Verify ORG can verify material")
+	return emitEvent(ctx, EventMaterialVerified, eventAssetMaterial, materialID, MaterialEventPayload{Material: material})
+}
+
+// 초기 데이터 등록 기록 키 (seed~대상, 값은 등록한 자산 ID 목록)
+const (
+	seedObjectType = "seed"
+
+	seedMaterials = "materials"
+	seedBatteries = "batteries"
+)
+
+// readSeed : 초기 데이터로 등록한 자산 ID 목록 (등록 전이면 nil)
+func readSeed(ctx contractapi.TransactionContextInterface, name string) ([]string, error) {
+	seedKey, err := ctx.GetStub().CreateCompositeKey(seedObjectType, []string{name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create seed key: %v", err)
 	}
 
-	// materialID로 원자재 조회
-	materialAsBytes, err := ctx.GetStub().GetState(materialID)
+	seedAsBytes, err := ctx.GetStub().GetState(seedKey)
 	if err != nil {
-		return fmt.Errorf("failed to read material: %v", err)
+		return nil, fmt.Errorf("failed to read seed record: %v", err)
 	}
-	if materialAsBytes == nil {
-		return fmt.Errorf("material not found: %s", materialID)
+	if seedAsBytes == nil {
+		return nil, nil
 	}
 
-	// 원자재 정보를 언마샬링
-	var material RawMaterial
-	err = json.Unmarshal(materialAsBytes, &material)
+	var assetIDs []string
+	err = json.Unmarshal(seedAsBytes, &assetIDs)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal material: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal seed record: %v", err)
 	}
 
-	// VerifiedBy 값을 "Verified"로 변경
-	material.Verified = "VERIFIED"
+	return assetIDs, nil
+}
 
-	// 업데이트된 원자재를 다시 마샬링하여 원장에 저장
-	updatedMaterialAsBytes, err := json.Marshal(material)
+// saveSeed : 초기 데이터로 등록한 자산 ID 목록을 기록 (이후 호출은 아무것도 등록하지 않음)
+func saveSeed(ctx contractapi.TransactionContextInterface, name string, assetIDs []string) error {
+	seedKey, err := ctx.GetStub().CreateCompositeKey(seedObjectType, []string{name})
 	if err != nil {
-		return fmt.Errorf("failed to marshal updated material: %v", err)
+		return fmt.Errorf("failed to create seed key: %v", err)
 	}
 
-	err = ctx.GetStub().PutState(material.MaterialID, updatedMaterialAsBytes)
+	seedAsBytes, err := json.Marshal(assetIDs)
 	if err != nil {
-		return fmt.Errorf("failed to update material: %v", err)
+		return fmt.Errorf("failed to marshal seed record: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(seedKey, seedAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put seed record: %v", err)
 	}
 
 	return nil
 }
 
-// InitMaterials : 원장에 신규 원자재와 재활용 원자재를 초기화하는 함수
+// InitMaterials : 원장에 신규 원자재와 재활용 원자재를 초기화하는 함수 (관리자, 한 번만 등록)
+// 초기 로트는 미검증 상태로 등록되며, 사용하려면 VerifyMaterial로 증빙과 함께 검증해야 한다.
 func (s *PublicContract) InitMaterials(ctx contractapi.TransactionContextInterface) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	if _, err := authorize(ctx, "InitMaterials"); err != nil {
+		return err
+	}
+
+	// 이미 초기화되었으면 다시 등록하지 않음
+	seeded, err := readSeed(ctx, seedMaterials)
+	if err != nil {
+		return err
+	}
+	if seeded != nil {
+		return nil
+	}
+
+	_, err = s.seedInitialMaterials(ctx)
+	return err
+}
+
+// seedInitialMaterials : 초기 원자재 로트를 등록하고 등록한 로트를 반환
+func (s *PublicContract) seedInitialMaterials(ctx contractapi.TransactionContextInterface) ([]RawMaterial, error) {
 	// 신규 원자재
 	newMaterials := []RawMaterial{
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "org1",
 			Name:         "Lithium",
-			Quantity:     100,
+			Quantity:     wholeQuantity(100, unitKilogram),
 			Status:       "NEW",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "org1",
 			Name:         "Cobalt",
-			Quantity:     150,
+			Quantity:     wholeQuantity(150, unitKilogram),
 			Status:       "NEW",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Manganese",
-			Quantity:     70,
+			Quantity:     wholeQuantity(70, unitKilogram),
 			Status:       "NEW",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Nickel",
-			Quantity:     200,
+			Quantity:     wholeQuantity(200, unitKilogram),
 			Status:       "NEW",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Lithium",
-			Quantity:     500,
+			Quantity:     wholeQuantity(500, unitKilogram),
 			Status:       "NEW",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Cobalt",
-			Quantity:     350,
+			Quantity:     wholeQuantity(350, unitKilogram),
 			Status:       "NEW",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Manganese",
-			Quantity:     570,
+			Quantity:     wholeQuantity(570, unitKilogram),
 			Status:       "NEW",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Nickel",
-			Quantity:     500,
+			Quantity:     wholeQuantity(500, unitKilogram),
 			Status:       "NEW",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 	}
 
 	// 재활용 원자재
 	recycledMaterials := []RawMaterial{
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Nickel",
-			Quantity:     50,
+			Quantity:     wholeQuantity(50, unitKilogram),
 			Status:       "RECYCLED",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Manganese",
-			Quantity:     40,
+			Quantity:     wholeQuantity(40, unitKilogram),
 			Status:       "RECYCLED",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Lithium",
-			Quantity:     30,
+			Quantity:     wholeQuantity(30, unitKilogram),
 			Status:       "RECYCLED",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Cobalt",
-			Quantity:     30,
+			Quantity:     wholeQuantity(30, unitKilogram),
 			Status:       "RECYCLED",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Nickel",
-			Quantity:     50,
+			Quantity:     wholeQuantity(50, unitKilogram),
 			Status:       "RECYCLED",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Manganese",
-			Quantity:     40,
+			Quantity:     wholeQuantity(40, unitKilogram),
 			Status:       "RECYCLED",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Lithium",
-			Quantity:     30,
+			Quantity:     wholeQuantity(30, unitKilogram),
 			Status:       "RECYCLED",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 		{
-			MaterialID:   fmt.Sprintf("MATERIAL-%s", uuid.New().String()),
 			SupplierID:   "SUPPLIER-001",
 			Name:         "Cobalt",
-			Quantity:     30,
+			Quantity:     wholeQuantity(30, unitKilogram),
 			Status:       "RECYCLED",
-			Verified:     "VERIFIED",
+			Verified:     notVerifiedLabel,
 			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
 		},
 	}
 
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 신규 로트는 공급 조직, 재활용 로트는 재활용 조직이 소유
+	mapping, err := readRoleMapping(ctx)
+	if err != nil {
+		return nil, err
+	}
+	newLotOwner, err := seedOwner(mapping, roleSupplier)
+	if err != nil {
+		return nil, err
+	}
+	recycledLotOwner, err := seedOwner(mapping, roleRecycler)
+	if err != nil {
+		return nil, err
+	}
+
+	materialIDs := make([]string, 0, len(newMaterials)+len(recycledMaterials))
+
 	// 신규 원자재를 원장에 저장
-	for _, material := range newMaterials {
-		materialAsBytes, err := json.Marshal(material)
+	for i := range newMaterials {
+		newMaterials[i].MaterialID, err = newID(ctx, "MATERIAL")
+		if err != nil {
+			return nil, err
+		}
+		newMaterials[i].Owner = newLotOwner
+		newMaterials[i].Timestamp = now.Format(time.RFC3339)
+		materialIDs = append(materialIDs, newMaterials[i].MaterialID)
+
+		err = s.saveMaterial(ctx, &newMaterials[i])
 		if err != nil {
-			return fmt.Errorf("failed to marshal new material: %v", err)
+			return nil, fmt.Errorf("failed to put new material to ledger: %v", err)
 		}
 
-		err = ctx.GetStub().PutState(material.MaterialID, materialAsBytes)
+		err = emitEvent(ctx, EventMaterialRegistered, eventAssetMaterial, newMaterials[i].MaterialID, MaterialEventPayload{Material: &newMaterials[i]})
 		if err != nil {
-			return fmt.Errorf("failed to put new material to ledger: %v", err)
+			return nil, err
 		}
 	}
 
 	// 재활용 원자재를 원장에 저장
-	for _, material := range recycledMaterials {
-		materialAsBytes, err := json.Marshal(material)
+	for i := range recycledMaterials {
+		recycledMaterials[i].MaterialID, err = newID(ctx, "MATERIAL")
+		if err != nil {
+			return nil, err
+		}
+		recycledMaterials[i].Owner = recycledLotOwner
+		recycledMaterials[i].Timestamp = now.Format(time.RFC3339)
+		materialIDs = append(materialIDs, recycledMaterials[i].MaterialID)
+
+		err = s.saveMaterial(ctx, &recycledMaterials[i])
 		if err != nil {
-			return fmt.Errorf("failed to marshal recycled material: %v", err)
+			return nil, fmt.Errorf("failed to put recycled material to ledger: %v", err)
 		}
 
-		err = ctx.GetStub().PutState(material.MaterialID, materialAsBytes)
+		err = emitEvent(ctx, EventMaterialRegistered, eventAssetMaterial, recycledMaterials[i].MaterialID, MaterialEventPayload{Material: &recycledMaterials[i]})
 		if err != nil {
-			return fmt.Errorf("failed to put recycled material to ledger: %v", err)
+			return nil, err
 		}
 	}
 
-	return nil
+	err = saveSeed(ctx, seedMaterials, materialIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(newMaterials, recycledMaterials...), nil
 }
 
 func (s *PublicContract) QueryMaterial(ctx contractapi.TransactionContextInterface, materialID string) (*RawMaterial, error) {
-	rawMaterialAsBytes, err := ctx.GetStub().GetState(materialID)
+	rawMaterial, err := readMaterialState(ctx, materialID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read raw material: %v", err)
+		return nil, err
 	}
 
-	if rawMaterialAsBytes == nil {
+	if rawMaterial == nil {
 		return nil, fmt.Errorf("raw material not found: %s", materialID)
 	}
 
-	rawMaterial := new(RawMaterial)
-	err = json.Unmarshal(rawMaterialAsBytes, rawMaterial)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal raw material: %v", err)
-	}
-
 	return rawMaterial, nil
 }
 
 // Battery 관련 구조체 및 함수
 type Battery struct {
+	DocType                  string                       `json:"docType"`
 	BatteryID                string                       `json:"batteryID"`
-	PassportID               string                       `json:"passportID`
+	PassportID               string                       `json:"PassportID"`
 	RawMaterials             map[string]RawMaterialDetail `json:"rawMaterials"`
 	ManufactureDate          time.Time                    `json:"manufactureDate"`
 	ManufacturerName         string                       `json:"ManufacturerName"`
+	Owner                    string                       `json:"owner"` // 현재 소유 조직 (MSP, 소유자 도입 이전 배터리는 MigrateBatteryOwners 전까지 빈 값)
 	Location                 string                       `json:"location"`
 	Category                 string                       `json:"category"`
 	Weight                   float64                      `json:"weight"`
 	Status                   string                       `json:"status"`
-	Verified                 string                       `'json:"verifed"`
+	Verified                 string                       `json:"Verified"`
+	VerificationID           string                       `json:"verificationID"`     // 현재 검증 기록 (검증 기록 도입 이전 배터리는 빈 값)
+	VerifiedUntil            string                       `json:"verifiedUntil"`      // 현재 검증의 유효기간 (지나면 조회 시 미검증)
 	Capacity                 float64                      `json:"capacity"`           //P
 	Voltage                  float64                      `json:"voltage"`            //P
 	SOC                      float64                      `json:"soc"`                //I
@@ -392,76 +512,158 @@ type Battery struct {
 	ContainsHazardous        string                       `json:"containsHazardous"` //P
 	RecycleAvailability      bool                         `json:"recycleAvailability"`
 	RecyclingRatesByMaterial map[string]float64           `json:"recyclingRatesByMaterial"`
+	ComplianceStatus         string                       `json:"complianceStatus"` // 재생 원료 함량 평가 결과 (평가 이전 배터리는 빈 값)
+	Grade                    string                       `json:"grade"`            // 현재 분석의 2차 사용 등급 (A/B/C, 미평가 시 빈 값)
+	GradeID                  string                       `json:"gradeID"`
+	PredecessorPassportID    string                       `json:"predecessorPassportID"` // 2차 사용 전환 이전의 여권 (전환 이전 배터리는 빈 값)
+	ProductionOrderID        string                       `json:"productionOrderID"`     // 일괄 생산된 배터리의 생산 오더 (단건 생산은 빈 값)
+	SerialNumber             string                       `json:"serialNumber"`          // 일괄 생산 시 모델별 순차 일련번호 (단건 생산은 빈 값)
+	CycleCount               int                          `json:"cycleCount"`            // 마지막 사용량 보고의 누적 주기 수 (보고 이전 배터리는 0)
+	EnergyThroughputKWh      float64                      `json:"energyThroughputKWh"`   // 마지막 사용량 보고의 누적 에너지 처리량
+	OdometerKm               float64                      `json:"odometerKm"`            // 마지막 사용량 보고의 누적 주행 거리
+	LastUsageReportedAt      string                       `json:"lastUsageReportedAt"`   // 마지막 사용량 보고의 측정 시각 (보고 이전 배터리는 빈 값)
+	LastModifiedBy           string                       `json:"lastModifiedBy"`
 }
 
-func (s *PublicContract) VerifyBattery(ctx contractapi.TransactionContextInterface, batteryID string) error {
+func (s *PublicContract) VerifyBattery(ctx contractapi.TransactionContextInterface, batteryID string, verificationJSON string) error {
 
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "VerifyBattery")
 	if err != nil {
-		return fmt.Errorf("failed to get MSPID: %v", err)
-	}
-
-	if clientMSPID != "Org7MSP" {
-		return fmt.Errorf("permission denied: only Verify ORG can verify Battery")
+		return err
 	}
 
 	// batteryID로 배터리 조회
-	batteryAsBytes, err := ctx.GetStub().GetState(batteryID)
+	battery, err := loadBattery(ctx, batteryID)
 	if err != nil {
-		return fmt.Errorf("failed to read battery: %v", err)
+		return err
 	}
-	if batteryAsBytes == nil {
-		return fmt.Errorf("battery not found: %s", batteryID)
+
+	// 해체된 배터리는 검증할 수 없음
+	err = requireBatteryStatus(battery, "VERIFY", statusManufactured, statusInService, statusUnderMaintenance, statusUnderAnalysis, statusSecondLife, statusEndOfLife)
+	if err != nil {
+		return err
 	}
 
-	// 배터리 정보를 언마샬링
-	var battery Battery
-	err = json.Unmarshal(batteryAsBytes, &battery)
+	// 검증 방법, 증빙, 범위, 유효기간을 검증 기록으로 남김
+	verification, err := newVerification(ctx, eventAssetBattery, batteryID, verificationOutcomeVerified, verificationJSON)
+	if err != nil {
+		return err
+	}
+	err = saveVerification(ctx, verification)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal battery: %v", err)
+		return err
 	}
 
-	// Verified 필드를 "Verified"로 변경
-	battery.Verified = "VERIFIED"
+	// Verified 필드를 "Verified"로 변경하고 현재 검증 기록을 연결
+	battery.Verified = verification.Outcome
+	battery.VerificationID = verification.VerificationID
+	battery.VerifiedUntil = verification.ValidUntil
 
-	// 업데이트된 배터리를 다시 마샬링하여 원장에 저장
-	updatedBatteryAsBytes, err := json.Marshal(battery)
+	// 업데이트된 배터리를 원장에 저장
+	err = s.saveBattery(ctx, battery)
 	if err != nil {
-		return fmt.Errorf("failed to marshal updated battery: %v", err)
+		return fmt.Errorf("failed to update battery: %v", err)
+	}
+
+	return emitEvent(ctx, EventBatteryVerified, eventAssetBattery, batteryID, BatteryEventPayload{Battery: battery})
+}
+
+// seedOwner : 초기 데이터를 소유할 조직 (역할에 매핑된 첫 번째 조직)
+func seedOwner(mapping *RoleMapping, role string) (string, error) {
+	msps := mapping.RoleMSPs[role]
+	if len(msps) == 0 {
+		return "", fmt.Errorf("no MSP is mapped to role %s for initial data", role)
 	}
+	return msps[0], nil
+}
 
-	err = ctx.GetStub().PutState(battery.BatteryID, updatedBatteryAsBytes)
+// initialMaterialLots : 초기 데이터로 등록된 원자재 로트 (아직 없으면 등록)
+func (s *PublicContract) initialMaterialLots(ctx contractapi.TransactionContextInterface) ([]RawMaterial, error) {
+	materialIDs, err := readSeed(ctx, seedMaterials)
 	if err != nil {
-		return fmt.Errorf("failed to update battery: %v", err)
+		return nil, err
+	}
+	if materialIDs == nil {
+		return s.seedInitialMaterials(ctx)
+	}
+
+	lots := make([]RawMaterial, 0, len(materialIDs))
+	for _, materialID := range materialIDs {
+		lot, err := readMaterialState(ctx, materialID)
+		if err != nil {
+			return nil, err
+		}
+		if lot == nil {
+			return nil, fmt.Errorf("initial raw material not found: %s", materialID)
+		}
+		lots = append(lots, *lot)
 	}
 
+	return lots, nil
+}
+
+// assignInitialLots : 배터리의 투입 원자재마다 종류와 상태가 같은 초기 로트를 배정 (배터리 순번으로 돌아가며 배정)
+func assignInitialLots(rawMaterials map[string]RawMaterialDetail, lots []RawMaterial, index int) error {
+	for key, detail := range rawMaterials {
+		candidates := []string{}
+		for _, lot := range lots {
+			if lot.Name == detail.MaterialType && lot.Status == detail.Status {
+				candidates = append(candidates, lot.MaterialID)
+			}
+		}
+		if len(candidates) == 0 {
+			return fmt.Errorf("no initial raw material lot for %s (%s)", detail.MaterialType, detail.Status)
+		}
+
+		detail.MaterialID = candidates[index%len(candidates)]
+		rawMaterials[key] = detail
+	}
 	return nil
 }
 
-// InitBatteries : 원장에 초기 배터리 데이터를 등록하는 함수
+// InitBatteries : 원장에 초기 배터리 데이터를 등록하는 함수 (관리자, 한 번만 등록)
+// 초기 배터리는 미검증 상태로 등록되며, 검증하려면 VerifyBattery로 증빙과 함께 검증해야 한다.
 func (s *PublicContract) InitBatteries(ctx contractapi.TransactionContextInterface) error {
-	// 초기 배터리 데이터 설정
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	if _, err := authorize(ctx, "InitBatteries"); err != nil {
+		return err
+	}
+
+	// 이미 초기화되었으면 다시 등록하지 않음
+	seeded, err := readSeed(ctx, seedBatteries)
+	if err != nil {
+		return err
+	}
+	if seeded != nil {
+		return nil
+	}
+
+	// 계보 간선이 가리킬 초기 원자재 로트 (아직 없으면 먼저 등록)
+	lots, err := s.initialMaterialLots(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 초기 배터리 데이터 설정 (투입 원자재는 종류와 상태가 같은 초기 로트에 배정)
 	initialBatteries := []Battery{
 		{
-			BatteryID:  fmt.Sprintf("BATTERY-%s", uuid.New().String()),
-			PassportID: uuid.New().String(),
 			RawMaterials: map[string]RawMaterialDetail{
-				"material1": {MaterialID: "MATERIAL-001", MaterialType: "Lithium", Quantity: 100, Status: "NEW"},
-				"material2": {MaterialID: "MATERIAL-002", MaterialType: "Cobalt", Quantity: 100, Status: "NEW"},
-				"material3": {MaterialID: "MATERIAL-003", MaterialType: "Manganese", Quantity: 80, Status: "NEW"},
-				"material4": {MaterialID: "MATERIAL-004", MaterialType: "Nickel", Quantity: 60, Status: "NEW"},
-				"material5": {MaterialID: "MATERIAL-005", MaterialType: "Lithium", Quantity: 20, Status: "RECYCLED"},
-				"material6": {MaterialID: "MATERIAL-006", MaterialType: "Cobalt", Quantity: 40, Status: "RECYCLED"},
-				"material7": {MaterialID: "MATERIAL-007", MaterialType: "Manganese", Quantity: 30, Status: "RECYCLED"},
-				"material8": {MaterialID: "MATERIAL-008", MaterialType: "Nickel", Quantity: 20, Status: "RECYCLED"},
+				"material1": {MaterialType: "Lithium", Quantity: wholeQuantity(100, unitKilogram), Status: "NEW"},
+				"material2": {MaterialType: "Cobalt", Quantity: wholeQuantity(100, unitKilogram), Status: "NEW"},
+				"material3": {MaterialType: "Manganese", Quantity: wholeQuantity(80, unitKilogram), Status: "NEW"},
+				"material4": {MaterialType: "Nickel", Quantity: wholeQuantity(60, unitKilogram), Status: "NEW"},
+				"material5": {MaterialType: "Lithium", Quantity: wholeQuantity(20, unitKilogram), Status: "RECYCLED"},
+				"material6": {MaterialType: "Cobalt", Quantity: wholeQuantity(40, unitKilogram), Status: "RECYCLED"},
+				"material7": {MaterialType: "Manganese", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material8": {MaterialType: "Nickel", Quantity: wholeQuantity(20, unitKilogram), Status: "RECYCLED"},
 			},
-			ManufactureDate:     time.Now(),
 			ManufacturerName:    "LG Energy Solution",
 			Location:            "Pyeongtaek, Korea",
 			Category:            "EV Battery",
 			Weight:              590.5,
-			Status:              "ORIGINAL",
-			Verified:            "NOT VERIFIED",
+			Verified:            notVerifiedLabel,
 			Capacity:            3000.0,
 			Voltage:             350.0,
 			SOC:                 100.0,
@@ -481,25 +683,21 @@ func (s *PublicContract) InitBatteries(ctx contractapi.TransactionContextInterfa
 			},
 		},
 		{
-			BatteryID:  fmt.Sprintf("BATTERY-%s", uuid.New().String()),
-			PassportID: uuid.New().String(),
 			RawMaterials: map[string]RawMaterialDetail{
-				"material1": {MaterialID: "MATERIAL-009", MaterialType: "Lithium", Quantity: 90, Status: "NEW"},
-				"material2": {MaterialID: "MATERIAL-010", MaterialType: "Cobalt", Quantity: 800, Status: "NEW"},
-				"material3": {MaterialID: "MATERIAL-011", MaterialType: "Manganese", Quantity: 80, Status: "NEW"},
-				"material4": {MaterialID: "MATERIAL-012", MaterialType: "Nickel", Quantity: 100, Status: "NEW"},
-				"material5": {MaterialID: "MATERIAL-013", MaterialType: "Lithium", Quantity: 10, Status: "RECYCLED"},
-				"material6": {MaterialID: "MATERIAL-014", MaterialType: "Cobalt", Quantity: 30, Status: "RECYCLED"},
-				"material7": {MaterialID: "MATERIAL-015", MaterialType: "Manganese", Quantity: 30, Status: "RECYCLED"},
-				"material8": {MaterialID: "MATERIAL-016", MaterialType: "Nickel", Quantity: 20, Status: "RECYCLED"},
+				"material1": {MaterialType: "Lithium", Quantity: wholeQuantity(90, unitKilogram), Status: "NEW"},
+				"material2": {MaterialType: "Cobalt", Quantity: wholeQuantity(800, unitKilogram), Status: "NEW"},
+				"material3": {MaterialType: "Manganese", Quantity: wholeQuantity(80, unitKilogram), Status: "NEW"},
+				"material4": {MaterialType: "Nickel", Quantity: wholeQuantity(100, unitKilogram), Status: "NEW"},
+				"material5": {MaterialType: "Lithium", Quantity: wholeQuantity(10, unitKilogram), Status: "RECYCLED"},
+				"material6": {MaterialType: "Cobalt", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material7": {MaterialType: "Manganese", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material8": {MaterialType: "Nickel", Quantity: wholeQuantity(20, unitKilogram), Status: "RECYCLED"},
 			},
-			ManufactureDate:     time.Now(),
 			ManufacturerName:    "LG Energy Solution",
 			Location:            "Pyeongtaek, Korea",
 			Category:            "EV Battery",
 			Weight:              600.0,
-			Status:              "ORIGINAL",
-			Verified:            "NOT VERIFIED",
+			Verified:            notVerifiedLabel,
 			Capacity:            77.4,
 			Voltage:             400.0,
 			SOC:                 100.0,
@@ -519,25 +717,21 @@ func (s *PublicContract) InitBatteries(ctx contractapi.TransactionContextInterfa
 			},
 		},
 		{
-			BatteryID:  fmt.Sprintf("BATTERY-%s", uuid.New().String()),
-			PassportID: uuid.New().String(),
 			RawMaterials: map[string]RawMaterialDetail{
-				"material1": {MaterialID: "MATERIAL-017", MaterialType: "Lithium", Quantity: 90, Status: "NEW"},
-				"material2": {MaterialID: "MATERIAL-018", MaterialType: "Cobalt", Quantity: 800, Status: "NEW"},
-				"material3": {MaterialID: "MATERIAL-019", MaterialType: "Manganese", Quantity: 80, Status: "NEW"},
-				"material4": {MaterialID: "MATERIAL-020", MaterialType: "Nickel", Quantity: 100, Status: "NEW"},
-				"material5": {MaterialID: "MATERIAL-021", MaterialType: "Lithium", Quantity: 10, Status: "RECYCLED"},
-				"material6": {MaterialID: "MATERIAL-022", MaterialType: "Cobalt", Quantity: 30, Status: "RECYCLED"},
-				"material7": {MaterialID: "MATERIAL-023", MaterialType: "Manganese", Quantity: 30, Status: "RECYCLED"},
-				"material8": {MaterialID: "MATERIAL-024", MaterialType: "Nickel", Quantity: 20, Status: "RECYCLED"},
+				"material1": {MaterialType: "Lithium", Quantity: wholeQuantity(90, unitKilogram), Status: "NEW"},
+				"material2": {MaterialType: "Cobalt", Quantity: wholeQuantity(800, unitKilogram), Status: "NEW"},
+				"material3": {MaterialType: "Manganese", Quantity: wholeQuantity(80, unitKilogram), Status: "NEW"},
+				"material4": {MaterialType: "Nickel", Quantity: wholeQuantity(100, unitKilogram), Status: "NEW"},
+				"material5": {MaterialType: "Lithium", Quantity: wholeQuantity(10, unitKilogram), Status: "RECYCLED"},
+				"material6": {MaterialType: "Cobalt", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material7": {MaterialType: "Manganese", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material8": {MaterialType: "Nickel", Quantity: wholeQuantity(20, unitKilogram), Status: "RECYCLED"},
 			},
-			ManufactureDate:     time.Now(),
 			ManufacturerName:    "LG Energy Solution",
 			Location:            "Pyeongtaek, Korea",
 			Category:            "EV Battery",
 			Weight:              599.5,
-			Status:              "ORIGINAL",
-			Verified:            "VERIFIED",
+			Verified:            notVerifiedLabel,
 			Capacity:            72.6,
 			Voltage:             400.0,
 			SOC:                 100.0,
@@ -557,25 +751,21 @@ func (s *PublicContract) InitBatteries(ctx contractapi.TransactionContextInterfa
 			},
 		},
 		{
-			BatteryID:  fmt.Sprintf("BATTERY-%s", uuid.New().String()),
-			PassportID: uuid.New().String(),
 			RawMaterials: map[string]RawMaterialDetail{
-				"material1": {MaterialID: "MATERIAL-025", MaterialType: "Lithium", Quantity: 90, Status: "NEW"},
-				"material2": {MaterialID: "MATERIAL-026", MaterialType: "Cobalt", Quantity: 800, Status: "NEW"},
-				"material3": {MaterialID: "MATERIAL-027", MaterialType: "Manganese", Quantity: 80, Status: "NEW"},
-				"material4": {MaterialID: "MATERIAL-028", MaterialType: "Nickel", Quantity: 100, Status: "NEW"},
-				"material5": {MaterialID: "MATERIAL-029", MaterialType: "Lithium", Quantity: 10, Status: "RECYCLED"},
-				"material6": {MaterialID: "MATERIAL-030", MaterialType: "Cobalt", Quantity: 30, Status: "RECYCLED"},
-				"material7": {MaterialID: "MATERIAL-031", MaterialType: "Manganese", Quantity: 30, Status: "RECYCLED"},
-				"material8": {MaterialID: "MATERIAL-032", MaterialType: "Nickel", Quantity: 20, Status: "RECYCLED"},
+				"material1": {MaterialType: "Lithium", Quantity: wholeQuantity(90, unitKilogram), Status: "NEW"},
+				"material2": {MaterialType: "Cobalt", Quantity: wholeQuantity(800, unitKilogram), Status: "NEW"},
+				"material3": {MaterialType: "Manganese", Quantity: wholeQuantity(80, unitKilogram), Status: "NEW"},
+				"material4": {MaterialType: "Nickel", Quantity: wholeQuantity(100, unitKilogram), Status: "NEW"},
+				"material5": {MaterialType: "Lithium", Quantity: wholeQuantity(10, unitKilogram), Status: "RECYCLED"},
+				"material6": {MaterialType: "Cobalt", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material7": {MaterialType: "Manganese", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material8": {MaterialType: "Nickel", Quantity: wholeQuantity(20, unitKilogram), Status: "RECYCLED"},
 			},
-			ManufactureDate:     time.Now(),
 			ManufacturerName:    "LG Energy Solution",
 			Location:            "Pyeongtaek, Korea",
 			Category:            "EV Battery",
 			Weight:              600.0,
-			Status:              "ORIGINAL",
-			Verified:            "VERIFIED",
+			Verified:            notVerifiedLabel,
 			Capacity:            77.4,
 			Voltage:             400.0,
 			SOC:                 100.0,
@@ -595,25 +785,21 @@ func (s *PublicContract) InitBatteries(ctx contractapi.TransactionContextInterfa
 			},
 		},
 		{
-			BatteryID:  fmt.Sprintf("BATTERY-%s", uuid.New().String()),
-			PassportID: uuid.New().String(),
 			RawMaterials: map[string]RawMaterialDetail{
-				"material1": {MaterialID: "MATERIAL-033", MaterialType: "Lithium", Quantity: 90, Status: "NEW"},
-				"material2": {MaterialID: "MATERIAL-034", MaterialType: "Cobalt", Quantity: 800, Status: "NEW"},
-				"material3": {MaterialID: "MATERIAL-035", MaterialType: "Manganese", Quantity: 80, Status: "NEW"},
-				"material4": {MaterialID: "MATERIAL-036", MaterialType: "Nickel", Quantity: 100, Status: "NEW"},
-				"material5": {MaterialID: "MATERIAL-037", MaterialType: "Lithium", Quantity: 10, Status: "RECYCLED"},
-				"material6": {MaterialID: "MATERIAL-038", MaterialType: "Cobalt", Quantity: 30, Status: "RECYCLED"},
-				"material7": {MaterialID: "MATERIAL-039", MaterialType: "Manganese", Quantity: 30, Status: "RECYCLED"},
-				"material8": {MaterialID: "MATERIAL-040", MaterialType: "Nickel", Quantity: 20, Status: "RECYCLED"},
+				"material1": {MaterialType: "Lithium", Quantity: wholeQuantity(90, unitKilogram), Status: "NEW"},
+				"material2": {MaterialType: "Cobalt", Quantity: wholeQuantity(800, unitKilogram), Status: "NEW"},
+				"material3": {MaterialType: "Manganese", Quantity: wholeQuantity(80, unitKilogram), Status: "NEW"},
+				"material4": {MaterialType: "Nickel", Quantity: wholeQuantity(100, unitKilogram), Status: "NEW"},
+				"material5": {MaterialType: "Lithium", Quantity: wholeQuantity(10, unitKilogram), Status: "RECYCLED"},
+				"material6": {MaterialType: "Cobalt", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material7": {MaterialType: "Manganese", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material8": {MaterialType: "Nickel", Quantity: wholeQuantity(20, unitKilogram), Status: "RECYCLED"},
 			},
-			ManufactureDate:     time.Now(),
 			ManufacturerName:    "LG Energy Solution",
 			Location:            "Pyeongtaek, Korea",
 			Category:            "EV Battery",
 			Weight:              600.0,
-			Status:              "ORIGINAL",
-			Verified:            "VERIFIED",
+			Verified:            notVerifiedLabel,
 			Capacity:            72.6,
 			Voltage:             800.0,
 			SOC:                 100.0,
@@ -633,25 +819,21 @@ func (s *PublicContract) InitBatteries(ctx contractapi.TransactionContextInterfa
 			},
 		},
 		{
-			BatteryID:  fmt.Sprintf("BATTERY-%s", uuid.New().String()),
-			PassportID: uuid.New().String(),
 			RawMaterials: map[string]RawMaterialDetail{
-				"material1": {MaterialID: "MATERIAL-041", MaterialType: "Lithium", Quantity: 90, Status: "NEW"},
-				"material2": {MaterialID: "MATERIAL-042", MaterialType: "Cobalt", Quantity: 800, Status: "NEW"},
-				"material3": {MaterialID: "MATERIAL-043", MaterialType: "Manganese", Quantity: 80, Status: "NEW"},
-				"material4": {MaterialID: "MATERIAL-044", MaterialType: "Nickel", Quantity: 100, Status: "NEW"},
-				"material5": {MaterialID: "MATERIAL-045", MaterialType: "Lithium", Quantity: 10, Status: "RECYCLED"},
-				"material6": {MaterialID: "MATERIAL-046", MaterialType: "Cobalt", Quantity: 30, Status: "RECYCLED"},
-				"material7": {MaterialID: "MATERIAL-047", MaterialType: "Manganese", Quantity: 30, Status: "RECYCLED"},
-				"material8": {MaterialID: "MATERIAL-048", MaterialType: "Nickel", Quantity: 20, Status: "RECYCLED"},
+				"material1": {MaterialType: "Lithium", Quantity: wholeQuantity(90, unitKilogram), Status: "NEW"},
+				"material2": {MaterialType: "Cobalt", Quantity: wholeQuantity(800, unitKilogram), Status: "NEW"},
+				"material3": {MaterialType: "Manganese", Quantity: wholeQuantity(80, unitKilogram), Status: "NEW"},
+				"material4": {MaterialType: "Nickel", Quantity: wholeQuantity(100, unitKilogram), Status: "NEW"},
+				"material5": {MaterialType: "Lithium", Quantity: wholeQuantity(10, unitKilogram), Status: "RECYCLED"},
+				"material6": {MaterialType: "Cobalt", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material7": {MaterialType: "Manganese", Quantity: wholeQuantity(30, unitKilogram), Status: "RECYCLED"},
+				"material8": {MaterialType: "Nickel", Quantity: wholeQuantity(20, unitKilogram), Status: "RECYCLED"},
 			},
-			ManufactureDate:     time.Now(),
 			ManufacturerName:    "LG Energy Solution",
 			Location:            "Pyeongtaek, Korea",
 			Category:            "EV Battery",
 			Weight:              600.0,
-			Status:              "ORIGINAL",
-			Verified:            "NOT VERIFIED",
+			Verified:            notVerifiedLabel,
 			Capacity:            75.5,
 			Voltage:             400.0,
 			SOC:                 100.0,
@@ -672,218 +854,161 @@ func (s *PublicContract) InitBatteries(ctx contractapi.TransactionContextInterfa
 		},
 	}
 
-	// 배터리 데이터를 원장에 저장
-	for _, battery := range initialBatteries {
-		batteryAsBytes, err := json.Marshal(battery)
-		if err != nil {
-			return fmt.Errorf("failed to marshal battery: %v", err)
-		}
-
-		err = ctx.GetStub().PutState(battery.BatteryID, batteryAsBytes)
-		if err != nil {
-			return fmt.Errorf("failed to put battery to ledger: %v", err)
-		}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
 	}
-
-	return nil
-}
-func (s *PublicContract) CreateBattery(ctx contractapi.TransactionContextInterface, rawMaterialsJSON string, weight, capacity, voltage float64, category string, totalLifeCycle int) (string, error) {
-
-	// 호출한 조직의 MSPID 확인
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	profile, err := readRecycledContentProfile(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get MSPID: %v", err)
+		return err
 	}
 
-	// org2만이 이 함수를 호출할 수 있도록 제한
-	if clientMSPID != "Org2MSP" {
-		return "", fmt.Errorf("permission denied: only Battery Manufacturer ORG can create batteries")
+	// 초기 배터리는 제조 조직이 소유
+	mapping, err := readRoleMapping(ctx)
+	if err != nil {
+		return err
 	}
-
-	var rawMaterials map[string]RawMaterialDetail
-
-	err = json.Unmarshal([]byte(rawMaterialsJSON), &rawMaterials)
+	owner, err := seedOwner(mapping, roleManufacturer)
 	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal raw materials: %v", err)
+		return err
 	}
 
-	// 재활용 비율 계산을 위한 총량 및 재활용량 추적
-	materialTotals := make(map[string]int)
-	recycledTotals := make(map[string]int)
+	batteryIDs := make([]string, 0, len(initialBatteries))
 
-	// 사용된 원자재의 수량만큼 원장에 저장된 원자재의 수량을 감소
-	for _, materialDetail := range rawMaterials {
-		// 원자재 ID로 원자재 조회
-		rawMaterial, err := s.QueryMaterial(ctx, materialDetail.MaterialID)
+	// 배터리 데이터를 원장에 저장
+	for i := range initialBatteries {
+		initialBatteries[i].BatteryID, err = newID(ctx, "BATTERY")
+		if err != nil {
+			return err
+		}
+		initialBatteries[i].PassportID, err = newID(ctx, "PASSPORT")
 		if err != nil {
-			return "", fmt.Errorf("failed to query raw material: %v", err)
+			return err
 		}
+		initialBatteries[i].ManufactureDate = now
+		initialBatteries[i].Status = statusManufactured
+		initialBatteries[i].Owner = owner
 
-		// 사용 가능한 수량 확인
-		if rawMaterial.Quantity < materialDetail.Quantity {
-			return "", fmt.Errorf("not enough quantity for material %s (needed: %d, available: %d)", materialDetail.MaterialID, materialDetail.Quantity, rawMaterial.Quantity)
+		err = recordTransition(ctx, &initialBatteries[i], actionManufacture, "")
+		if err != nil {
+			return err
 		}
 
-		// 원자재의 총량과 재활용량을 계산
-		materialTotals[materialDetail.MaterialType] += materialDetail.Quantity
+		err = recordInitialCustody(ctx, &initialBatteries[i])
+		if err != nil {
+			return err
+		}
 
-		if rawMaterial.Status == "RECYCLED" {
-			recycledTotals[materialDetail.MaterialType] += materialDetail.Quantity
+		err = assignInitialLots(initialBatteries[i].RawMaterials, lots, i)
+		if err != nil {
+			return err
 		}
 
-		// 사용된 수량 감소
-		rawMaterial.Quantity -= materialDetail.Quantity
+		err = recordConsumption(ctx, initialBatteries[i].BatteryID, initialBatteries[i].RawMaterials)
+		if err != nil {
+			return err
+		}
 
-		// 원자재 업데이트
-		rawMaterialAsBytes, err := json.Marshal(rawMaterial)
+		compliance := evaluateRecycledContent(&initialBatteries[i], profile)
+		err = saveRecycledContentCompliance(ctx, compliance)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal updated raw material: %v", err)
+			return err
 		}
+		initialBatteries[i].ComplianceStatus = compliance.Status
 
-		err = ctx.GetStub().PutState(rawMaterial.MaterialID, rawMaterialAsBytes)
+		err = s.saveBattery(ctx, &initialBatteries[i])
 		if err != nil {
-			return "", fmt.Errorf("failed to update raw material: %v", err)
+			return fmt.Errorf("failed to put battery to ledger: %v", err)
 		}
-	}
 
-	// 배터리 정보 생성
-	batteryID := fmt.Sprintf("BATTERY-%s", uuid.New().String())
-	passportID := fmt.Sprintf("PASSPORT-%s", uuid.New().String())
-	battery := Battery{
-		BatteryID:                batteryID,
-		PassportID:               passportID,
-		RawMaterials:             rawMaterials,
-		ManufacturerName:         "LG Energy Solution",
-		Location:                 "Pyeongtaek, Korea",
-		ContainsHazardous:        "Cadmium, Lithium, Nickel, Lead",
-		ManufactureDate:          time.Now(),
-		Weight:                   weight,
-		Category:                 category,
-		Voltage:                  voltage,
-		Status:                   "ORIGINAL",
-		Verified:                 "NOT VERIFIED",
-		Capacity:                 capacity,
-		TotalLifeCycle:           totalLifeCycle,
-		SOCE:                     100,
-		SOC:                      100,
-		SOH:                      100,
-		RemainingLifeCycle:       totalLifeCycle,
-		RecyclingRatesByMaterial: make(map[string]float64),
-	}
-
-	// 재활용 비율을 계산하여 저장
-	for materialType, total := range materialTotals {
-		recycled := recycledTotals[materialType]
-		var rate float64
-		if total > 0 {
-			rate = (float64(recycled) / float64(total)) * 100
-		} else {
-			rate = 0
+		err = emitEvent(ctx, EventBatteryCreated, eventAssetBattery, initialBatteries[i].BatteryID, BatteryEventPayload{Battery: &initialBatteries[i]})
+		if err != nil {
+			return err
 		}
-		// 소수점 두 자리까지 반올림
-		rate = math.Round(rate*100) / 100
-		battery.RecyclingRatesByMaterial[materialType] = rate
+		batteryIDs = append(batteryIDs, initialBatteries[i].BatteryID)
 	}
 
-	// 배터리 상태를 원장에 저장
-	batteryAsBytes, err := json.Marshal(battery)
+	return saveSeed(ctx, seedBatteries, batteryIDs)
+}
+func (s *PublicContract) CreateBattery(ctx contractapi.TransactionContextInterface, rawMaterialsJSON string, weight, capacity, voltage float64, category string, totalLifeCycle int, manufacturingEnergyJSON string) (string, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "CreateBattery")
+	if err != nil {
+		return "", err
+	}
+
+	var rawMaterials map[string]RawMaterialDetail
+
+	err = json.Unmarshal([]byte(rawMaterialsJSON), &rawMaterials)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal raw materials: %v", err)
+	}
+
+	// 제조 공정 에너지 사용량과 배출 계수 (빈 문자열이면 제조 배출량 미신고)
+	manufacturing, err := parseManufacturingEmissions(manufacturingEnergyJSON)
+	if err != nil {
+		return "", err
+	}
+
+	// 사용된 원자재의 수량만큼 원장에 저장된 원자재의 수량을 감소
+	consumption, err := s.consumeMaterials(ctx, caller, rawMaterials, 1)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal battery: %v", err)
+		return "", err
 	}
 
-	err = ctx.GetStub().PutState(batteryID, batteryAsBytes)
+	model := &BatteryModel{
+		Category:       category,
+		Weight:         weight,
+		Capacity:       capacity,
+		Voltage:        voltage,
+		TotalLifeCycle: totalLifeCycle,
+	}
+	battery, err := s.manufactureBattery(ctx, model, consumption, manufacturing, "", "")
 	if err != nil {
-		return "", fmt.Errorf("failed to store battery: %v", err)
+		return "", err
 	}
 
-	return batteryID, nil
+	return battery.BatteryID, nil
 }
 
 // QueryAllRawMaterials : 원장에 저장된 모든 원자재 조회
 func (s *PublicContract) QueryAllRawMaterials(ctx contractapi.TransactionContextInterface) ([]RawMaterial, error) {
-	// 원자재의 범위를 ""에서 ""까지로 설정하여 모든 원자재를 조회
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	// 상태 인덱스 전체(모든 상태 파티션)를 읽어 원자재만 조회
+	rawMaterials, err := s.queryMaterialsByIndex(ctx, indexMaterialByStatus)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all raw materials: %v", err)
 	}
-	defer resultsIterator.Close()
-
-	var rawMaterials []RawMaterial
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var rawMaterial RawMaterial
-		err = json.Unmarshal(queryResponse.Value, &rawMaterial)
-		if err != nil {
-			return nil, err
-		}
-
-		// 원자재 리스트에 추가
-		rawMaterials = append(rawMaterials, rawMaterial)
-	}
 
 	return rawMaterials, nil
 }
 
 func (s *PublicContract) QueryBatteryDetails(ctx contractapi.TransactionContextInterface, batteryID string) (*Battery, error) {
-	// 배터리 정보 조회
-	batteryAsBytes, err := ctx.GetStub().GetState(batteryID)
+	// 배터리 정보 조회 (nil 필드 초기화 포함)
+	battery, err := readBatteryState(ctx, batteryID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read battery from state: %v", err)
+		return nil, err
 	}
-	if batteryAsBytes == nil {
+	if battery == nil {
 		return nil, fmt.Errorf("battery not found: %s", batteryID)
 	}
 
-	var battery Battery
-	err = json.Unmarshal(batteryAsBytes, &battery)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal battery: %v", err)
-	}
-
-	// accidentLogs가 nil이면 빈 배열로 초기화
-	if battery.AccidentLogs == nil {
-		battery.AccidentLogs = []string{}
-	}
-
-	// maintenanceLogs가 nil이면 빈 배열로 초기화
-	if battery.MaintenanceLogs == nil {
-		battery.MaintenanceLogs = []string{}
-	}
-
-	return &battery, nil
+	return battery, nil
 }
 
 // getPerformance : 특정 배터리의 성능 정보를 반환하는 함수
 func (s *PublicContract) QueryPerformance(ctx contractapi.TransactionContextInterface, batteryID string) (map[string]interface{}, error) {
 
-	// 호출한 조직의 MSPID 확인
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "QueryPerformance")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get MSPID: %v", err)
-	}
-
-	if clientMSPID != "Org3MSP" && clientMSPID != "Org4MSP" && clientMSPID != "Org5MSP" {
-		return nil, fmt.Errorf("permission denied: only EV ORG or Maintenance ORG can query batteries with maintenance requests")
+		return nil, err
 	}
 
 	// 배터리 정보 조회
-	batteryAsBytes, err := ctx.GetStub().GetState(batteryID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read battery from state: %v", err)
-	}
-	if batteryAsBytes == nil {
-		return nil, fmt.Errorf("battery not found: %s", batteryID)
-	}
-
-	// 배터리 정보 언마샬링
-	var battery Battery
-	err = json.Unmarshal(batteryAsBytes, &battery)
+	battery, err := s.QueryBatteryDetails(ctx, batteryID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal battery: %v", err)
+		return nil, err
 	}
 
 	// 성능 관련 정보만 반환
@@ -917,100 +1042,163 @@ var extractionRates = map[string]float64{
 type ExtractMaterialsResponse struct {
 	Message            string                            `json:"message"`
 	ExtractedMaterials map[string]map[string]interface{} `json:"extractedMaterials"`
+	Extraction         *ExtractionRecord                 `json:"extraction"`
 }
 
 // ExtractMaterials : 배터리에서 원자재를 추출하고 배터리의 상태를 "Disassembled"로 설정하며, 추출된 원자재 정보를 반환합니다.
 func (s *PublicContract) ExtractMaterials(ctx contractapi.TransactionContextInterface, batteryID string, extractedQuantitiesJSON string) (*ExtractMaterialsResponse, error) {
 
-	// 호출한 조직의 MSPID 확인
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "ExtractMaterials")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get MSPID: %v", err)
-	}
-
-	// Org6MSP (Recycle ORG)만 이 함수를 호출할 수 있도록 제한
-	if clientMSPID != "Org6MSP" {
-		return nil, fmt.Errorf("permission denied: only Recycle ORG can extract materials")
+		return nil, err
 	}
 
 	// 배터리 정보 조회
-	battery, err := s.QueryBatteryDetails(ctx, batteryID)
+	battery, err := loadBattery(ctx, batteryID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query battery details: %v", err)
 	}
-	if battery.RecycleAvailability == false {
-		return nil, fmt.Errorf("This battery is not recyclable.")
+
+	// 같은 배터리를 두 번 해체할 수 없음
+	existingRecord, err := readExtractionRecord(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if existingRecord != nil {
+		return nil, fmt.Errorf("battery %s has already been extracted in transaction %s", batteryID, existingRecord.TxID)
+	}
+
+	// 재활용 가능(END_OF_LIFE) 상태의 배터리만 해체 상태로 전이
+	err = transitionBattery(ctx, battery, actionDisassemble)
+	if err != nil {
+		return nil, err
+	}
+
+	// extractedQuantitiesJSON을 원자재별 회수량/폐기량으로 변환
+	extractedQuantities, err := parseExtractionQuantities(extractedQuantitiesJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := readRecoveryYieldPolicy(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// extractedQuantitiesJSON을 맵으로 변환
-	extractedQuantities := make(map[string]int)
-	err = json.Unmarshal([]byte(extractedQuantitiesJSON), &extractedQuantities)
+	// 배터리 BOM 대비 물질 수지 검증 (원자재 종류 이름순)
+	balances, err := balanceMaterials(battery, extractedQuantities, policy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal extracted quantities: %v", err)
+		return nil, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &ExtractionRecord{
+		BatteryID:      batteryID,
+		RecyclerMSP:    caller.MSPID,
+		Materials:      balances,
+		TotalContained: gramsQuantity(0),
+		TotalRecovered: gramsQuantity(0),
+		TotalWaste:     gramsQuantity(0),
+		TotalLoss:      gramsQuantity(0),
+		TxID:           ctx.GetStub().GetTxID(),
+		ExtractedAt:    now.Format(time.RFC3339),
 	}
 
 	extractedMaterials := make(map[string]map[string]interface{})
-	// ExtractMaterials 함수 내에서
-	for _, detail := range battery.RawMaterials {
-		materialType := detail.MaterialType
-		// 추출할 양을 확인
-		extractedQuantity, exists := extractedQuantities[materialType]
-		if !exists || extractedQuantity <= 0 {
-			continue // 추출량이 없거나 0이면 건너뜀
+	recycledMaterials := []RawMaterial{}
+	for i := range record.Materials {
+		balance := &record.Materials[i]
+		record.TotalContained = sumQuantities(record.TotalContained, balance.Contained)
+		record.TotalRecovered = sumQuantities(record.TotalRecovered, balance.Recovered)
+		record.TotalWaste = sumQuantities(record.TotalWaste, balance.Waste)
+		record.TotalLoss = sumQuantities(record.TotalLoss, balance.Loss)
+
+		if balance.Recovered.Milli <= 0 {
+			continue // 회수량이 없으면 재활용 로트를 만들지 않음
 		}
 
-		// 새로운 ID 생성
-		newMaterialID := fmt.Sprintf("MATERIAL-%s", uuid.New().String())
+		// 재활용 로트는 재활용사가 신고한 단위로 기록
+		recovered := extractedQuantities[balance.MaterialType].Recovered
 
-		// 새로운 원자재를 생성하여 저장
-		newRawMaterial := RawMaterial{
-			MaterialID:   newMaterialID,
-			SupplierID:   "Recycle ORG", // 공급자를 Recycle ORG로 설정
-			Name:         materialType,
-			Quantity:     extractedQuantity,
-			Verified:     "NOT VERIFIED",
-			Status:       "RECYCLED",
-			Availability: "AVAILABLE",
-			Timestamp:    time.Now().Format(time.RFC3339),
+		// 새로운 ID 생성
+		newMaterialID, err := newID(ctx, "MATERIAL")
+		if err != nil {
+			return nil, err
+		}
+		balance.MaterialID = newMaterialID
+
+		// 원자재 종류별로 하나의 재활용 로트를 생성하여 저장
+		// (회수 공정 배출량을 회수량으로 나눈 값을 로트의 배출 계수로 사용)
+		newRawMaterial := RawMaterial{
+			MaterialID:      newMaterialID,
+			SupplierID:      "Recycle ORG", // 공급자를 Recycle ORG로 설정
+			Owner:           caller.MSPID,
+			Name:            balance.MaterialType,
+			Quantity:        recovered,
+			Verified:        "NOT VERIFIED",
+			Status:          "RECYCLED",
+			Availability:    "AVAILABLE",
+			Timestamp:       now.Format(time.RFC3339),
+			SourceBatteryID: batteryID,
+			YieldPercent:    balance.YieldPercent,
+			EmissionFactor:  math.Round(balance.ProcessEmissions/recovered.kilograms()*1e6) / 1e6,
+			EmissionSource:  balance.EmissionSource,
 		}
 
 		// 원장에 새로운 원자재 저장
-		newRawMaterialAsBytes, err := json.Marshal(newRawMaterial)
+		err = s.saveMaterial(ctx, &newRawMaterial)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal new raw material: %v", err)
+			return nil, fmt.Errorf("failed to store new raw material: %v", err)
 		}
 
-		err = ctx.GetStub().PutState(newMaterialID, newRawMaterialAsBytes)
+		recycledMaterials = append(recycledMaterials, newRawMaterial)
+
+		// 배터리 → 재활용 로트 계보 기록
+		err = recordRecovery(ctx, batteryID, newMaterialID, recovered)
 		if err != nil {
-			return nil, fmt.Errorf("failed to store new raw material: %v", err)
+			return nil, err
 		}
 
 		// 추출된 원자재 정보를 기록
-		extractedMaterials[materialType] = map[string]interface{}{
-			"materialID": newMaterialID,
-			"quantity":   extractedQuantity,
-			"status":     "RECYCLED",
+		extractedMaterials[balance.MaterialType] = map[string]interface{}{
+			"materialID":   newMaterialID,
+			"quantity":     recovered,
+			"status":       "RECYCLED",
+			"yieldPercent": balance.YieldPercent,
 		}
 	}
 
-	// 배터리 상태를 "Disassembled"로 설정
-	battery.Status = "DISASSEMBLED"
+	// 물질 수지 기록 저장
+	err = saveExtractionRecord(ctx, record)
+	if err != nil {
+		return nil, err
+	}
 
 	// 업데이트된 배터리 정보 저장
-	batteryAsBytes, err := json.Marshal(battery)
+	err = s.saveBattery(ctx, battery)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal battery: %v", err)
+		return nil, fmt.Errorf("failed to update battery: %v", err)
 	}
 
-	err = ctx.GetStub().PutState(battery.BatteryID, batteryAsBytes)
+	err = emitEvent(ctx, EventMaterialsExtracted, eventAssetBattery, batteryID, MaterialsExtractedPayload{
+		Extraction:        record,
+		Battery:           battery,
+		RecycledMaterials: recycledMaterials,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update battery: %v", err)
+		return nil, err
 	}
 
 	// 응답 생성
 	response := &ExtractMaterialsResponse{
 		Message:            "Materials extracted successfully",
 		ExtractedMaterials: extractedMaterials,
+		Extraction:         record,
 	}
 
 	return response, nil
@@ -1018,49 +1206,8 @@ func (s *PublicContract) ExtractMaterials(ctx contractapi.TransactionContextInte
 
 // RequestMaintenance : 특정 배터리의 유지보수 요청 생성
 func (s *PublicContract) RequestMaintenance(ctx contractapi.TransactionContextInterface, batteryID string) error {
-
-	// 호출한 조직의 MSPID 확인
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return fmt.Errorf("failed to get MSPID: %v", err)
-	}
-
-	// org3만이 이 함수를 호출할 수 있도록 제한
-	if clientMSPID != "Org3MSP" {
-		return fmt.Errorf("permission denied: only EV ORG can extract materials")
-	}
-
-	// 배터리 정보 조회
-	batteryAsBytes, err := ctx.GetStub().GetState(batteryID)
-	if err != nil {
-		return fmt.Errorf("failed to read battery: %v", err)
-	}
-	if batteryAsBytes == nil {
-		return fmt.Errorf("battery not found: %s", batteryID)
-	}
-
-	// 배터리 정보 언마샬링
-	var battery Battery
-	err = json.Unmarshal(batteryAsBytes, &battery)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal battery: %v", err)
-	}
-
-	// 유지보수 요청 플래그 설정
-	battery.MaintenanceRequest = true
-
-	// 변경된 배터리 정보 저장
-	updatedBatteryAsBytes, err := json.Marshal(battery)
-	if err != nil {
-		return fmt.Errorf("failed to marshal battery: %v", err)
-	}
-
-	err = ctx.GetStub().PutState(battery.BatteryID, updatedBatteryAsBytes)
-	if err != nil {
-		return fmt.Errorf("failed to update battery: %v", err)
-	}
-
-	return nil
+	// 운행 중(IN_SERVICE)인 배터리만 유지보수 상태로 전이 (EV ORG)
+	return s.changeBatteryStatus(ctx, batteryID, actionRequestMaintenance)
 }
 
 /*
@@ -1100,157 +1247,26 @@ func (s *SmartContract) AddMaintenanceLog(ctx contractapi.TransactionContextInte
 }
 */
 
-func (s *PublicContract) AddMaintenanceLog(ctx contractapi.TransactionContextInterface, maintenanceDataJSON string) error {
-
-	// 호출한 조직의 MSPID 확인
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return fmt.Errorf("failed to get MSPID: %v", err)
-	}
-
-	if clientMSPID != "Org4MSP" {
-		return fmt.Errorf("permission denied: only authorized organizations can add maintenance logs")
-	}
-
-	// incidentDataJSON을 구조체로 언마샬링
-	var maintenanceData struct {
-		BatteryID          string  `json:"batteryID"`
-		Info               string  `json:"info"`
-		MaintenanceDate    string  `json:"maintenanceDate"`
-		Company            string  `json:"company"`
-		SOC                float64 `json:"SOC"`
-		SOH                float64 `json:"SOH"`
-		RemainingLifeCycle int     `json:"remainingLifeCycle"`
-	}
-
-	err = json.Unmarshal([]byte(maintenanceDataJSON), &maintenanceData)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal incident data: %v", err)
-	}
-
-	// 배터리 ID로 배터리 정보 조회
-	battery, err := s.QueryBatteryDetails(ctx, maintenanceData.BatteryID)
-	if err != nil {
-		return err
-	}
-
-	// Check if maintenanceRequest is true
-	if !battery.MaintenanceRequest {
-		return fmt.Errorf("cannot add maintenance log: maintenance request is not active for battery %s", maintenanceData.BatteryID)
-	}
-
-	//로그 생성 및 추가
-	maintenanceLog := fmt.Sprintf("Maintenance on %s by %s: %s",
-		maintenanceData.MaintenanceDate, maintenanceData.Company, maintenanceData.Info)
-	battery.MaintenanceLogs = append(battery.MaintenanceLogs, maintenanceLog)
-
-	// 배터리의 SOC 및 SOH 업데이트
-	battery.SOC = maintenanceData.SOC
-	battery.SOH = maintenanceData.SOH
-
-	// 배터리 정보 저장
-	err = s.saveBattery(ctx, battery)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // RequestAnalysis : 특정 배터리에 대한 분석 요청 생성
 func (s *PublicContract) RequestAnalysis(ctx contractapi.TransactionContextInterface, batteryID string) error {
-
-	// 호출한 조직의 MSPID 확인
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return fmt.Errorf("failed to get MSPID: %v", err)
-	}
-
-	// org3만이 이 함수를 호출할 수 있도록 제한
-	if clientMSPID != "Org3MSP" {
-		return fmt.Errorf("permission denied: only EV ORG can extract materials")
-	}
-
-	// 배터리 정보 조회
-	batteryAsBytes, err := ctx.GetStub().GetState(batteryID)
-	if err != nil {
-		return fmt.Errorf("failed to read battery: %v", err)
-	}
-	if batteryAsBytes == nil {
-		return fmt.Errorf("battery not found: %s", batteryID)
-	}
-
-	// 배터리 정보 언마샬링
-	var battery Battery
-	err = json.Unmarshal(batteryAsBytes, &battery)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal battery: %v", err)
-	}
-
-	// 분석 요청 플래그 설정
-	battery.AnalysisRequest = true
-
-	// 변경된 배터리 정보 저장
-	updatedBatteryAsBytes, err := json.Marshal(battery)
-	if err != nil {
-		return fmt.Errorf("failed to marshal battery: %v", err)
-	}
-
-	err = ctx.GetStub().PutState(battery.BatteryID, updatedBatteryAsBytes)
-	if err != nil {
-		return fmt.Errorf("failed to update battery: %v", err)
-	}
-
-	return nil
+	// 운행 중, 유지보수 중 또는 2차 사용 중인 배터리만 분석 상태로 전이 (EV ORG)
+	return s.changeBatteryStatus(ctx, batteryID, actionRequestAnalysis)
 }
 
 // QueryBatteriesWithMaintenanceRequest : 유지보수 요청이 true인 배터리들만 조회
 func (s *PublicContract) QueryBatteriesWithMaintenanceRequest(ctx contractapi.TransactionContextInterface) ([]Battery, error) {
 
-	// 호출한 조직의 MSPID 확인
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "QueryBatteriesWithMaintenanceRequest")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+		return nil, err
 	}
 
-	// org3 또는 org4만이 이 함수를 호출할 수 있도록 제한
-	if clientMSPID != "Org3MSP" && clientMSPID != "Org4MSP" {
-		return nil, fmt.Errorf("permission denied: only EV ORG or Maintenance ORG can query batteries with maintenance requests")
-	}
-
-	// 원장에 저장된 모든 배터리를 조회
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	// 유지보수 요청(MaintenanceRequest)이 true인 인덱스 파티션만 조회
+	batteriesWithMaintenanceRequest, err := s.queryBatteriesByIndex(ctx, indexBatteryByMaintenanceRequest, "true")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query batteries: %v", err)
 	}
-	defer resultsIterator.Close()
-
-	var batteriesWithMaintenanceRequest []Battery
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var battery Battery
-		err = json.Unmarshal(queryResponse.Value, &battery)
-		if err != nil {
-			return nil, err
-		}
-
-		// 로그 필드들이 nil이면 빈 배열로 초기화
-		if battery.AccidentLogs == nil {
-			battery.AccidentLogs = []string{}
-		}
-		if battery.MaintenanceLogs == nil {
-			battery.MaintenanceLogs = []string{}
-		}
-
-		// 유지보수 요청(MaintenanceRequest)이 true인 배터리만 필터링하여 추가
-		if battery.MaintenanceRequest {
-			batteriesWithMaintenanceRequest = append(batteriesWithMaintenanceRequest, battery)
-		}
-	}
 
 	return batteriesWithMaintenanceRequest, nil
 }
@@ -1258,50 +1274,17 @@ func (s *PublicContract) QueryBatteriesWithMaintenanceRequest(ctx contractapi.Tr
 // QueryBatteriesWithAnalysisRequest : 분석 요청이 true인 배터리들만 조회
 func (s *PublicContract) QueryBatteriesWithAnalysisRequest(ctx contractapi.TransactionContextInterface) ([]Battery, error) {
 
-	// 호출한 조직의 MSPID 확인
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "QueryBatteriesWithAnalysisRequest")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get MSPID: %v", err)
-	}
-
-	// org3 또는 org5만이 이 함수를 호출할 수 있도록 제한
-	if clientMSPID != "Org3MSP" && clientMSPID != "Org5MSP" {
-		return nil, fmt.Errorf("permission denied: only EV ORG or Analysis ORG can query batteries with maintenance requests")
+		return nil, err
 	}
 
-	// 원장에 저장된 모든 배터리를 조회
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	// 분석 요청(AnalysisRequest)이 true인 인덱스 파티션만 조회
+	batteriesWithAnalysisRequest, err := s.queryBatteriesByIndex(ctx, indexBatteryByAnalysisRequest, "true")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query batteries: %v", err)
 	}
-	defer resultsIterator.Close()
-
-	var batteriesWithAnalysisRequest []Battery
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var battery Battery
-		err = json.Unmarshal(queryResponse.Value, &battery)
-		if err != nil {
-			return nil, err
-		}
-
-		// 로그 필드들이 nil이면 빈 배열로 초기화
-		if battery.AccidentLogs == nil {
-			battery.AccidentLogs = []string{}
-		}
-		if battery.MaintenanceLogs == nil {
-			battery.MaintenanceLogs = []string{}
-		}
-
-		// 분석 요청(AnalysisRequest)이 true인 배터리만 필터링하여 추가
-		if battery.AnalysisRequest {
-			batteriesWithAnalysisRequest = append(batteriesWithAnalysisRequest, battery)
-		}
-	}
 
 	return batteriesWithAnalysisRequest, nil
 }
@@ -1309,38 +1292,16 @@ func (s *PublicContract) QueryBatteriesWithAnalysisRequest(ctx contractapi.Trans
 // QueryBatterySOCEAndLifeCycle : 특정 배터리의 SOCE, Remaining Life Cycle, Capacity 등을 조회하는 함수
 func (s *PublicContract) QueryBatterySOCEAndLifeCycle(ctx contractapi.TransactionContextInterface, batteryID string) (map[string]interface{}, error) {
 
-	// 호출한 조직의 MSPID 확인
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "QueryBatterySOCEAndLifeCycle")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get MSPID: %v", err)
-	}
-
-	// org3 또는 org5만이 이 함수를 호출할 수 있도록 제한
-	if clientMSPID != "Org3MSP" && clientMSPID != "Org5MSP" {
-		return nil, fmt.Errorf("permission denied: only EV ORG or Analysis ORG can query batteries with maintenance requests")
+		return nil, err
 	}
 
 	// 배터리 정보 조회
-	batteryAsBytes, err := ctx.GetStub().GetState(batteryID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read battery: %v", err)
-	}
-	if batteryAsBytes == nil {
-		return nil, fmt.Errorf("battery not found: %s", batteryID)
-	}
-
-	var battery Battery
-	err = json.Unmarshal(batteryAsBytes, &battery)
+	battery, err := s.QueryBatteryDetails(ctx, batteryID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal battery: %v", err)
-	}
-
-	// 로그 필드들이 nil이면 빈 배열로 초기화
-	if battery.AccidentLogs == nil {
-		battery.AccidentLogs = []string{}
-	}
-	if battery.MaintenanceLogs == nil {
-		battery.MaintenanceLogs = []string{}
+		return nil, err
 	}
 
 	// 배터리의 SOCE, Remaining Life Cycle, Total Life Cycle, Capacity 반환
@@ -1350,6 +1311,7 @@ func (s *PublicContract) QueryBatterySOCEAndLifeCycle(ctx contractapi.Transactio
 		"soce":               battery.SOCE,
 		"remainingLifeCycle": battery.RemainingLifeCycle,
 		"totalLifeCycle":     battery.TotalLifeCycle,
+		"cycleCount":         battery.CycleCount,
 	}
 
 	return batteryDetails, nil
@@ -1358,197 +1320,86 @@ func (s *PublicContract) QueryBatterySOCEAndLifeCycle(ctx contractapi.Transactio
 // QueryBatteriesWithRecycleAvailability : 재활용 가능성이 true로 설정된 배터리들만 조회
 func (s *PublicContract) QueryBatteriesWithRecycleAvailability(ctx contractapi.TransactionContextInterface) ([]Battery, error) {
 
-	// 호출한 조직의 MSPID 확인
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "QueryBatteriesWithRecycleAvailability")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get MSPID: %v", err)
-	}
-
-	// org3 또는 org6만이 이 함수를 호출할 수 있도록 제한
-	if clientMSPID != "Org3MSP" && clientMSPID != "Org6MSP" {
-		return nil, fmt.Errorf("permission denied: only EV ORG or Recycle ORG can query batteries with maintenance requests")
+		return nil, err
 	}
 
-	// 원장에 저장된 모든 배터리를 조회
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	// RecycleAvailability가 true인 인덱스 파티션만 조회
+	batteriesWithRecycleAvailability, err := s.queryBatteriesByIndex(ctx, indexBatteryByRecycleAvailability, "true")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query batteries: %v", err)
 	}
-	defer resultsIterator.Close()
-
-	var batteriesWithRecycleAvailability []Battery
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var battery Battery
-		err = json.Unmarshal(queryResponse.Value, &battery)
-		if err != nil {
-			return nil, err
-		}
-
-		// 로그 필드들이 nil이면 빈 배열로 초기화
-		if battery.AccidentLogs == nil {
-			battery.AccidentLogs = []string{}
-		}
-		if battery.MaintenanceLogs == nil {
-			battery.MaintenanceLogs = []string{}
-		}
-
-		// RecycleAvailability가 true인 배터리만 필터링하여 추가
-		if battery.RecycleAvailability {
-			batteriesWithRecycleAvailability = append(batteriesWithRecycleAvailability, battery)
-		}
-	}
 
 	return batteriesWithRecycleAvailability, nil
 }
 
-func (s *PublicContract) AddAccidentLog(ctx contractapi.TransactionContextInterface, batteryID string, incidentDataJSON string) error {
-
-	battery, err := s.QueryBatteryDetails(ctx, batteryID)
-	if err != nil {
-		return err
-	}
-
-	var incidentData struct {
-		IncidentDate            string `json:"incidentDate"`
-		IncidentType            string `json:"incidentType"`
-		BatteryImpactAssessment string `json:"batteryImpactAssessment"`
-		ActionInformation       string `json:"actionInformation"`
-	}
-
-	err = json.Unmarshal([]byte(incidentDataJSON), &incidentData)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal incident data: %v", err)
-	}
-
-	accidentLog := fmt.Sprintf("Accident on %s: %s, Impact: %s, Action: %s",
-		incidentData.IncidentDate, incidentData.IncidentType, incidentData.BatteryImpactAssessment, incidentData.ActionInformation)
-	battery.AccidentLogs = append(battery.AccidentLogs, accidentLog)
-
-	battery.SOH -= 10
-	if battery.SOH < 0 {
-		battery.SOH = 0
-	}
-	battery.MaintenanceRequest = false
-
-	err = s.saveBattery(ctx, battery)
-	if err != nil {
-		return err
+// SetRecycleAvailability : 특정 배터리의 재활용 가능 여부를 설정하는 함수
+func (s *PublicContract) SetRecycleAvailability(ctx contractapi.TransactionContextInterface, batteryID string, recycleAvailability bool) error {
+	// 분석 중(UNDER_ANALYSIS)인 배터리만 판정 가능 (Analysis ORG)
+	// 재활용 가능하면 END_OF_LIFE, 아니면 운행 상태로 복귀
+	action := actionReturnToService
+	if recycleAvailability {
+		action = actionDeclareEndOfLife
 	}
 
-	return nil
+	return s.changeBatteryStatus(ctx, batteryID, action)
 }
 
-// SetRecycleAvailability : 특정 배터리의 재활용 가능 여부를 설정하는 함수
-func (s *PublicContract) SetRecycleAvailability(ctx contractapi.TransactionContextInterface, batteryID string, recycleAvailability bool) error {
+// 저장 함수 : 배터리를 docType과 함께 저장하고 보조 인덱스를 갱신
+func (s *PublicContract) saveBattery(ctx contractapi.TransactionContextInterface, battery *Battery) error {
+	battery.DocType = docTypeBattery
+	battery.Status = legacyLifecycleStatus(battery)
+	applyLifecycleFlags(battery)
+	assumeLegacyDetailUnits(battery.RawMaterials)
+	battery.ManufactureDate = storedManufactureDate(battery.ManufactureDate)
 
-	// 호출한 조직의 MSPID 확인
+	// 이력 조회 시 제출 조직을 알 수 있도록 마지막 변경 조직을 기록
 	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
 	if err != nil {
 		return fmt.Errorf("failed to get MSPID: %v", err)
 	}
+	battery.LastModifiedBy = clientMSPID
 
-	// org5만이 이 함수를 호출할 수 있도록 제한
-	if clientMSPID != "Org5MSP" {
-		return fmt.Errorf("permission denied: only Battery Analysis ORG can create batteries")
-	}
-
-	// 배터리 정보 조회
-	batteryAsBytes, err := ctx.GetStub().GetState(batteryID)
+	previous, err := readStoredBattery(ctx, battery.BatteryID)
 	if err != nil {
-		return fmt.Errorf("failed to read battery: %v", err)
-	}
-	if batteryAsBytes == nil {
-		return fmt.Errorf("battery not found: %s", batteryID)
-	}
-
-	// 배터리 정보 언마샬링
-	var battery Battery
-	err = json.Unmarshal(batteryAsBytes, &battery)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal battery: %v", err)
-	}
-
-	if battery.AnalysisRequest == false {
-		return fmt.Errorf("cannot analysis this battery: analysis request is not active for battery %s", battery.BatteryID)
-	}
-	// 재활용 가능 여부 설정
-	battery.RecycleAvailability = recycleAvailability
-
-	// 변경된 배터리 정보 저장
-	updatedBatteryAsBytes, err := json.Marshal(battery)
-	if err != nil {
-		return fmt.Errorf("failed to marshal battery: %v", err)
+		return err
 	}
 
-	err = ctx.GetStub().PutState(battery.BatteryID, updatedBatteryAsBytes)
+	batteryAsBytes, err := json.Marshal(battery)
 	if err != nil {
-		return fmt.Errorf("failed to update battery: %v", err)
+		return fmt.Errorf("failed to marshal battery update: %v", err)
 	}
 
-	return nil
-}
-
-// 저장 함수
-func (s *PublicContract) saveBattery(ctx contractapi.TransactionContextInterface, battery *Battery) error {
-	batteryAsBytes, err := json.Marshal(battery)
+	err = ctx.GetStub().PutState(battery.BatteryID, batteryAsBytes)
 	if err != nil {
-		return fmt.Errorf("failed to marshal battery update: %v", err)
+		return err
 	}
 
-	return ctx.GetStub().PutState(battery.BatteryID, batteryAsBytes)
+	return updateIndexes(ctx, batteryIndexEntries(previous), batteryIndexEntries(battery))
 }
 
 // QueryExtractedMaterial : 추출된 원자재를 materialID로 조회
 func (s *PublicContract) QueryExtractedMaterial(ctx contractapi.TransactionContextInterface, materialID string) (*RawMaterial, error) {
 	// materialID로 원자재 조회
-	materialAsBytes, err := ctx.GetStub().GetState(materialID)
+	material, err := readMaterialState(ctx, materialID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read material: %v", err)
+		return nil, err
 	}
-	if materialAsBytes == nil {
+	if material == nil {
 		return nil, fmt.Errorf("material not found: %s", materialID)
 	}
 
-	var material RawMaterial
-	err = json.Unmarshal(materialAsBytes, &material)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal material: %v", err)
-	}
-
-	return &material, nil
+	return material, nil
 }
 
 // QueryRecycledMaterials : 재활용된 원자재(Status가 "RECYCLED"인 원자재) 목록 조회
 func (s *PublicContract) QueryRecycledMaterials(ctx contractapi.TransactionContextInterface) ([]RawMaterial, error) {
-	// 원장에 저장된 모든 원자재 조회
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	// 상태 인덱스의 "RECYCLED" 파티션만 조회
+	recycledMaterials, err := s.queryMaterialsByIndex(ctx, indexMaterialByStatus, "RECYCLED")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all raw materials: %v", err)
-	}
-	defer resultsIterator.Close()
-
-	var recycledMaterials []RawMaterial
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var material RawMaterial
-		err = json.Unmarshal(queryResponse.Value, &material)
-		if err != nil {
-			return nil, err
-		}
-
-		// 원자재의 상태가 "RECYCLED"인 경우 필터링하여 목록에 추가
-		if material.Status == "RECYCLED" {
-			recycledMaterials = append(recycledMaterials, material)
-		}
+		return nil, fmt.Errorf("failed to get recycled raw materials: %v", err)
 	}
 
 	return recycledMaterials, nil
@@ -1556,71 +1407,43 @@ func (s *PublicContract) QueryRecycledMaterials(ctx contractapi.TransactionConte
 
 // QueryRecycledMaterials : 재활용된 원자재(Status가 "NEW"인 원자재) 목록 조회
 func (s *PublicContract) QueryNewMaterials(ctx contractapi.TransactionContextInterface) ([]RawMaterial, error) {
-	// 원장에 저장된 모든 원자재 조회
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	// 상태 인덱스의 "NEW" 파티션만 조회
+	newMaterials, err := s.queryMaterialsByIndex(ctx, indexMaterialByStatus, "NEW")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all raw materials: %v", err)
+		return nil, fmt.Errorf("failed to get new raw materials: %v", err)
 	}
-	defer resultsIterator.Close()
-
-	var recycledMaterials []RawMaterial
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var material RawMaterial
-		err = json.Unmarshal(queryResponse.Value, &material)
-		if err != nil {
-			return nil, err
-		}
 
-		if material.Status == "NEW" {
-			recycledMaterials = append(recycledMaterials, material)
-		}
-	}
-
-	return recycledMaterials, nil
+	return newMaterials, nil
 }
 
 // QueryAllMaterials : 신규 원자재와 재활용 원자재를 모두 조회하는 함수
 func (s *PublicContract) QueryAllMaterials(ctx contractapi.TransactionContextInterface) (map[string][]RawMaterial, error) {
-	// 원장에 저장된 모든 원자재 조회
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get all raw materials: %v", err)
-	}
-	defer resultsIterator.Close()
-
 	// 분류할 신규 및 재활용 원자재 리스트
 	allMaterials := map[string][]RawMaterial{
 		"newMaterials":      {},
 		"recycledMaterials": {},
 	}
 
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var material RawMaterial
-		err = json.Unmarshal(queryResponse.Value, &material)
+	// 상태 인덱스의 "NEW"와 "RECYCLED" 파티션만 각각 조회
+	partitions := []struct {
+		status string
+		group  string
+	}{
+		{"NEW", "newMaterials"},
+		{"RECYCLED", "recycledMaterials"},
+	}
+	for _, partition := range partitions {
+		materials, err := s.queryMaterialsByIndex(ctx, indexMaterialByStatus, partition.status)
 		if err != nil {
-			return nil, err
-		}
-
-		// 필터링: 원자재 ID가 없거나 수량이 0인 경우 제외
-		if material.MaterialID == "" || material.Quantity == 0 {
-			continue
+			return nil, fmt.Errorf("failed to get all raw materials: %v", err)
 		}
 
-		// 원자재 상태에 따라 분류
-		if material.Status == "RECYCLED" {
-			allMaterials["recycledMaterials"] = append(allMaterials["recycledMaterials"], material)
-		} else if material.Status == "NEW" {
-			allMaterials["newMaterials"] = append(allMaterials["newMaterials"], material)
+		for _, material := range materials {
+			// 필터링: 수량이 0인 경우 제외
+			if material.Quantity.Milli == 0 {
+				continue
+			}
+			allMaterials[partition.group] = append(allMaterials[partition.group], material)
 		}
 	}
 
@@ -1628,53 +1451,21 @@ func (s *PublicContract) QueryAllMaterials(ctx contractapi.TransactionContextInt
 }
 
 func (s *PublicContract) QueryAllBatteries(ctx contractapi.TransactionContextInterface) ([]Battery, error) {
-	// 모든 배터리를 조회하기 위해 상태 범위를 ""에서 ""까지로 설정
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	// 상태 인덱스 전체(모든 상태 파티션)를 읽어 배터리만 조회
+	batteries, err := s.queryBatteriesByIndex(ctx, indexBatteryByStatus)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all batteries: %v", err)
 	}
-	defer resultsIterator.Close()
-
-	var batteries []Battery
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var battery Battery
-		err = json.Unmarshal(queryResponse.Value, &battery)
-		if err != nil {
-			return nil, err
-		}
-
-		if battery.BatteryID == "" {
-			continue
-		}
-
-		// 필드 초기화: nil 필드들을 빈 배열 혹은 빈 객체로 설정
-		if battery.AccidentLogs == nil {
-			battery.AccidentLogs = []string{}
-		}
-		if battery.MaintenanceLogs == nil {
-			battery.MaintenanceLogs = []string{}
-		}
-		if battery.RawMaterials == nil {
-			battery.RawMaterials = make(map[string]RawMaterialDetail) // 빈 객체로 설정
-		}
-		if battery.RecyclingRatesByMaterial == nil {
-			battery.RecyclingRatesByMaterial = make(map[string]float64) // 빈 객체로 설정
-		}
-
-		// Battery 목록에 추가
-		batteries = append(batteries, battery)
-	}
 
 	return batteries, nil
 }
 
 func main() {
-	chaincode, err := contractapi.NewChaincode(new(PublicContract))
+	publicContract := new(PublicContract)
+	publicContract.TransactionContextHandler = new(TxContext)
+	publicContract.AfterTransaction = flushEvents
+
+	chaincode, err := contractapi.NewChaincode(publicContract)
 	if err != nil {
 		fmt.Printf("Error creating unified chaincode: %v\n", err)
 		return
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeMaintenance = "maintenance"
+
+	// 유지보수 기록 키 (maintenance~배터리ID~기록ID)
+	maintenanceRecordObjectType = "maintenance"
+	// 정비 업체별 보조 인덱스 (maintenance~company~배터리ID~기록ID)
+	indexMaintenanceByCompany = "maintenance~company~id"
+)
+
+// MaintenanceReading : 정비 전/후 배터리 측정값
+type MaintenanceReading struct {
+	SOC                float64 `json:"soc"`
+	SOH                float64 `json:"soh"`
+	RemainingLifeCycle int     `json:"remainingLifeCycle"`
+}
+
+// MaintenanceRecord : 배터리별 유지보수 기록
+type MaintenanceRecord struct {
+	DocType         string             `json:"docType"`
+	RecordID        string             `json:"recordID"`
+	BatteryID       string             `json:"batteryID"`
+	TechnicianOrg   string             `json:"technicianOrg"`
+	Company         string             `json:"company"`
+	MaintenanceDate string             `json:"maintenanceDate"` // RFC3339 (UTC)
+	WorkPerformed   string             `json:"workPerformed"`
+	PartsReplaced   []string           `json:"partsReplaced"`
+	Before          MaintenanceReading `json:"before"`
+	After           MaintenanceReading `json:"after"`
+	Migrated        bool               `json:"migrated"` // 기존 문자열 로그에서 변환된 기록 여부
+	RecordedAt      string             `json:"recordedAt"`
+}
+
+// parseRecordDate : "2006-01-02" 또는 RFC3339 형식의 날짜를 파싱
+func parseRecordDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD or RFC3339", value)
+	}
+	return t.UTC(), nil
+}
+
+// parseDateRange : 조회 기간을 [from, to) 형식의 RFC3339 문자열로 변환 (날짜만 주어진 to는 그 날 전체를 포함)
+func parseDateRange(from string, to string) (string, string, error) {
+	fromTime, err := parseRecordDate(from)
+	if err != nil {
+		return "", "", err
+	}
+	toTime, err := parseRecordDate(to)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := time.Parse("2006-01-02", to); err == nil {
+		toTime = toTime.AddDate(0, 0, 1)
+	} else {
+		toTime = toTime.Add(time.Second)
+	}
+	if !fromTime.Before(toTime) {
+		return "", "", fmt.Errorf("invalid date range: %s is after %s", from, to)
+	}
+	return fromTime.Format(time.RFC3339), toTime.Format(time.RFC3339), nil
+}
+
+func readingOf(battery *Battery) MaintenanceReading {
+	return MaintenanceReading{
+		SOC:                battery.SOC,
+		SOH:                battery.SOH,
+		RemainingLifeCycle: battery.RemainingLifeCycle,
+	}
+}
+
+// saveMaintenanceRecord : 유지보수 기록을 배터리별 복합 키와 업체 인덱스에 저장
+func (s *PublicContract) saveMaintenanceRecord(ctx contractapi.TransactionContextInterface, record *MaintenanceRecord) error {
+	record.DocType = docTypeMaintenance
+	if record.PartsReplaced == nil {
+		record.PartsReplaced = []string{}
+	}
+
+	recordKey, err := ctx.GetStub().CreateCompositeKey(maintenanceRecordObjectType, []string{record.BatteryID, record.RecordID})
+	if err != nil {
+		return fmt.Errorf("failed to create maintenance record key: %v", err)
+	}
+
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal maintenance record: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(recordKey, recordAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store maintenance record: %v", err)
+	}
+
+	// 기록은 수정되지 않으므로 인덱스 항목만 추가
+	return updateIndexes(ctx, nil, []indexEntry{
+		{indexMaintenanceByCompany, []string{record.Company, record.BatteryID, record.RecordID}},
+	})
+}
+
+// sortMaintenanceRecords : 정비 일자, 기록 시각 순으로 정렬
+func sortMaintenanceRecords(records []MaintenanceRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].MaintenanceDate != records[j].MaintenanceDate {
+			return records[i].MaintenanceDate < records[j].MaintenanceDate
+		}
+		return records[i].RecordedAt < records[j].RecordedAt
+	})
+}
+
+// readMaintenanceRecords : 이터레이터의 유지보수 기록 문서를 모두 읽음
+func readMaintenanceRecords(resultsIterator shim.StateQueryIteratorInterface) ([]MaintenanceRecord, error) {
+	defer resultsIterator.Close()
+
+	records := []MaintenanceRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record MaintenanceRecord
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal maintenance record: %v", err)
+		}
+		if record.PartsReplaced == nil {
+			record.PartsReplaced = []string{}
+		}
+
+		records = append(records, record)
+	}
+
+	sortMaintenanceRecords(records)
+	return records, nil
+}
+
+// AddMaintenanceLog : 정비 결과를 배터리별 유지보수 기록으로 저장하고 입력된 SOC/SOH/잔여 수명만 배터리에 갱신
+func (s *PublicContract) AddMaintenanceLog(ctx contractapi.TransactionContextInterface, maintenanceDataJSON string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "AddMaintenanceLog")
+	if err != nil {
+		return err
+	}
+
+	// maintenanceDataJSON을 구조체로 언마샬링 (info는 workPerformed의 이전 이름)
+	var maintenanceData struct {
+		BatteryID          string   `json:"batteryID"`
+		Info               string   `json:"info"`
+		WorkPerformed      string   `json:"workPerformed"`
+		PartsReplaced      []string `json:"partsReplaced"`
+		MaintenanceDate    string   `json:"maintenanceDate"`
+		Company            string   `json:"company"`
+		SOC                *float64 `json:"SOC"`
+		SOH                *float64 `json:"SOH"`
+		RemainingLifeCycle *int     `json:"remainingLifeCycle"`
+	}
+
+	err = json.Unmarshal([]byte(maintenanceDataJSON), &maintenanceData)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal maintenance data: %v", err)
+	}
+
+	// 배터리 ID로 배터리 정보 조회
+	battery, err := loadBattery(ctx, maintenanceData.BatteryID)
+	if err != nil {
+		return err
+	}
+
+	// 유지보수 중(UNDER_MAINTENANCE)인 배터리에만 기록 가능
+	err = requireBatteryStatus(battery, "ADD_MAINTENANCE_LOG", statusUnderMaintenance)
+	if err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 정비 일자가 없으면 트랜잭션 시각을 사용
+	maintenanceDate := now
+	if maintenanceData.MaintenanceDate != "" {
+		maintenanceDate, err = parseRecordDate(maintenanceData.MaintenanceDate)
+		if err != nil {
+			return err
+		}
+	}
+
+	workPerformed := maintenanceData.WorkPerformed
+	if workPerformed == "" {
+		workPerformed = maintenanceData.Info
+	}
+
+	before := readingOf(battery)
+
+	// 배터리의 SOC, SOH 및 잔여 수명 업데이트 (입력된 값만 반영)
+	if maintenanceData.SOC != nil {
+		if *maintenanceData.SOC < 0 || *maintenanceData.SOC > 100 {
+			return fmt.Errorf("SOC must be between 0 and 100: %v", *maintenanceData.SOC)
+		}
+		battery.SOC = *maintenanceData.SOC
+	}
+	if maintenanceData.SOH != nil {
+		if *maintenanceData.SOH < 0 || *maintenanceData.SOH > 100 {
+			return fmt.Errorf("SOH must be between 0 and 100: %v", *maintenanceData.SOH)
+		}
+		battery.SOH = *maintenanceData.SOH
+	}
+	if maintenanceData.RemainingLifeCycle != nil {
+		if *maintenanceData.RemainingLifeCycle < 0 || *maintenanceData.RemainingLifeCycle > battery.TotalLifeCycle {
+			return fmt.Errorf("remaining life cycle must be between 0 and %d: %d", battery.TotalLifeCycle, *maintenanceData.RemainingLifeCycle)
+		}
+		battery.RemainingLifeCycle = *maintenanceData.RemainingLifeCycle
+	}
+
+	recordID, err := newID(ctx, "MAINTENANCE")
+	if err != nil {
+		return err
+	}
+
+	record := MaintenanceRecord{
+		RecordID:        recordID,
+		BatteryID:       battery.BatteryID,
+		TechnicianOrg:   caller.MSPID,
+		Company:         maintenanceData.Company,
+		MaintenanceDate: maintenanceDate.Format(time.RFC3339),
+		WorkPerformed:   workPerformed,
+		PartsReplaced:   maintenanceData.PartsReplaced,
+		Before:          before,
+		After:           readingOf(battery),
+		RecordedAt:      now.Format(time.RFC3339),
+	}
+
+	err = s.saveMaintenanceRecord(ctx, &record)
+	if err != nil {
+		return err
+	}
+
+	// 배터리 정보 저장
+	err = s.saveBattery(ctx, battery)
+	if err != nil {
+		return err
+	}
+
+	return emitEvent(ctx, EventMaintenanceLogged, eventAssetBattery, battery.BatteryID, MaintenanceLoggedPayload{Record: &record, Battery: battery})
+}
+
+// QueryMaintenanceRecords : 특정 배터리의 유지보수 기록 조회
+func (s *PublicContract) QueryMaintenanceRecords(ctx contractapi.TransactionContextInterface, batteryID string) ([]MaintenanceRecord, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(maintenanceRecordObjectType, []string{batteryID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query maintenance records: %v", err)
+	}
+
+	return readMaintenanceRecords(resultsIterator)
+}
+
+// QueryMaintenanceRecordsByCompany : 특정 정비 업체가 작성한 유지보수 기록 조회
+func (s *PublicContract) QueryMaintenanceRecordsByCompany(ctx contractapi.TransactionContextInterface, company string) ([]MaintenanceRecord, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(indexMaintenanceByCompany, []string{company})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index %s: %v", indexMaintenanceByCompany, err)
+	}
+	defer resultsIterator.Close()
+
+	records := []MaintenanceRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split index key: %v", err)
+		}
+
+		recordKey, err := ctx.GetStub().CreateCompositeKey(maintenanceRecordObjectType, keyParts[1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to create maintenance record key: %v", err)
+		}
+
+		recordAsBytes, err := ctx.GetStub().GetState(recordKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read maintenance record: %v", err)
+		}
+		if recordAsBytes == nil {
+			continue
+		}
+
+		var record MaintenanceRecord
+		err = json.Unmarshal(recordAsBytes, &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal maintenance record: %v", err)
+		}
+		if record.PartsReplaced == nil {
+			record.PartsReplaced = []string{}
+		}
+
+		records = append(records, record)
+	}
+
+	sortMaintenanceRecords(records)
+	return records, nil
+}
+
+// QueryMaintenanceRecordsByDateRange : 정비 일자가 기간(from ~ to, YYYY-MM-DD 또는 RFC3339)에 속하는 유지보수 기록 조회
+func (s *PublicContract) QueryMaintenanceRecordsByDateRange(ctx contractapi.TransactionContextInterface, from string, to string) ([]MaintenanceRecord, error) {
+	fromDate, toDate, err := parseDateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	query := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"docType":         docTypeMaintenance,
+			"maintenanceDate": map[string]interface{}{"$gte": fromDate, "$lt": toDate},
+		},
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query maintenance records: %v", err)
+	}
+
+	return readMaintenanceRecords(resultsIterator)
+}
+
+// parseLegacyMaintenanceLog : "Maintenance on <날짜> by <업체>: <내용>" 형식의 기존 로그를 분해
+func parseLegacyMaintenanceLog(log string) (date string, company string, info string, ok bool) {
+	rest := strings.TrimPrefix(log, "Maintenance on ")
+	if rest == log {
+		return "", "", "", false
+	}
+	byIndex := strings.Index(rest, " by ")
+	if byIndex < 0 {
+		return "", "", "", false
+	}
+	date = rest[:byIndex]
+	rest = rest[byIndex+len(" by "):]
+	colonIndex := strings.Index(rest, ": ")
+	if colonIndex < 0 {
+		return "", "", "", false
+	}
+	return date, rest[:colonIndex], rest[colonIndex+len(": "):], true
+}
+
+// MigrateMaintenanceLogs : 배터리 문서의 문자열 유지보수 로그를 MaintenanceRecord로 변환하고 배터리 문서에서 제거
+// (docType이 없는 기존 배터리는 MigrateIndexes를 먼저 실행해야 대상에 포함됨)
+func (s *PublicContract) MigrateMaintenanceLogs(ctx contractapi.TransactionContextInterface) (int, error) {
+	if _, err := authorize(ctx, "MigrateMaintenanceLogs"); err != nil {
+		return 0, err
+	}
+
+	batteries, err := s.loadBatteriesByIndex(ctx, indexBatteryByStatus)
+	if err != nil {
+		return 0, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for i := range batteries {
+		battery := &batteries[i]
+		if len(battery.MaintenanceLogs) == 0 {
+			continue
+		}
+
+		for _, log := range battery.MaintenanceLogs {
+			recordID, err := newID(ctx, "MAINTENANCE")
+			if err != nil {
+				return migrated, err
+			}
+
+			// 형식을 알 수 없는 로그는 원문 전체를 작업 내용으로 보존
+			record := MaintenanceRecord{
+				RecordID:      recordID,
+				BatteryID:     battery.BatteryID,
+				WorkPerformed: log,
+				Migrated:      true,
+				RecordedAt:    now.Format(time.RFC3339),
+			}
+			if date, company, info, ok := parseLegacyMaintenanceLog(log); ok {
+				record.Company = company
+				record.WorkPerformed = info
+				if maintenanceDate, err := parseRecordDate(date); err == nil {
+					record.MaintenanceDate = maintenanceDate.Format(time.RFC3339)
+				}
+			}
+
+			err = s.saveMaintenanceRecord(ctx, &record)
+			if err != nil {
+				return migrated, err
+			}
+			migrated++
+		}
+
+		battery.MaintenanceLogs = []string{}
+		err = s.saveBattery(ctx, battery)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to update battery: %v", err)
+		}
+	}
+
+	err = emitEvent(ctx, EventMigrationCompleted, eventAssetConfig, "maintenanceLogs", MigrationCompletedPayload{Migration: "MigrateMaintenanceLogs", Migrated: migrated})
+	if err != nil {
+		return migrated, err
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,163 @@
+// Package merkle : BMS 텔레메트리 배치의 Merkle 트리와 포함 증명
+//
+// 클라이언트는 배치의 레코드(오프체인에 보관하는 바이트 그대로)로 트리를 만들어 루트를
+// AnchorTelemetryBatch로 원장에 고정하고, 나중에 레코드 한 건과 Proof를
+// VerifyTelemetryRecord에 넘겨 검증한다. 체인코드도 같은 패키지로 루트를 다시 계산한다.
+//
+// 해시 규칙 (SHA-256)
+//   - 잎: H(0x00 || 레코드)
+//   - 내부 노드: H(0x01 || 왼쪽 || 오른쪽)
+//   - 단계의 노드 수가 홀수이면 짝이 없는 마지막 노드를 복제하지 않고 그대로 위 단계로 올린다.
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// 잎과 내부 노드의 해시 접두사 (잎을 내부 노드로 위장하는 2차 원상 공격 방지)
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// LeafHash : 레코드 한 건의 잎 해시
+func LeafHash(record []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(record)
+	return h.Sum(nil)
+}
+
+// NodeHash : 두 자식 노드로 부모 노드 해시를 계산
+func NodeHash(left []byte, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// Tree : 레코드 순서대로 만든 Merkle 트리 (levels[0]이 잎, 마지막 단계가 루트)
+type Tree struct {
+	levels [][][]byte
+}
+
+// New : 레코드 목록으로 트리를 생성 (레코드 순서가 잎의 순서)
+func New(records [][]byte) (*Tree, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("merkle tree needs at least one record")
+	}
+
+	level := make([][]byte, len(records))
+	for i, record := range records {
+		level[i] = LeafHash(record)
+	}
+
+	tree := &Tree{levels: [][][]byte{level}}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, NodeHash(level[i], level[i+1]))
+		}
+		tree.levels = append(tree.levels, next)
+		level = next
+	}
+
+	return tree, nil
+}
+
+// Size : 잎(레코드) 수
+func (t *Tree) Size() int {
+	return len(t.levels[0])
+}
+
+// Root : 루트 해시
+func (t *Tree) Root() []byte {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// RootHex : 원장에 고정할 16진수 루트 해시
+func (t *Tree) RootHex() string {
+	return hex.EncodeToString(t.Root())
+}
+
+// Proof : index번째 레코드의 포함 증명
+func (t *Tree) Proof(index int) (*Proof, error) {
+	if index < 0 || index >= t.Size() {
+		return nil, fmt.Errorf("record index out of range: %d (tree size %d)", index, t.Size())
+	}
+
+	proof := &Proof{Index: index, TreeSize: t.Size(), Siblings: []string{}}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof.Siblings = append(proof.Siblings, hex.EncodeToString(level[sibling]))
+		}
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// Proof : 포함 증명 (잎 위치, 트리 크기, 잎에서 루트 방향의 형제 노드 해시)
+// 형제의 좌우는 Index와 TreeSize로 정해지므로 증명에 따로 담지 않는다.
+type Proof struct {
+	Index    int      `json:"index"`
+	TreeSize int      `json:"treeSize"`
+	Siblings []string `json:"siblings"`
+}
+
+// ComputeRoot : 레코드와 증명으로 루트 해시를 다시 계산
+// 형제 노드가 모자라거나 남으면 오류를 반환한다.
+func (p *Proof) ComputeRoot(record []byte) ([]byte, error) {
+	if p.TreeSize <= 0 {
+		return nil, fmt.Errorf("proof tree size must be greater than 0: %d", p.TreeSize)
+	}
+	if p.Index < 0 || p.Index >= p.TreeSize {
+		return nil, fmt.Errorf("proof index out of range: %d (tree size %d)", p.Index, p.TreeSize)
+	}
+
+	hash := LeafHash(record)
+	index, size, used := p.Index, p.TreeSize, 0
+	for size > 1 {
+		if sibling := index ^ 1; sibling < size {
+			if used == len(p.Siblings) {
+				return nil, fmt.Errorf("proof has too few siblings for tree size %d", p.TreeSize)
+			}
+			siblingHash, err := hex.DecodeString(p.Siblings[used])
+			if err != nil || len(siblingHash) != sha256.Size {
+				return nil, fmt.Errorf("invalid proof sibling %q: expected hex-encoded SHA-256", p.Siblings[used])
+			}
+			used++
+
+			if index%2 == 0 {
+				hash = NodeHash(hash, siblingHash)
+			} else {
+				hash = NodeHash(siblingHash, hash)
+			}
+		}
+		index /= 2
+		size = (size + 1) / 2
+	}
+	if used != len(p.Siblings) {
+		return nil, fmt.Errorf("proof has %d unused siblings", len(p.Siblings)-used)
+	}
+
+	return hash, nil
+}
+
+// Verify : 레코드가 루트 해시의 트리에 포함되는지 확인
+func Verify(root []byte, record []byte, proof *Proof) (bool, error) {
+	computed, err := proof.ComputeRoot(record)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(computed, root), nil
+}
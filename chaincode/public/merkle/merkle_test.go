@@ -0,0 +1,176 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func testRecords(n int) [][]byte {
+	records := make([][]byte, n)
+	for i := range records {
+		records[i] = []byte(fmt.Sprintf(`{"seq":%d}`, i))
+	}
+	return records
+}
+
+func TestNewRejectsEmpty(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Fatal("expected error for empty record list")
+	}
+}
+
+// 짝이 없는 마지막 노드는 복제하지 않고 그대로 위 단계로 올라가야 함
+func TestRootOddPromotion(t *testing.T) {
+	l := make([][]byte, 5)
+	for i, record := range testRecords(5) {
+		l[i] = LeafHash(record)
+	}
+
+	tests := []struct {
+		size int
+		want []byte
+	}{
+		{1, l[0]},
+		{2, NodeHash(l[0], l[1])},
+		{3, NodeHash(NodeHash(l[0], l[1]), l[2])},
+		{4, NodeHash(NodeHash(l[0], l[1]), NodeHash(l[2], l[3]))},
+		{5, NodeHash(NodeHash(NodeHash(l[0], l[1]), NodeHash(l[2], l[3])), l[4])},
+	}
+	for _, tt := range tests {
+		tree, err := New(testRecords(tt.size))
+		if err != nil {
+			t.Fatalf("size %d: %v", tt.size, err)
+		}
+		if !bytes.Equal(tree.Root(), tt.want) {
+			t.Errorf("size %d: root %x, want %x", tt.size, tree.Root(), tt.want)
+		}
+	}
+}
+
+func TestProofSizes(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		records := testRecords(size)
+		tree, err := New(records)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+
+		for index := 0; index < size; index++ {
+			proof, err := tree.Proof(index)
+			if err != nil {
+				t.Fatalf("size %d index %d: %v", size, index, err)
+			}
+
+			ok, err := Verify(tree.Root(), records[index], proof)
+			if err != nil || !ok {
+				t.Errorf("size %d index %d: verify = %v, %v", size, index, ok, err)
+			}
+
+			// 다른 위치의 레코드는 같은 증명으로 검증되지 않아야 함
+			if size > 1 {
+				other := records[(index+1)%size]
+				if ok, _ := Verify(tree.Root(), other, proof); ok {
+					t.Errorf("size %d index %d: proof verified a different record", size, index)
+				}
+			}
+		}
+	}
+}
+
+func TestProofSiblingCount(t *testing.T) {
+	tests := []struct {
+		size     int
+		index    int
+		siblings int
+	}{
+		{1, 0, 0},
+		{2, 1, 1},
+		{3, 2, 1}, // 마지막 잎은 첫 단계에서 짝 없이 올라감
+		{4, 3, 2},
+		{5, 4, 1},
+		{6, 4, 2},
+		{7, 6, 2},
+		{8, 0, 3},
+	}
+	for _, tt := range tests {
+		tree, _ := New(testRecords(tt.size))
+		proof, err := tree.Proof(tt.index)
+		if err != nil {
+			t.Fatalf("size %d index %d: %v", tt.size, tt.index, err)
+		}
+		if len(proof.Siblings) != tt.siblings {
+			t.Errorf("size %d index %d: %d siblings, want %d", tt.size, tt.index, len(proof.Siblings), tt.siblings)
+		}
+	}
+}
+
+func TestTamperedProof(t *testing.T) {
+	records := testRecords(7)
+	tree, _ := New(records)
+	root := tree.Root()
+
+	tamper := func(hexHash string) string {
+		hash, _ := hex.DecodeString(hexHash)
+		hash[0] ^= 0xff
+		return hex.EncodeToString(hash)
+	}
+
+	tests := []struct {
+		name    string
+		index   int
+		record  []byte
+		modify  func(p *Proof)
+		wantErr bool
+	}{
+		{name: "tampered first sibling", index: 2, modify: func(p *Proof) { p.Siblings[0] = tamper(p.Siblings[0]) }},
+		{name: "tampered last sibling", index: 2, modify: func(p *Proof) { p.Siblings[len(p.Siblings)-1] = tamper(p.Siblings[len(p.Siblings)-1]) }},
+		{name: "tampered sibling of promoted leaf", index: 6, modify: func(p *Proof) { p.Siblings[0] = tamper(p.Siblings[0]) }},
+		{name: "swapped siblings", index: 1, modify: func(p *Proof) { p.Siblings[0], p.Siblings[1] = p.Siblings[1], p.Siblings[0] }},
+		{name: "wrong index", index: 1, modify: func(p *Proof) { p.Index = 0 }},
+		{name: "tampered record", index: 3, record: []byte(`{"seq":99}`), modify: func(p *Proof) {}},
+		{name: "missing sibling", index: 3, modify: func(p *Proof) { p.Siblings = p.Siblings[:len(p.Siblings)-1] }, wantErr: true},
+		{name: "extra sibling", index: 3, modify: func(p *Proof) { p.Siblings = append(p.Siblings, p.Siblings[0]) }, wantErr: true},
+		{name: "non-hex sibling", index: 3, modify: func(p *Proof) { p.Siblings[0] = "zz" }, wantErr: true},
+		{name: "short sibling", index: 3, modify: func(p *Proof) { p.Siblings[0] = p.Siblings[0][:32] }, wantErr: true},
+		{name: "index out of range", index: 3, modify: func(p *Proof) { p.Index = 7 }, wantErr: true},
+		{name: "zero tree size", index: 3, modify: func(p *Proof) { p.TreeSize = 0 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof, err := tree.Proof(tt.index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(proof)
+
+			record := tt.record
+			if record == nil {
+				record = records[tt.index]
+			}
+			ok, err := Verify(root, record, proof)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got ok = %v", ok)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				t.Fatal("tampered proof verified")
+			}
+		})
+	}
+}
+
+func TestProofIndexOutOfRange(t *testing.T) {
+	tree, _ := New(testRecords(3))
+	for _, index := range []int{-1, 3} {
+		if _, err := tree.Proof(index); err == nil {
+			t.Errorf("index %d: expected error", index)
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 한 페이지에서 조회할 수 있는 최대 레코드 수
+const maxPageSize = 200
+
+// PaginatedMaterials : 원자재 페이지 조회 결과
+type PaginatedMaterials struct {
+	Records             []RawMaterial `json:"records"`
+	FetchedRecordsCount int32         `json:"fetchedRecordsCount"`
+	Bookmark            string        `json:"bookmark"`
+}
+
+// PaginatedBatteries : 배터리 페이지 조회 결과
+type PaginatedBatteries struct {
+	Records             []Battery `json:"records"`
+	FetchedRecordsCount int32     `json:"fetchedRecordsCount"`
+	Bookmark            string    `json:"bookmark"`
+}
+
+// MaterialFilter : 원자재 페이지 조회 필터 (빈 값은 조건에서 제외)
+type MaterialFilter struct {
+	Status       string `json:"status"`
+	Name         string `json:"name"`
+	SupplierID   string `json:"supplierID"`
+	Owner        string `json:"owner"`
+	Verified     string `json:"verified"`
+	Availability string `json:"availability"`
+}
+
+// BatteryFilter : 배터리 페이지 조회 필터 (빈 값은 조건에서 제외)
+type BatteryFilter struct {
+	Status           string   `json:"status"`
+	Category         string   `json:"category"`
+	Verified         string   `json:"verified"`
+	Owner            string   `json:"owner"`
+	SOHMin           *float64 `json:"sohMin"`
+	SOHMax           *float64 `json:"sohMax"`
+	ManufacturedFrom string   `json:"manufacturedFrom"` // RFC3339
+	ManufacturedTo   string   `json:"manufacturedTo"`   // RFC3339
+}
+
+// parseFilter : filterJSON이 비어 있으면 빈 필터로 취급
+func parseFilter(filterJSON string, filter interface{}) error {
+	if filterJSON == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(filterJSON), filter); err != nil {
+		return fmt.Errorf("failed to unmarshal filter: %v", err)
+	}
+	return nil
+}
+
+func validatePageSize(pageSize int32) error {
+	if pageSize <= 0 || pageSize > maxPageSize {
+		return fmt.Errorf("page size must be between 1 and %d: %d", maxPageSize, pageSize)
+	}
+	return nil
+}
+
+// verifiedSelector : 검증 표시 필터를 유효기간까지 반영한 조건으로 변환
+// 조회 결과에 적용하는 effectiveVerified와 같은 기준 (유효기간이 지났거나 없는 검증은 미검증)
+func verifiedSelector(ctx contractapi.TransactionContextInterface, verifiedField string, verified string) (map[string]interface{}, error) {
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// verifiedUntil은 UTC RFC3339 (초 단위) 문자열로 저장되므로 같은 형식으로 사전순 비교
+	nowText := now.Format(time.RFC3339)
+
+	if verified == notVerifiedLabel {
+		return map[string]interface{}{"$or": []interface{}{
+			map[string]interface{}{verifiedField: notVerifiedLabel},
+			map[string]interface{}{"verifiedUntil": map[string]interface{}{"$lte": nowText}},
+			map[string]interface{}{"verifiedUntil": map[string]interface{}{"$exists": false}},
+		}}, nil
+	}
+	return map[string]interface{}{
+		verifiedField:   verified,
+		"verifiedUntil": map[string]interface{}{"$gt": nowText},
+	}, nil
+}
+
+// materialSelector : 필터를 CouchDB selector로 변환
+func materialSelector(ctx contractapi.TransactionContextInterface, filter MaterialFilter) (map[string]interface{}, error) {
+	selector := map[string]interface{}{"docType": docTypeMaterial}
+	if filter.Status != "" {
+		selector["status"] = filter.Status
+	}
+	if filter.Name != "" {
+		selector["name"] = filter.Name
+	}
+	if filter.SupplierID != "" {
+		selector["supplierID"] = filter.SupplierID
+	}
+	if filter.Owner != "" {
+		selector["owner"] = filter.Owner
+	}
+	if filter.Verified != "" {
+		verified, err := verifiedSelector(ctx, "verified", filter.Verified)
+		if err != nil {
+			return nil, err
+		}
+		selector["$and"] = []interface{}{verified}
+	}
+	if filter.Availability != "" {
+		selector["availability"] = filter.Availability
+	}
+	return selector, nil
+}
+
+// batterySelector : 필터를 CouchDB selector로 변환
+func batterySelector(ctx contractapi.TransactionContextInterface, filter BatteryFilter) (map[string]interface{}, error) {
+	selector := map[string]interface{}{"docType": docTypeBattery}
+	if filter.Status != "" {
+		selector["status"] = filter.Status
+	}
+	if filter.Category != "" {
+		selector["category"] = filter.Category
+	}
+	if filter.Verified != "" {
+		verified, err := verifiedSelector(ctx, "Verified", filter.Verified)
+		if err != nil {
+			return nil, err
+		}
+		selector["$and"] = []interface{}{verified}
+	}
+	if filter.Owner != "" {
+		selector["owner"] = filter.Owner
+	}
+
+	soh := map[string]interface{}{}
+	if filter.SOHMin != nil {
+		soh["$gte"] = *filter.SOHMin
+	}
+	if filter.SOHMax != nil {
+		soh["$lte"] = *filter.SOHMax
+	}
+	if len(soh) > 0 {
+		selector["soh"] = soh
+	}
+
+	// 제조일은 초 단위 UTC RFC3339(고정 폭)로 저장되므로 경계도 같은 형식으로 맞춰 사전순 비교
+	// (소수 초가 있는 경계는 시작은 올리고 끝은 내려 초 단위 저장값과 같은 결과가 되도록 함)
+	manufactured := map[string]interface{}{}
+	if filter.ManufacturedFrom != "" {
+		from, err := time.Parse(time.RFC3339, filter.ManufacturedFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manufacturedFrom: %v", err)
+		}
+		start := storedManufactureDate(from)
+		if start.Before(from) {
+			start = start.Add(manufactureDatePrecision)
+		}
+		manufactured["$gte"] = start.Format(time.RFC3339)
+	}
+	if filter.ManufacturedTo != "" {
+		to, err := time.Parse(time.RFC3339, filter.ManufacturedTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manufacturedTo: %v", err)
+		}
+		manufactured["$lte"] = storedManufactureDate(to).Format(time.RFC3339)
+	}
+	if len(manufactured) > 0 {
+		selector["manufactureDate"] = manufactured
+	}
+
+	return selector, nil
+}
+
+// manufactureDatePrecision : 저장하는 제조일의 정밀도 (소수 초 없이 고정 폭 문자열이 되도록)
+const manufactureDatePrecision = time.Second
+
+// storedManufactureDate : 제조일을 저장 형식(UTC, 초 단위)으로 정규화
+func storedManufactureDate(date time.Time) time.Time {
+	return date.UTC().Truncate(manufactureDatePrecision)
+}
+
+// MigrateManufactureDates : 소수 초나 UTC가 아닌 오프셋으로 저장된 배터리 제조일을 고정 폭 형식으로 다시 저장
+func (s *PublicContract) MigrateManufactureDates(ctx contractapi.TransactionContextInterface) (int, error) {
+	if _, err := authorize(ctx, "MigrateManufactureDates"); err != nil {
+		return 0, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(indexBatteryByStatus, []string{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query index %s: %v", indexBatteryByStatus, err)
+	}
+	defer resultsIterator.Close()
+
+	migrated := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return migrated, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to split index key: %v", err)
+		}
+
+		battery, err := readStoredBattery(ctx, keyParts[len(keyParts)-1])
+		if err != nil {
+			return migrated, err
+		}
+		if battery == nil {
+			continue
+		}
+		normalized := storedManufactureDate(battery.ManufactureDate)
+		if normalized.Equal(battery.ManufactureDate) && battery.ManufactureDate.Location() == time.UTC {
+			continue
+		}
+
+		if err := s.saveBattery(ctx, battery); err != nil {
+			return migrated, fmt.Errorf("failed to update battery: %v", err)
+		}
+		migrated++
+	}
+
+	err = emitEvent(ctx, EventMigrationCompleted, eventAssetConfig, "manufactureDates", MigrationCompletedPayload{Migration: "MigrateManufactureDates", Migrated: migrated})
+	if err != nil {
+		return migrated, err
+	}
+
+	return migrated, nil
+}
+
+// queryMaterialsWithPagination : selector로 원자재를 페이지 단위 조회
+func (s *PublicContract) queryMaterialsWithPagination(ctx contractapi.TransactionContextInterface, selector map[string]interface{}, pageSize int32, bookmark string) (*PaginatedMaterials, error) {
+	if err := validatePageSize(pageSize); err != nil {
+		return nil, err
+	}
+
+	queryBytes, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(queryBytes), pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query raw materials: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rawMaterials := []RawMaterial{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var rawMaterial RawMaterial
+		err = json.Unmarshal(queryResponse.Value, &rawMaterial)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal raw material: %v", err)
+		}
+		rawMaterial.Quantity.assumeLegacyUnit()
+		rawMaterial.Verified = effectiveVerified(rawMaterial.Verified, rawMaterial.VerifiedUntil, now)
+
+		rawMaterials = append(rawMaterials, rawMaterial)
+	}
+
+	return &PaginatedMaterials{
+		Records:             rawMaterials,
+		FetchedRecordsCount: metadata.FetchedRecordsCount,
+		Bookmark:            metadata.Bookmark,
+	}, nil
+}
+
+// queryBatteriesWithPagination : selector로 배터리를 페이지 단위 조회
+func (s *PublicContract) queryBatteriesWithPagination(ctx contractapi.TransactionContextInterface, selector map[string]interface{}, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+	if err := validatePageSize(pageSize); err != nil {
+		return nil, err
+	}
+
+	queryBytes, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(queryBytes), pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batteries: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	batteries := []Battery{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		battery, err := readBatteryState(ctx, queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		if battery == nil {
+			continue
+		}
+
+		batteries = append(batteries, *battery)
+	}
+
+	return &PaginatedBatteries{
+		Records:             batteries,
+		FetchedRecordsCount: metadata.FetchedRecordsCount,
+		Bookmark:            metadata.Bookmark,
+	}, nil
+}
+
+// QueryAllRawMaterialsPaginated : 필터 조건에 맞는 원자재를 페이지 단위로 조회
+func (s *PublicContract) QueryAllRawMaterialsPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedMaterials, error) {
+	var filter MaterialFilter
+	if err := parseFilter(filterJSON, &filter); err != nil {
+		return nil, err
+	}
+
+	selector, err := materialSelector(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queryMaterialsWithPagination(ctx, selector, pageSize, bookmark)
+}
+
+// QueryNewMaterialsPaginated : 신규 원자재를 페이지 단위로 조회
+func (s *PublicContract) QueryNewMaterialsPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedMaterials, error) {
+	var filter MaterialFilter
+	if err := parseFilter(filterJSON, &filter); err != nil {
+		return nil, err
+	}
+	filter.Status = "NEW"
+
+	selector, err := materialSelector(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queryMaterialsWithPagination(ctx, selector, pageSize, bookmark)
+}
+
+// QueryRecycledMaterialsPaginated : 재활용 원자재를 페이지 단위로 조회
+func (s *PublicContract) QueryRecycledMaterialsPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedMaterials, error) {
+	var filter MaterialFilter
+	if err := parseFilter(filterJSON, &filter); err != nil {
+		return nil, err
+	}
+	filter.Status = "RECYCLED"
+
+	selector, err := materialSelector(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queryMaterialsWithPagination(ctx, selector, pageSize, bookmark)
+}
+
+// QueryAllMaterialsPaginated : 수량이 남아 있는 신규/재활용 원자재를 페이지 단위로 조회
+func (s *PublicContract) QueryAllMaterialsPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedMaterials, error) {
+	var filter MaterialFilter
+	if err := parseFilter(filterJSON, &filter); err != nil {
+		return nil, err
+	}
+
+	selector, err := materialSelector(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Status == "" {
+		selector["status"] = map[string]interface{}{"$in": []string{"NEW", "RECYCLED"}}
+	}
+	// 수량은 {"milli", "unit"} 객체 (단위 도입 이전 문서는 정수)
+	selector["$or"] = []interface{}{
+		map[string]interface{}{"quantity.milli": map[string]interface{}{"$gt": 0}},
+		map[string]interface{}{"quantity": map[string]interface{}{"$type": "number", "$gt": 0}},
+	}
+
+	return s.queryMaterialsWithPagination(ctx, selector, pageSize, bookmark)
+}
+
+// QueryAllBatteriesPaginated : 필터 조건(상태, 카테고리, SOH 범위, 제조일 범위)에 맞는 배터리를 페이지 단위로 조회
+func (s *PublicContract) QueryAllBatteriesPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+	var filter BatteryFilter
+	if err := parseFilter(filterJSON, &filter); err != nil {
+		return nil, err
+	}
+
+	selector, err := batterySelector(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queryBatteriesWithPagination(ctx, selector, pageSize, bookmark)
+}
+
+// QueryBatteriesWithMaintenanceRequestPaginated : 유지보수 요청이 true인 배터리를 페이지 단위로 조회
+func (s *PublicContract) QueryBatteriesWithMaintenanceRequestPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "QueryBatteriesWithMaintenanceRequestPaginated")
+	if err != nil {
+		return nil, err
+	}
+
+	var filter BatteryFilter
+	if err := parseFilter(filterJSON, &filter); err != nil {
+		return nil, err
+	}
+
+	selector, err := batterySelector(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	selector["maintenanceRequest"] = true
+
+	return s.queryBatteriesWithPagination(ctx, selector, pageSize, bookmark)
+}
+
+// QueryBatteriesWithAnalysisRequestPaginated : 분석 요청이 true인 배터리를 페이지 단위로 조회
+func (s *PublicContract) QueryBatteriesWithAnalysisRequestPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "QueryBatteriesWithAnalysisRequestPaginated")
+	if err != nil {
+		return nil, err
+	}
+
+	var filter BatteryFilter
+	if err := parseFilter(filterJSON, &filter); err != nil {
+		return nil, err
+	}
+
+	selector, err := batterySelector(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	selector["analysisRequest"] = true
+
+	return s.queryBatteriesWithPagination(ctx, selector, pageSize, bookmark)
+}
+
+// QueryBatteriesWithRecycleAvailabilityPaginated : 재활용 가능으로 설정된 배터리를 페이지 단위로 조회
+func (s *PublicContract) QueryBatteriesWithRecycleAvailabilityPaginated(ctx contractapi.TransactionContextInterface, filterJSON string, pageSize int32, bookmark string) (*PaginatedBatteries, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "QueryBatteriesWithRecycleAvailabilityPaginated")
+	if err != nil {
+		return nil, err
+	}
+
+	var filter BatteryFilter
+	if err := parseFilter(filterJSON, &filter); err != nil {
+		return nil, err
+	}
+
+	selector, err := batterySelector(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	selector["recycleAvailability"] = true
+
+	return s.queryBatteriesWithPagination(ctx, selector, pageSize, bookmark)
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// 배터리 여권 문서 형식 (Regulation (EU) 2023/1542 Annex XIII)
+const (
+	passportSchemaVersion = "1.0"
+	passportRegulation    = "Regulation (EU) 2023/1542 Annex XIII"
+)
+
+// 여권 항목의 접근 등급 (Annex XIII 1~3항)
+const (
+	accessPublic            = "public"            // 일반 공개
+	accessInterestedPersons = "interestedPersons" // 정당한 이해관계자 및 집행위원회
+	accessAuthorities       = "authorities"       // 인증기관, 시장감시당국 및 집행위원회
+)
+
+// Annex XIII의 배터리 상태 구분
+const (
+	passportStatusOriginal   = "original"
+	passportStatusRepurposed = "repurposed"
+	passportStatusWaste      = "waste"
+)
+
+// criticalRawMaterials : 배터리 원자재 중 EU 핵심 원자재 목록에 포함된 원자재
+var criticalRawMaterials = map[string]bool{
+	"Cobalt":    true,
+	"Lithium":   true,
+	"Manganese": true,
+	"Nickel":    true,
+	"Graphite":  true,
+}
+
+//go:embed passport_schema.json
+var passportSchema []byte
+
+// PassportAttribute : 여권 항목 하나 (원장에 아직 기록되지 않은 항목은 Value가 null)
+type PassportAttribute struct {
+	Value  interface{} `json:"value" metadata:",optional"`
+	Unit   string      `json:"unit,omitempty" metadata:",optional"`
+	Access string      `json:"access"`
+}
+
+func passportAttribute(access string, value interface{}, unit string) PassportAttribute {
+	return PassportAttribute{Value: value, Unit: unit, Access: access}
+}
+
+// PassportGeneralInformation : 일반 정보 (Annex VI Part A)
+type PassportGeneralInformation struct {
+	ManufacturerIdentification PassportAttribute `json:"manufacturerIdentification"`
+	ManufacturingPlace         PassportAttribute `json:"manufacturingPlace"`
+	ManufacturingDate          PassportAttribute `json:"manufacturingDate"`
+	BatteryCategory            PassportAttribute `json:"batteryCategory"`
+	BatteryWeight              PassportAttribute `json:"batteryWeight"`
+	BatteryStatus              PassportAttribute `json:"batteryStatus"`
+	LifecycleStatus            PassportAttribute `json:"lifecycleStatus"`
+	ConformityVerification     PassportAttribute `json:"conformityVerification"`
+}
+
+// PassportCarbonFootprint : 탄소발자국 정보 (제7조)
+type PassportCarbonFootprint struct {
+	TotalCarbonFootprint             PassportAttribute `json:"totalCarbonFootprint"`
+	CarbonFootprintPerFunctionalUnit PassportAttribute `json:"carbonFootprintPerFunctionalUnit"`
+	PerformanceClass                 PassportAttribute `json:"performanceClass"`
+	LifecycleStageShares             PassportAttribute `json:"lifecycleStageShares"`
+	StudyReference                   PassportAttribute `json:"studyReference"`
+}
+
+// PassportDueDiligence : 공급망 실사 정보 (제52조)
+type PassportDueDiligence struct {
+	MaterialSources    PassportAttribute `json:"materialSources"`
+	DueDiligenceReport PassportAttribute `json:"dueDiligenceReport"`
+}
+
+// PassportMaterialSource : 배터리에 투입된 원자재 로트의 출처
+type PassportMaterialSource struct {
+	MaterialID          string `json:"materialID"`
+	MaterialType        string `json:"materialType"`
+	SupplierID          string `json:"supplierID"`
+	Recycled            bool   `json:"recycled"`
+	Verified            string `json:"verified"`
+	Country             string `json:"country"`             // 원산지 (재활용 로트 및 미등록 로트는 빈 값)
+	CertificationScheme string `json:"certificationScheme"` // 원산지 인증 제도
+	HighRisk            bool   `json:"highRisk"`
+	HighRiskOverride    string `json:"highRiskOverride"` // 고위험 로트 투입 사유
+}
+
+// PassportMaterials : 원자재 및 구성 정보
+type PassportMaterials struct {
+	BatteryChemistry     PassportAttribute `json:"batteryChemistry"`
+	HazardousSubstances  PassportAttribute `json:"hazardousSubstances"`
+	CriticalRawMaterials PassportAttribute `json:"criticalRawMaterials"`
+	DetailedComposition  PassportAttribute `json:"detailedComposition"`
+}
+
+// PassportComposition : 원자재 종류별 투입량과 비중
+type PassportComposition struct {
+	MaterialType string  `json:"materialType"`
+	Quantity     float64 `json:"quantity"` // kg
+	SharePercent float64 `json:"sharePercent"`
+}
+
+// PassportCircularity : 순환성 정보 (재생 원료 함량, 해체 및 회수)
+type PassportCircularity struct {
+	RecycledContent        PassportAttribute `json:"recycledContent"`
+	RenewableContent       PassportAttribute `json:"renewableContent"`
+	EndOfLifeAvailability  PassportAttribute `json:"endOfLifeAvailability"`
+	DismantlingInformation PassportAttribute `json:"dismantlingInformation"`
+	RecoveredMaterials     PassportAttribute `json:"recoveredMaterials"`
+}
+
+// PassportPerformance : 성능 및 내구성 정보 (제10조, 제14조)
+type PassportPerformance struct {
+	RatedCapacity          PassportAttribute `json:"ratedCapacity"`
+	NominalVoltage         PassportAttribute `json:"nominalVoltage"`
+	ExpectedLifetimeCycles PassportAttribute `json:"expectedLifetimeCycles"`
+	StateOfHealth          PassportAttribute `json:"stateOfHealth"`
+	StateOfCharge          PassportAttribute `json:"stateOfCharge"`
+	StateOfCertifiedEnergy PassportAttribute `json:"stateOfCertifiedEnergy"`
+	RemainingLifeCycles    PassportAttribute `json:"remainingLifeCycles"`
+	NegativeEvents         PassportAttribute `json:"negativeEvents"`
+	MaintenanceHistory     PassportAttribute `json:"maintenanceHistory"`
+}
+
+// PassportNegativeEvent : 사고 기록 요약
+type PassportNegativeEvent struct {
+	IncidentType  string `json:"incidentType"`
+	IncidentDate  string `json:"incidentDate"`
+	SeverityLabel string `json:"severityLabel"`
+}
+
+// PassportMaintenanceEntry : 유지보수 기록 요약
+type PassportMaintenanceEntry struct {
+	MaintenanceDate string `json:"maintenanceDate"`
+	Company         string `json:"company"`
+	WorkPerformed   string `json:"workPerformed"`
+}
+
+// PassportDocument : Annex XIII 구조의 배터리 여권 문서
+type PassportDocument struct {
+	SchemaVersion            string                     `json:"schemaVersion"`
+	Regulation               string                     `json:"regulation"`
+	PassportID               string                     `json:"passportID"`
+	PredecessorPassportID    string                     `json:"predecessorPassportID,omitempty" metadata:",optional"` // 2차 사용 전환 이전의 여권
+	BatteryID                string                     `json:"batteryID"`
+	GeneratedAt              string                     `json:"generatedAt"`
+	GeneralInformation       PassportGeneralInformation `json:"generalInformation"`
+	CarbonFootprint          PassportCarbonFootprint    `json:"carbonFootprint"`
+	SupplyChainDueDiligence  PassportDueDiligence       `json:"supplyChainDueDiligence"`
+	MaterialsAndComposition  PassportMaterials          `json:"materialsAndComposition"`
+	Circularity              PassportCircularity        `json:"circularity"`
+	PerformanceAndDurability PassportPerformance        `json:"performanceAndDurability"`
+}
+
+// passportBatteryStatus : 수명 주기 상태를 Annex XIII의 배터리 상태로 변환 (2차 사용으로 전환된 배터리는 폐기 전까지 repurposed)
+func passportBatteryStatus(battery *Battery) string {
+	switch {
+	case battery.Status == statusEndOfLife || battery.Status == statusDisassembled:
+		return passportStatusWaste
+	case battery.Status == statusSecondLife || battery.PredecessorPassportID != "":
+		return passportStatusRepurposed
+	default:
+		return passportStatusOriginal
+	}
+}
+
+// nullableString : 빈 문자열은 null로 표시 (원장에 공개되지 않은 값)
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// splitList : 쉼표로 구분된 목록을 정리
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// sortedRawMaterialKeys : BOM 항목 키를 정렬하여 반환 (문서 순서를 결정적으로 유지)
+func sortedRawMaterialKeys(rawMaterials map[string]RawMaterialDetail) []string {
+	keys := make([]string, 0, len(rawMaterials))
+	for key := range rawMaterials {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// passportMaterialSources : BOM의 원자재 로트별 공급자와 검증 상태
+func passportMaterialSources(ctx contractapi.TransactionContextInterface, battery *Battery) ([]PassportMaterialSource, error) {
+	sources := []PassportMaterialSource{}
+	seen := make(map[string]bool)
+	for _, key := range sortedRawMaterialKeys(battery.RawMaterials) {
+		detail := battery.RawMaterials[key]
+		if seen[detail.MaterialID] {
+			continue
+		}
+		seen[detail.MaterialID] = true
+
+		source := PassportMaterialSource{
+			MaterialID:   detail.MaterialID,
+			MaterialType: detail.MaterialType,
+			Recycled:     detail.Status == "RECYCLED",
+
+			HighRiskOverride: detail.HighRiskOverride,
+		}
+		material, err := readMaterialState(ctx, detail.MaterialID)
+		if err != nil {
+			return nil, err
+		}
+		if material != nil {
+			source.SupplierID = material.SupplierID
+			source.Verified = material.Verified
+			source.Recycled = material.Status == "RECYCLED"
+			if material.Origin != nil {
+				source.Country = material.Origin.Country
+				source.CertificationScheme = material.Origin.CertificationScheme
+				source.HighRisk = material.Origin.HighRisk
+			}
+			if material.DueDiligence != nil && material.DueDiligence.HighRisk {
+				source.HighRisk = true
+			}
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// passportComposition : 원자재 종류별 투입량과 전체 대비 비중
+func passportComposition(battery *Battery) ([]PassportComposition, []string) {
+	totals := make(map[string]int64) // mg
+	var total int64
+	for _, detail := range battery.RawMaterials {
+		totals[detail.MaterialType] += detail.Quantity.milligrams()
+		total += detail.Quantity.milligrams()
+	}
+
+	materialTypes := make([]string, 0, len(totals))
+	for materialType := range totals {
+		materialTypes = append(materialTypes, materialType)
+	}
+	sort.Strings(materialTypes)
+
+	composition := make([]PassportComposition, 0, len(materialTypes))
+	critical := []string{}
+	for _, materialType := range materialTypes {
+		entry := PassportComposition{MaterialType: materialType, Quantity: milligramsToKilograms(totals[materialType])}
+		if total > 0 {
+			entry.SharePercent = math.Round(float64(totals[materialType])/float64(total)*10000) / 100
+		}
+		composition = append(composition, entry)
+		if criticalRawMaterials[materialType] {
+			critical = append(critical, materialType)
+		}
+	}
+
+	return composition, critical
+}
+
+// buildPassport : 원장의 배터리, 원자재, 정비·사고·해체 기록으로 여권 문서를 구성
+func (s *PublicContract) buildPassport(ctx contractapi.TransactionContextInterface, battery *Battery) (*PassportDocument, error) {
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := passportMaterialSources(ctx, battery)
+	if err != nil {
+		return nil, err
+	}
+	composition, critical := passportComposition(battery)
+
+	maintenanceRecords, err := s.QueryMaintenanceRecords(ctx, battery.BatteryID)
+	if err != nil {
+		return nil, err
+	}
+	maintenanceHistory := make([]PassportMaintenanceEntry, 0, len(maintenanceRecords))
+	for _, record := range maintenanceRecords {
+		maintenanceHistory = append(maintenanceHistory, PassportMaintenanceEntry{
+			MaintenanceDate: record.MaintenanceDate,
+			Company:         record.Company,
+			WorkPerformed:   record.WorkPerformed,
+		})
+	}
+
+	accidentRecords, err := s.QueryAccidentRecords(ctx, battery.BatteryID)
+	if err != nil {
+		return nil, err
+	}
+	negativeEvents := make([]PassportNegativeEvent, 0, len(accidentRecords))
+	for _, record := range accidentRecords {
+		negativeEvents = append(negativeEvents, PassportNegativeEvent{
+			IncidentType:  record.IncidentType,
+			IncidentDate:  record.IncidentDate,
+			SeverityLabel: record.SeverityLabel,
+		})
+	}
+
+	var recoveredMaterials interface{}
+	extraction, err := readExtractionRecord(ctx, battery.BatteryID)
+	if err != nil {
+		return nil, err
+	}
+	if extraction != nil {
+		recoveredMaterials = extraction.Materials
+	}
+
+	// 제조 시 계산된 탄소발자국 (기록 이전에 제조된 배터리는 null)
+	var totalCarbonFootprint, carbonFootprintPerKWh, lifecycleStageShares, studyReference interface{}
+	footprint, err := readCarbonFootprint(ctx, battery.BatteryID)
+	if err != nil {
+		return nil, err
+	}
+	if footprint != nil {
+		totalCarbonFootprint = footprint.Total
+		if footprint.PerKWh > 0 {
+			carbonFootprintPerKWh = footprint.PerKWh
+		}
+		shares := make(map[string]float64)
+		for _, stage := range footprint.Stages {
+			shares[stage.Stage] = stage.SharePercent
+		}
+		lifecycleStageShares = shares
+		studyReference = footprint.TxID
+	}
+
+	recycledContent := battery.RecyclingRatesByMaterial
+	if recycledContent == nil {
+		recycledContent = map[string]float64{}
+	}
+
+	return &PassportDocument{
+		SchemaVersion:         passportSchemaVersion,
+		Regulation:            passportRegulation,
+		PassportID:            battery.PassportID,
+		PredecessorPassportID: battery.PredecessorPassportID,
+		BatteryID:             battery.BatteryID,
+		GeneratedAt:           now.Format(time.RFC3339),
+		GeneralInformation: PassportGeneralInformation{
+			ManufacturerIdentification: passportAttribute(accessPublic, battery.ManufacturerName, ""),
+			ManufacturingPlace:         passportAttribute(accessPublic, nullableString(battery.Location), ""),
+			ManufacturingDate:          passportAttribute(accessPublic, battery.ManufactureDate.UTC().Format(time.RFC3339), ""),
+			BatteryCategory:            passportAttribute(accessPublic, battery.Category, ""),
+			BatteryWeight:              passportAttribute(accessPublic, battery.Weight, "kg"),
+			BatteryStatus:              passportAttribute(accessPublic, passportBatteryStatus(battery), ""),
+			LifecycleStatus:            passportAttribute(accessPublic, battery.Status, ""),
+			ConformityVerification:     passportAttribute(accessAuthorities, battery.Verified, ""),
+		},
+		CarbonFootprint: PassportCarbonFootprint{
+			TotalCarbonFootprint:             passportAttribute(accessPublic, totalCarbonFootprint, "kgCO2e"),
+			CarbonFootprintPerFunctionalUnit: passportAttribute(accessPublic, carbonFootprintPerKWh, "kgCO2e/kWh"),
+			PerformanceClass:                 passportAttribute(accessPublic, nil, ""),
+			LifecycleStageShares:             passportAttribute(accessPublic, lifecycleStageShares, "%"),
+			StudyReference:                   passportAttribute(accessPublic, studyReference, ""),
+		},
+		SupplyChainDueDiligence: PassportDueDiligence{
+			MaterialSources:    passportAttribute(accessPublic, sources, ""),
+			DueDiligenceReport: passportAttribute(accessPublic, nil, ""),
+		},
+		MaterialsAndComposition: PassportMaterials{
+			BatteryChemistry:     passportAttribute(accessPublic, nil, ""),
+			HazardousSubstances:  passportAttribute(accessPublic, splitList(battery.ContainsHazardous), ""),
+			CriticalRawMaterials: passportAttribute(accessPublic, critical, ""),
+			DetailedComposition:  passportAttribute(accessInterestedPersons, composition, ""),
+		},
+		Circularity: PassportCircularity{
+			RecycledContent:        passportAttribute(accessPublic, recycledContent, "%"),
+			RenewableContent:       passportAttribute(accessPublic, nil, "%"),
+			EndOfLifeAvailability:  passportAttribute(accessPublic, battery.RecycleAvailability, ""),
+			DismantlingInformation: passportAttribute(accessInterestedPersons, nil, ""),
+			RecoveredMaterials:     passportAttribute(accessInterestedPersons, recoveredMaterials, ""),
+		},
+		PerformanceAndDurability: PassportPerformance{
+			RatedCapacity:          passportAttribute(accessPublic, battery.Capacity, "Ah"),
+			NominalVoltage:         passportAttribute(accessPublic, battery.Voltage, "V"),
+			ExpectedLifetimeCycles: passportAttribute(accessPublic, battery.TotalLifeCycle, "cycles"),
+			StateOfHealth:          passportAttribute(accessInterestedPersons, battery.SOH, "%"),
+			StateOfCharge:          passportAttribute(accessInterestedPersons, battery.SOC, "%"),
+			StateOfCertifiedEnergy: passportAttribute(accessInterestedPersons, battery.SOCE, "%"),
+			RemainingLifeCycles:    passportAttribute(accessInterestedPersons, battery.RemainingLifeCycle, "cycles"),
+			NegativeEvents:         passportAttribute(accessInterestedPersons, negativeEvents, ""),
+			MaintenanceHistory:     passportAttribute(accessInterestedPersons, maintenanceHistory, ""),
+		},
+	}, nil
+}
+
+// validatePassport : 번들된 JSON 스키마(passport_schema.json)로 여권 문서를 검증
+func validatePassport(passport *PassportDocument) error {
+	passportAsBytes, err := json.Marshal(passport)
+	if err != nil {
+		return fmt.Errorf("failed to marshal passport: %v", err)
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(passportSchema), gojsonschema.NewBytesLoader(passportAsBytes))
+	if err != nil {
+		return fmt.Errorf("failed to validate passport: %v", err)
+	}
+	if !result.Valid() {
+		violations := make([]string, 0, len(result.Errors()))
+		for _, violation := range result.Errors() {
+			violations = append(violations, violation.String())
+		}
+		return fmt.Errorf("passport for battery %s does not conform to schema: %s", passport.BatteryID, strings.Join(violations, "; "))
+	}
+
+	return nil
+}
+
+// ExportPassport : Regulation (EU) 2023/1542 Annex XIII 구조의 배터리 여권을 원장에서 구성하여 반환
+// 각 항목에는 접근 등급(public, interestedPersons, authorities)이 표시되며, 문서는 번들된 스키마로 검증된다.
+func (s *PublicContract) ExportPassport(ctx contractapi.TransactionContextInterface, batteryID string) (*PassportDocument, error) {
+	battery, err := s.QueryBatteryDetails(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+
+	passport, err := s.buildPassport(ctx, battery)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validatePassport(passport)
+	if err != nil {
+		return nil, err
+	}
+
+	return passport, nil
+}
+
+// QueryPassportSchema : 여권 문서의 JSON 스키마 조회
+func (s *PublicContract) QueryPassportSchema(ctx contractapi.TransactionContextInterface) (string, error) {
+	return string(passportSchema), nil
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 프라이빗 데이터 컬렉션 이름 (collections_config.json과 일치해야 함)
+const (
+	// 원자재 공급 단가 및 계약 조건 (공급사, 제조사)
+	collectionSupplierCommercial = "supplierCommercialCollection"
+	// 제조 공장 상세 정보 (제조사, 검증 기관)
+	collectionManufacturerPlant = "manufacturerPlantCollection"
+	// 원시 텔레메트리 (전기차 운행, 유지보수, 분석 기관)
+	collectionTelemetry = "telemetryCollection"
+)
+
+// transient map 키 (민감한 입력은 트랜잭션 인자가 아닌 transient로 전달)
+const (
+	transientCommercialTerms = "commercialTerms"
+	transientPlantDetails    = "plantDetails"
+	transientTelemetry       = "telemetry"
+	transientSalt            = "salt"
+)
+
+// minSaltBytes : 프라이빗 데이터 솔트의 최소 길이 (단가처럼 경우의 수가 적은 값의 해시 대입 방지)
+const minSaltBytes = 16
+
+const (
+	docTypePrivateRef = "privateRef"
+
+	// 공개 원장의 프라이빗 데이터 해시 기록 키 (privateRef~자산ID~컬렉션~키)
+	privateRefObjectType = "privateRef"
+
+	// 텔레메트리 프라이빗 데이터 키 (telemetry~배터리ID~측정ID)
+	telemetryObjectType = "telemetry"
+)
+
+// CommercialTerms : 원자재 로트의 공급 단가 및 계약 조건 (supplierCommercialCollection)
+type CommercialTerms struct {
+	MaterialID    string  `json:"materialID"`
+	UnitPrice     float64 `json:"unitPrice"`
+	Currency      string  `json:"currency"`
+	ContractRef   string  `json:"contractRef"`
+	ContractTerms string  `json:"contractTerms"`
+	ValidUntil    string  `json:"validUntil"`
+	Salt          string  `json:"salt"` // transient "salt"의 16진수 (해시 대입 방지)
+}
+
+// PlantDetails : 배터리 제조 공장 상세 정보 (manufacturerPlantCollection)
+type PlantDetails struct {
+	BatteryID      string `json:"batteryID"`
+	PlantID        string `json:"plantID"`
+	PlantName      string `json:"plantName"`
+	Address        string `json:"address"`
+	ProductionLine string `json:"productionLine"`
+	Notes          string `json:"notes"`
+	Salt           string `json:"salt"` // sha256(transient "salt"‖배터리ID)의 16진수 (해시 대입 방지, 배터리마다 다름)
+}
+
+// TelemetryReading : 배터리 원시 측정값 한 건 (telemetryCollection)
+type TelemetryReading struct {
+	ReadingID   string             `json:"readingID"`
+	BatteryID   string             `json:"batteryID"`
+	RecordedAt  string             `json:"recordedAt"` // RFC3339
+	Source      string             `json:"source"`
+	Measurement map[string]float64 `json:"measurement"`
+	Salt        string             `json:"salt,omitempty" metadata:",optional"` // transient "salt"의 16진수 (해시 대입 방지, 오프체인 배치 레코드는 빈 값)
+}
+
+// PrivateDataReference : 공개 원장에 남기는 프라이빗 데이터의 위치와 SHA-256 해시
+type PrivateDataReference struct {
+	DocType       string `json:"docType"`
+	AssetID       string `json:"assetID"`
+	Collection    string `json:"collection"`
+	Key           string `json:"key"`
+	Hash          string `json:"hash"`
+	SubmittingMSP string `json:"submittingMSP"`
+	TxID          string `json:"txID"`
+	RecordedAt    string `json:"recordedAt"`
+}
+
+// PrivateDataVerification : 공개된 값과 원장의 해시를 비교한 결과
+type PrivateDataVerification struct {
+	Collection    string `json:"collection"`
+	Key           string `json:"key"`
+	OnChainHash   string `json:"onChainHash"`
+	DisclosedHash string `json:"disclosedHash"`
+	Matches       bool   `json:"matches"`
+}
+
+// readTransient : transient map에서 필수 입력을 읽음
+func readTransient(ctx contractapi.TransactionContextInterface, name string) ([]byte, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient map: %v", err)
+	}
+
+	value, exists := transientMap[name]
+	if !exists || len(value) == 0 {
+		return nil, fmt.Errorf("%s must be provided in the transient map", name)
+	}
+
+	return value, nil
+}
+
+// hasTransient : transient map에 선택 입력이 있는지 확인
+func hasTransient(ctx contractapi.TransactionContextInterface, name string) (bool, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return false, fmt.Errorf("failed to read transient map: %v", err)
+	}
+	return len(transientMap[name]) > 0, nil
+}
+
+// readSalt : 프라이빗 데이터에 함께 저장할 솔트를 transient "salt"에서 읽음
+// 공개 원장의 해시(GetPrivateDataHash와 해시 기록)는 솔트를 포함한 값의 해시이므로 값을 대입해 맞춰 볼 수 없다.
+func readSalt(ctx contractapi.TransactionContextInterface) (string, error) {
+	salt, err := readSaltBytes(ctx)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+func readSaltBytes(ctx contractapi.TransactionContextInterface) ([]byte, error) {
+	salt, err := readTransient(ctx, transientSalt)
+	if err != nil {
+		return nil, err
+	}
+	if len(salt) < minSaltBytes {
+		return nil, fmt.Errorf("%s must be at least %d random bytes", transientSalt, minSaltBytes)
+	}
+	return salt, nil
+}
+
+// putPrivateWithReference : 값을 컬렉션에 저장하고, 공개 원장에는 위치와 해시만 기록
+func putPrivateWithReference(ctx contractapi.TransactionContextInterface, assetID string, collection string, key string, value interface{}) (*PrivateDataReference, error) {
+	valueAsBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private data: %v", err)
+	}
+
+	err = ctx.GetStub().PutPrivateData(collection, key, valueAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put private data to %s: %v", collection, err)
+	}
+
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 피어가 저장하는 프라이빗 데이터 해시(GetPrivateDataHash)와 같은 SHA-256 값
+	hash := sha256.Sum256(valueAsBytes)
+	reference := &PrivateDataReference{
+		DocType:       docTypePrivateRef,
+		AssetID:       assetID,
+		Collection:    collection,
+		Key:           key,
+		Hash:          hex.EncodeToString(hash[:]),
+		SubmittingMSP: clientMSPID,
+		TxID:          ctx.GetStub().GetTxID(),
+		RecordedAt:    now.Format(time.RFC3339),
+	}
+
+	referenceKey, err := ctx.GetStub().CreateCompositeKey(privateRefObjectType, []string{assetID, collection, key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create private data reference key: %v", err)
+	}
+	referenceAsBytes, err := json.Marshal(reference)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private data reference: %v", err)
+	}
+	err = ctx.GetStub().PutState(referenceKey, referenceAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put private data reference: %v", err)
+	}
+
+	assetType := eventAssetBattery
+	if collection == collectionSupplierCommercial {
+		assetType = eventAssetMaterial
+	}
+	err = emitEvent(ctx, EventPrivateDataRecorded, assetType, assetID, PrivateDataRecordedPayload{Reference: reference})
+	if err != nil {
+		return nil, err
+	}
+
+	return reference, nil
+}
+
+// readPrivateReferences : 자산의 프라이빗 데이터 해시 기록 (collection이 비어 있으면 전체)
+func readPrivateReferences(ctx contractapi.TransactionContextInterface, assetID string, collection string) ([]PrivateDataReference, error) {
+	attributes := []string{assetID}
+	if collection != "" {
+		attributes = append(attributes, collection)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(privateRefObjectType, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query private data references: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	references := []PrivateDataReference{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var reference PrivateDataReference
+		if err := json.Unmarshal(queryResponse.Value, &reference); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal private data reference: %v", err)
+		}
+		references = append(references, reference)
+	}
+
+	return references, nil
+}
+
+// readPrivate : 컬렉션의 값을 읽음 (컬렉션 구성원이 아닌 조직의 피어에서는 실패)
+func readPrivate(ctx contractapi.TransactionContextInterface, collection string, key string, value interface{}) error {
+	valueAsBytes, err := ctx.GetStub().GetPrivateData(collection, key)
+	if err != nil {
+		return fmt.Errorf("failed to read private data from %s: %v", collection, err)
+	}
+	if valueAsBytes == nil {
+		return fmt.Errorf("no private data for %s in %s", key, collection)
+	}
+
+	err = json.Unmarshal(valueAsBytes, value)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal private data: %v", err)
+	}
+
+	return nil
+}
+
+func parseCommercialTerms(ctx contractapi.TransactionContextInterface, materialID string, termsAsBytes []byte) (*CommercialTerms, error) {
+	terms := new(CommercialTerms)
+	err := json.Unmarshal(termsAsBytes, terms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal commercial terms: %v", err)
+	}
+	if terms.UnitPrice < 0 {
+		return nil, fmt.Errorf("unit price must not be negative: %v", terms.UnitPrice)
+	}
+	terms.MaterialID = materialID
+	terms.Salt, err = readSalt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+// readUnitSalt : transient "salt"와 자산 ID로 자산마다 다른 솔트를 만듦 (sha256(salt‖assetID))
+// 배치 생산처럼 한 트랜잭션에서 같은 내용을 여러 개 저장해도 공개 해시가 서로 달라진다.
+func readUnitSalt(ctx contractapi.TransactionContextInterface, assetID string) (string, error) {
+	salt, err := readSaltBytes(ctx)
+	if err != nil {
+		return "", err
+	}
+	unitSalt := sha256.Sum256(append(salt, assetID...))
+	return hex.EncodeToString(unitSalt[:]), nil
+}
+
+func parsePlantDetails(ctx contractapi.TransactionContextInterface, batteryID string, detailsAsBytes []byte) (*PlantDetails, error) {
+	details := new(PlantDetails)
+	err := json.Unmarshal(detailsAsBytes, details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plant details: %v", err)
+	}
+	if strings.TrimSpace(details.PlantID) == "" {
+		return nil, fmt.Errorf("plantID is required")
+	}
+	details.BatteryID = batteryID
+	details.Salt, err = readUnitSalt(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// SetMaterialCommercialTerms : 원자재 로트의 단가·계약 조건을 transient "commercialTerms"로 받아 공급사 컬렉션에 저장 (로트 소유자만)
+// transient "salt"에 임의 값을 함께 전달해야 한다.
+func (s *PublicContract) SetMaterialCommercialTerms(ctx contractapi.TransactionContextInterface, materialID string) (*PrivateDataReference, error) {
+	caller, err := authorize(ctx, "SetMaterialCommercialTerms")
+	if err != nil {
+		return nil, err
+	}
+
+	material, err := readMaterialState(ctx, materialID)
+	if err != nil {
+		return nil, err
+	}
+	if material == nil {
+		return nil, fmt.Errorf("raw material not found: %s", materialID)
+	}
+	if err := requireLotOwner(caller, material); err != nil {
+		return nil, err
+	}
+
+	termsAsBytes, err := readTransient(ctx, transientCommercialTerms)
+	if err != nil {
+		return nil, err
+	}
+	terms, err := parseCommercialTerms(ctx, materialID, termsAsBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return putPrivateWithReference(ctx, materialID, collectionSupplierCommercial, materialID, terms)
+}
+
+// QueryMaterialCommercialTerms : 원자재 로트의 단가·계약 조건 조회 (공급사 컬렉션 구성원 전용)
+func (s *PublicContract) QueryMaterialCommercialTerms(ctx contractapi.TransactionContextInterface, materialID string) (*CommercialTerms, error) {
+	terms := new(CommercialTerms)
+	if err := readPrivate(ctx, collectionSupplierCommercial, materialID, terms); err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+// SetBatteryPlantDetails : 제조 공장 상세 정보를 transient "plantDetails"로 받아 제조사 컬렉션에 저장하고
+// 공개 배터리 문서의 공장 위치는 비움 (배터리 소유자만, transient "salt"에 임의 값을 함께 전달)
+func (s *PublicContract) SetBatteryPlantDetails(ctx contractapi.TransactionContextInterface, batteryID string) (*PrivateDataReference, error) {
+	caller, err := authorize(ctx, "SetBatteryPlantDetails")
+	if err != nil {
+		return nil, err
+	}
+
+	battery, err := loadBattery(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireBatteryOwner(caller, battery); err != nil {
+		return nil, err
+	}
+
+	detailsAsBytes, err := readTransient(ctx, transientPlantDetails)
+	if err != nil {
+		return nil, err
+	}
+	details, err := parsePlantDetails(ctx, batteryID, detailsAsBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	reference, err := putPrivateWithReference(ctx, batteryID, collectionManufacturerPlant, batteryID, details)
+	if err != nil {
+		return nil, err
+	}
+
+	battery.Location = ""
+	err = s.saveBattery(ctx, battery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update battery: %v", err)
+	}
+
+	return reference, nil
+}
+
+// QueryBatteryPlantDetails : 배터리 제조 공장 상세 정보 조회 (제조사 컬렉션 구성원 전용)
+func (s *PublicContract) QueryBatteryPlantDetails(ctx contractapi.TransactionContextInterface, batteryID string) (*PlantDetails, error) {
+	details := new(PlantDetails)
+	if err := readPrivate(ctx, collectionManufacturerPlant, batteryID, details); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// RecordTelemetry : 원시 텔레메트리를 transient "telemetry"로 받아 텔레메트리 컬렉션에 저장 (transient "salt"에 임의 값을 함께 전달)
+func (s *PublicContract) RecordTelemetry(ctx contractapi.TransactionContextInterface, batteryID string) (*PrivateDataReference, error) {
+	if _, err := authorize(ctx, "RecordTelemetry"); err != nil {
+		return nil, err
+	}
+
+	battery, err := readBatteryState(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if battery == nil {
+		return nil, fmt.Errorf("battery not found: %s", batteryID)
+	}
+
+	telemetryAsBytes, err := readTransient(ctx, transientTelemetry)
+	if err != nil {
+		return nil, err
+	}
+
+	var reading TelemetryReading
+	err = json.Unmarshal(telemetryAsBytes, &reading)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal telemetry: %v", err)
+	}
+	if len(reading.Measurement) == 0 {
+		return nil, fmt.Errorf("telemetry must contain at least one measurement")
+	}
+	if _, err := time.Parse(time.RFC3339, reading.RecordedAt); err != nil {
+		return nil, fmt.Errorf("invalid recordedAt %q: expected RFC3339", reading.RecordedAt)
+	}
+
+	reading.ReadingID, err = newID(ctx, "TELEMETRY")
+	if err != nil {
+		return nil, err
+	}
+	reading.BatteryID = batteryID
+	reading.Salt, err = readSalt(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	readingKey, err := ctx.GetStub().CreateCompositeKey(telemetryObjectType, []string{batteryID, reading.ReadingID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telemetry key: %v", err)
+	}
+
+	return putPrivateWithReference(ctx, batteryID, collectionTelemetry, readingKey, reading)
+}
+
+// QueryTelemetry : 배터리의 원시 텔레메트리 조회 (텔레메트리 컬렉션 구성원 전용)
+func (s *PublicContract) QueryTelemetry(ctx contractapi.TransactionContextInterface, batteryID string) ([]TelemetryReading, error) {
+	references, err := readPrivateReferences(ctx, batteryID, collectionTelemetry)
+	if err != nil {
+		return nil, err
+	}
+
+	readings := []TelemetryReading{}
+	for _, reference := range references {
+		var reading TelemetryReading
+		if err := readPrivate(ctx, collectionTelemetry, reference.Key, &reading); err != nil {
+			return nil, err
+		}
+		readings = append(readings, reading)
+	}
+
+	return readings, nil
+}
+
+// QueryPrivateDataReferences : 자산에 연결된 프라이빗 데이터의 위치와 해시 조회 (누구나 조회 가능)
+func (s *PublicContract) QueryPrivateDataReferences(ctx contractapi.TransactionContextInterface, assetID string) ([]PrivateDataReference, error) {
+	return readPrivateReferences(ctx, assetID, "")
+}
+
+// VerifyPrivateData : 상대방이 공개한 값(저장된 JSON 그대로)이 원장의 프라이빗 데이터 해시와 일치하는지 검증
+// 컬렉션 구성원이 아니어도 GetPrivateDataHash로 해시를 확인할 수 있다.
+func (s *PublicContract) VerifyPrivateData(ctx contractapi.TransactionContextInterface, collection string, key string, disclosedValue string) (*PrivateDataVerification, error) {
+	onChainHash, err := ctx.GetStub().GetPrivateDataHash(collection, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private data hash from %s: %v", collection, err)
+	}
+	if onChainHash == nil {
+		return nil, fmt.Errorf("no private data hash for %s in %s", key, collection)
+	}
+
+	disclosedHash := sha256.Sum256([]byte(disclosedValue))
+	verification := &PrivateDataVerification{
+		Collection:    collection,
+		Key:           key,
+		OnChainHash:   hex.EncodeToString(onChainHash),
+		DisclosedHash: hex.EncodeToString(disclosedHash[:]),
+	}
+	verification.Matches = verification.OnChainHash == verification.DisclosedHash
+
+	return verification, nil
+}
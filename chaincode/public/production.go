@@ -0,0 +1,531 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeProductionOrder = "productionOrder"
+
+	// 생산 오더 기록 키 (productionOrder~오더ID)
+	productionOrderObjectType = "productionOrder"
+	// 모델별 마지막 일련번호 키 (serial~model~모델ID, 값은 10진수 문자열)
+	serialCounterObjectType = "serial~model"
+
+	// 한 트랜잭션에서 생산할 수 있는 최대 배터리 수 (읽기/쓰기 집합 크기 제한)
+	maxBatchUnits = 1000
+)
+
+// BatteryModel : 배터리 모델 사양 (일괄 생산 시 모든 배터리에 적용)
+type BatteryModel struct {
+	ModelID        string  `json:"modelID"` // 일련번호 접두어 (단건 생산은 빈 값)
+	Category       string  `json:"category"`
+	Weight         float64 `json:"weight"`
+	Capacity       float64 `json:"capacity"`
+	Voltage        float64 `json:"voltage"`
+	TotalLifeCycle int     `json:"totalLifeCycle"`
+}
+
+// ProductionOrder : 일괄 생산 기록 (BillOfMaterials는 배터리 한 개당 투입량, 로트 단위로 환산)
+type ProductionOrder struct {
+	DocType         string                       `json:"docType"`
+	OrderID         string                       `json:"orderID"`
+	Model           BatteryModel                 `json:"model"`
+	BillOfMaterials map[string]RawMaterialDetail `json:"billOfMaterials"`
+	Units           int                          `json:"units"`
+	BatteryIDs      []string                     `json:"batteryIDs"`
+	SerialNumbers   []string                     `json:"serialNumbers"`
+	Manufacturer    string                       `json:"manufacturer"` // 생산 조직 (MSP)
+	TxID            string                       `json:"txID"`
+	CreatedAt       string                       `json:"createdAt"`
+}
+
+// materialConsumption : BOM에 따라 로트를 차감한 결과 (배터리 제조에 사용)
+type materialConsumption struct {
+	rawMaterials   map[string]RawMaterialDetail // 배터리 한 개당 BOM (로트 단위로 환산)
+	lots           map[string]*RawMaterial      // 참조한 로트 (MaterialID 기준)
+	materialTotals map[string]int64             // 배터리 한 개당 원자재 종류별 투입량 (mg)
+	recycledTotals map[string]int64             // 배터리 한 개당 원자재 종류별 재활용 투입량 (mg)
+}
+
+// bomEntryFromLot : BOM 항목의 원자재 종류를 투입 로트와 대조하고 로트의 상태(NEW/RECYCLED)를 기록
+// 종류를 비워 두면 로트의 종류를 사용하고, 로트와 다른 종류를 신고하면 거부한다.
+func bomEntryFromLot(key string, detail RawMaterialDetail, lot *RawMaterial) (RawMaterialDetail, error) {
+	if detail.MaterialType == "" {
+		detail.MaterialType = lot.Name
+	}
+	if detail.MaterialType != lot.Name {
+		return RawMaterialDetail{}, fmt.Errorf("material type mismatch for %s: declared %s, but lot %s is %s", key, detail.MaterialType, lot.MaterialID, lot.Name)
+	}
+	detail.Status = lot.Status
+	return detail, nil
+}
+
+// consumeMaterials : 배터리 한 개당 BOM의 units배만큼 로트를 차감
+// 예약을 지정한 항목은 예약에서 소비하고, 같은 로트를 여러 항목에서 참조해도 로트는 한 번만 갱신한다.
+// 원자재 종류와 상태는 신고 값이 아니라 투입 로트를 기준으로 집계한다.
+func (s *PublicContract) consumeMaterials(ctx contractapi.TransactionContextInterface, caller *Caller, rawMaterials map[string]RawMaterialDetail, units int64) (*materialConsumption, error) {
+	consumption := &materialConsumption{
+		rawMaterials:   rawMaterials,
+		lots:           make(map[string]*RawMaterial),
+		materialTotals: make(map[string]int64),
+		recycledTotals: make(map[string]int64),
+	}
+
+	// 로트별 총 투입량과 예약 없이 투입하는 양 (로트 단위 milli)
+	needed := make(map[string]int64)
+	unreserved := make(map[string]int64)
+	// 한 BOM에서 같은 예약을 두 번 소비하지 않도록 추적
+	consumedReservations := make(map[string]bool)
+
+	// 모든 피어에서 같은 순서로 이벤트가 만들어지도록 원자재 키를 정렬하여 순회
+	detailKeys := make([]string, 0, len(rawMaterials))
+	for key := range rawMaterials {
+		detailKeys = append(detailKeys, key)
+	}
+	sort.Strings(detailKeys)
+
+	for _, key := range detailKeys {
+		materialDetail := rawMaterials[key]
+
+		// 원자재 ID로 원자재 조회 (같은 로트는 한 번만 조회)
+		rawMaterial, exists := consumption.lots[materialDetail.MaterialID]
+		if !exists {
+			queried, err := loadMaterial(ctx, materialDetail.MaterialID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query raw material: %v", err)
+			}
+			rawMaterial = queried
+			consumption.lots[materialDetail.MaterialID] = rawMaterial
+
+			// 다른 조직이 소유한 로트는 투입할 수 없음 (TransferLot으로 먼저 인수)
+			if err := requireLotOwner(caller, rawMaterial); err != nil {
+				return nil, err
+			}
+		}
+
+		// 원자재 종류와 상태는 로트 기준으로 기록 (재활용 함량 집계와 해체 물질 수지가 신고 값에 의존하지 않도록)
+		materialDetail, err := bomEntryFromLot(key, materialDetail, rawMaterial)
+		if err != nil {
+			return nil, err
+		}
+
+		// 투입량은 질량 단위여야 하며 로트 단위로 정확히 환산되어야 함 (BOM에는 로트 단위로 기록)
+		err = materialDetail.Quantity.validate(key)
+		if err != nil {
+			return nil, err
+		}
+		consumed, err := materialDetail.Quantity.convert(rawMaterial.Quantity.Unit)
+		if err != nil {
+			return nil, fmt.Errorf("unit mismatch for %s: %v", key, err)
+		}
+		materialDetail.Quantity = consumed
+		rawMaterials[key] = materialDetail
+
+		if consumed.Milli > math.MaxInt64/units {
+			return nil, fmt.Errorf("quantity for %s exceeds the supported range for %d units", key, units)
+		}
+		total := consumed.Milli * units
+		needed[materialDetail.MaterialID] += total
+
+		// 예약을 지정하면 예약에서 소비하고, 지정하지 않으면 다른 오더의 유효한 예약량은 사용할 수 없음
+		if materialDetail.ReservationID != "" {
+			if consumedReservations[materialDetail.ReservationID] {
+				return nil, fmt.Errorf("reservation %s is used more than once", materialDetail.ReservationID)
+			}
+			consumedReservations[materialDetail.ReservationID] = true
+
+			reservation, err := consumeReservation(ctx, caller, materialDetail.ReservationID, materialDetail.MaterialID, Quantity{Milli: total, Unit: consumed.Unit})
+			if err != nil {
+				return nil, err
+			}
+			err = emitEvent(ctx, EventReservationConsumed, eventAssetMaterial, rawMaterial.MaterialID, ReservationEventPayload{OrderID: reservation.OrderID, Reservations: []Reservation{*reservation}})
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			unreserved[materialDetail.MaterialID] += total
+		}
+
+		// 원자재의 총량과 재활용량을 계산
+		consumption.materialTotals[materialDetail.MaterialType] += consumed.milligrams()
+		if rawMaterial.Status == "RECYCLED" {
+			consumption.recycledTotals[materialDetail.MaterialType] += consumed.milligrams()
+		}
+	}
+
+	materialIDs := make([]string, 0, len(needed))
+	for materialID := range needed {
+		materialIDs = append(materialIDs, materialID)
+	}
+	sort.Strings(materialIDs)
+
+	for _, materialID := range materialIDs {
+		rawMaterial := consumption.lots[materialID]
+		required := Quantity{Milli: needed[materialID], Unit: rawMaterial.Quantity.Unit}
+
+		// 사용 가능한 수량 확인
+		if rawMaterial.Quantity.Milli < required.Milli {
+			return nil, fmt.Errorf("not enough quantity for material %s (needed: %s, available: %s)", materialID, required, rawMaterial.Quantity)
+		}
+		if unreserved[materialID] > 0 {
+			availability, err := materialAvailability(ctx, rawMaterial)
+			if err != nil {
+				return nil, err
+			}
+			if availability.Available.Milli < unreserved[materialID] {
+				return nil, fmt.Errorf("not enough unreserved quantity for material %s (needed: %s, available: %s)", materialID, Quantity{Milli: unreserved[materialID], Unit: required.Unit}, availability.Available)
+			}
+		}
+
+		// 사용된 수량 감소
+		rawMaterial.Quantity.Milli -= required.Milli
+
+		// 원자재 업데이트
+		err := s.saveMaterial(ctx, rawMaterial)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update raw material: %v", err)
+		}
+
+		err = emitEvent(ctx, EventMaterialConsumed, eventAssetMaterial, rawMaterial.MaterialID, MaterialEventPayload{Material: rawMaterial})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 고위험 원산지 로트는 실사 기준에 따라 거부하거나 투입 사유를 요구
+	dueDiligencePolicy, err := readDueDiligencePolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = checkHighRiskLots(rawMaterials, consumption.lots, dueDiligencePolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return consumption, nil
+}
+
+// manufactureBattery : 차감한 BOM으로 배터리 한 개를 생성하고 계보, 탄소발자국, 재생 원료 함량 평가와 함께 저장
+func (s *PublicContract) manufactureBattery(ctx contractapi.TransactionContextInterface, model *BatteryModel, consumption *materialConsumption, manufacturing *ManufacturingEmissions, orderID string, serialNumber string) (*Battery, error) {
+
+	// 배터리 정보 생성
+	batteryID, err := newID(ctx, "BATTERY")
+	if err != nil {
+		return nil, err
+	}
+	passportID, err := newID(ctx, "PASSPORT")
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	// 배터리마다 BOM을 따로 보관
+	rawMaterials := make(map[string]RawMaterialDetail, len(consumption.rawMaterials))
+	for key, detail := range consumption.rawMaterials {
+		rawMaterials[key] = detail
+	}
+
+	battery := Battery{
+		BatteryID:                batteryID,
+		PassportID:               passportID,
+		RawMaterials:             rawMaterials,
+		ManufacturerName:         "LG Energy Solution",
+		Owner:                    clientMSPID,
+		ContainsHazardous:        "Cadmium, Lithium, Nickel, Lead",
+		ManufactureDate:          now,
+		Weight:                   model.Weight,
+		Category:                 model.Category,
+		Voltage:                  model.Voltage,
+		Verified:                 "NOT VERIFIED",
+		Capacity:                 model.Capacity,
+		TotalLifeCycle:           model.TotalLifeCycle,
+		SOCE:                     100,
+		SOC:                      100,
+		SOH:                      100,
+		RemainingLifeCycle:       model.TotalLifeCycle,
+		RecyclingRatesByMaterial: make(map[string]float64),
+		ProductionOrderID:        orderID,
+		SerialNumber:             serialNumber,
+	}
+
+	// 재활용 비율을 계산하여 저장
+	for materialType, total := range consumption.materialTotals {
+		recycled := consumption.recycledTotals[materialType]
+		var rate float64
+		if total > 0 {
+			rate = (float64(recycled) / float64(total)) * 100
+		} else {
+			rate = 0
+		}
+		// 소수점 두 자리까지 반올림
+		rate = math.Round(rate*100) / 100
+		battery.RecyclingRatesByMaterial[materialType] = rate
+	}
+
+	// 제조 상태로 전이
+	err = transitionBattery(ctx, &battery, actionManufacture)
+	if err != nil {
+		return nil, err
+	}
+
+	// 공장 위치는 공개 문서에 남기지 않고, transient "plantDetails"(솔트는 "salt")로 전달되면 제조사 컬렉션에만 저장
+	hasPlantDetails, err := hasTransient(ctx, transientPlantDetails)
+	if err != nil {
+		return nil, err
+	}
+	if hasPlantDetails {
+		detailsAsBytes, err := readTransient(ctx, transientPlantDetails)
+		if err != nil {
+			return nil, err
+		}
+		details, err := parsePlantDetails(ctx, batteryID, detailsAsBytes)
+		if err != nil {
+			return nil, err
+		}
+		_, err = putPrivateWithReference(ctx, batteryID, collectionManufacturerPlant, batteryID, details)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 제조 조직을 첫 소유자로 기록
+	err = recordInitialCustody(ctx, &battery)
+	if err != nil {
+		return nil, err
+	}
+
+	// 원자재 로트 → 배터리 계보 기록
+	err = recordConsumption(ctx, batteryID, rawMaterials)
+	if err != nil {
+		return nil, err
+	}
+
+	// 소비한 로트의 배출 계수와 제조 에너지로 탄소발자국 계산
+	err = saveCarbonFootprint(ctx, newCarbonFootprint(&battery, consumption.lots, manufacturing))
+	if err != nil {
+		return nil, err
+	}
+
+	// 재생 원료 함량을 제조일 기준으로 시행 중인 최소 함량 기준과 비교
+	profile, err := readRecycledContentProfile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	compliance := evaluateRecycledContent(&battery, profile)
+	err = saveRecycledContentCompliance(ctx, compliance)
+	if err != nil {
+		return nil, err
+	}
+	battery.ComplianceStatus = compliance.Status
+
+	// 배터리 상태를 원장에 저장
+	err = s.saveBattery(ctx, &battery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store battery: %v", err)
+	}
+
+	err = emitEvent(ctx, EventBatteryCreated, eventAssetBattery, batteryID, BatteryEventPayload{Battery: &battery})
+	if err != nil {
+		return nil, err
+	}
+
+	return &battery, nil
+}
+
+// readProductionOrder : 생산 오더 조회 (없으면 nil)
+func readProductionOrder(ctx contractapi.TransactionContextInterface, orderID string) (*ProductionOrder, error) {
+	orderKey, err := ctx.GetStub().CreateCompositeKey(productionOrderObjectType, []string{orderID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create production order key: %v", err)
+	}
+
+	orderAsBytes, err := ctx.GetStub().GetState(orderKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read production order: %v", err)
+	}
+	if orderAsBytes == nil {
+		return nil, nil
+	}
+
+	order := new(ProductionOrder)
+	err = json.Unmarshal(orderAsBytes, order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal production order: %v", err)
+	}
+
+	return order, nil
+}
+
+// nextSerialNumbers : 모델의 마지막 일련번호 다음부터 count개의 일련번호를 발급 (예: MODEL-000001)
+func nextSerialNumbers(ctx contractapi.TransactionContextInterface, modelID string, count int) ([]string, error) {
+	counterKey, err := ctx.GetStub().CreateCompositeKey(serialCounterObjectType, []string{modelID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create serial counter key: %v", err)
+	}
+
+	counterAsBytes, err := ctx.GetStub().GetState(counterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read serial counter: %v", err)
+	}
+	last := 0
+	if counterAsBytes != nil {
+		last, err = strconv.Atoi(string(counterAsBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse serial counter of %s: %v", modelID, err)
+		}
+	}
+
+	serialNumbers := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		serialNumbers = append(serialNumbers, fmt.Sprintf("%s-%06d", modelID, last+i))
+	}
+
+	err = ctx.GetStub().PutState(counterKey, []byte(strconv.Itoa(last+count)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update serial counter: %v", err)
+	}
+
+	return serialNumbers, nil
+}
+
+// CreateBatteryBatch : 생산 오더 하나로 같은 모델의 배터리 units개를 생산
+// modelJSON은 모델 사양, billOfMaterialsJSON은 배터리 한 개당 BOM (CreateBattery와 같은 형식, 예약 ID 지정 가능)이며
+// 원자재는 units배만큼 한 번에 차감한다. 어느 하나라도 실패하면 트랜잭션 전체가 반영되지 않는다.
+func (s *PublicContract) CreateBatteryBatch(ctx contractapi.TransactionContextInterface, orderID string, modelJSON string, billOfMaterialsJSON string, units int, manufacturingEnergyJSON string) (*ProductionOrder, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "CreateBatteryBatch")
+	if err != nil {
+		return nil, err
+	}
+
+	orderID = strings.TrimSpace(orderID)
+	if orderID == "" {
+		return nil, fmt.Errorf("production order ID must not be empty")
+	}
+	existing, err := readProductionOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("production order already exists: %s", orderID)
+	}
+
+	if units < 1 || units > maxBatchUnits {
+		return nil, fmt.Errorf("units must be between 1 and %d: %d", maxBatchUnits, units)
+	}
+
+	var model BatteryModel
+	err = json.Unmarshal([]byte(modelJSON), &model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal battery model: %v", err)
+	}
+	model.ModelID = strings.TrimSpace(model.ModelID)
+	if model.ModelID == "" {
+		return nil, fmt.Errorf("modelID is required")
+	}
+	if model.TotalLifeCycle < 0 {
+		return nil, fmt.Errorf("total life cycle must not be negative: %d", model.TotalLifeCycle)
+	}
+
+	var billOfMaterials map[string]RawMaterialDetail
+	err = json.Unmarshal([]byte(billOfMaterialsJSON), &billOfMaterials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bill of materials: %v", err)
+	}
+	if len(billOfMaterials) == 0 {
+		return nil, fmt.Errorf("bill of materials must not be empty")
+	}
+
+	// 제조 공정 에너지 사용량과 배출 계수 (배터리 한 개당, 빈 문자열이면 제조 배출량 미신고)
+	manufacturing, err := parseManufacturingEmissions(manufacturingEnergyJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// 전체 생산량만큼 원자재를 한 번에 차감
+	consumption, err := s.consumeMaterials(ctx, caller, billOfMaterials, int64(units))
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumbers, err := nextSerialNumbers(ctx, model.ModelID, units)
+	if err != nil {
+		return nil, err
+	}
+
+	batteryIDs := make([]string, 0, units)
+	for _, serialNumber := range serialNumbers {
+		battery, err := s.manufactureBattery(ctx, &model, consumption, manufacturing, orderID, serialNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to manufacture battery %s: %v", serialNumber, err)
+		}
+		batteryIDs = append(batteryIDs, battery.BatteryID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order := &ProductionOrder{
+		DocType:         docTypeProductionOrder,
+		OrderID:         orderID,
+		Model:           model,
+		BillOfMaterials: billOfMaterials,
+		Units:           units,
+		BatteryIDs:      batteryIDs,
+		SerialNumbers:   serialNumbers,
+		Manufacturer:    caller.MSPID,
+		TxID:            ctx.GetStub().GetTxID(),
+		CreatedAt:       now.Format(time.RFC3339),
+	}
+
+	orderKey, err := ctx.GetStub().CreateCompositeKey(productionOrderObjectType, []string{orderID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create production order key: %v", err)
+	}
+	orderAsBytes, err := json.Marshal(order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal production order: %v", err)
+	}
+	err = ctx.GetStub().PutState(orderKey, orderAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store production order: %v", err)
+	}
+
+	err = emitEvent(ctx, EventBatteriesProduced, eventAssetBattery, orderID, ProductionOrderPayload{Order: order})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// QueryProductionOrder : 생산 오더와 생산된 배터리 ID, 일련번호 조회
+func (s *PublicContract) QueryProductionOrder(ctx contractapi.TransactionContextInterface, orderID string) (*ProductionOrder, error) {
+	order, err := readProductionOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, fmt.Errorf("production order not found: %s", orderID)
+	}
+
+	return order, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBOMEntryFromLot(t *testing.T) {
+	recycledCobalt := &RawMaterial{MaterialID: "MATERIAL-1", Name: "Cobalt", Status: "RECYCLED"}
+	newLithium := &RawMaterial{MaterialID: "MATERIAL-2", Name: "Lithium", Status: "NEW"}
+
+	tests := []struct {
+		name     string
+		detail   RawMaterialDetail
+		lot      *RawMaterial
+		wantType string
+		wantErr  string
+	}{
+		{name: "matching type", detail: RawMaterialDetail{MaterialID: "MATERIAL-1", MaterialType: "Cobalt"}, lot: recycledCobalt, wantType: "Cobalt"},
+		{name: "type taken from lot", detail: RawMaterialDetail{MaterialID: "MATERIAL-2"}, lot: newLithium, wantType: "Lithium"},
+		// 재활용 코발트 로트를 리튬으로 신고하여 리튬 재생 원료 함량을 채우는 경우
+		{name: "recycled lot declared as another type", detail: RawMaterialDetail{MaterialID: "MATERIAL-1", MaterialType: "Lithium"}, lot: recycledCobalt, wantErr: "material type mismatch"},
+		{name: "case differs from lot", detail: RawMaterialDetail{MaterialID: "MATERIAL-2", MaterialType: "lithium"}, lot: newLithium, wantErr: "material type mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bomEntryFromLot("material1", tt.detail, tt.lot)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.MaterialType != tt.wantType {
+				t.Errorf("material type %s, want %s", got.MaterialType, tt.wantType)
+			}
+			if got.Status != tt.lot.Status {
+				t.Errorf("status %s, want lot status %s", got.Status, tt.lot.Status)
+			}
+		})
+	}
+}
+
+// 신고한 상태는 무시하고 로트의 상태를 기록해야 함
+func TestBOMEntryFromLotIgnoresDeclaredStatus(t *testing.T) {
+	lot := &RawMaterial{MaterialID: "MATERIAL-1", Name: "Nickel", Status: "NEW"}
+	got, err := bomEntryFromLot("material1", RawMaterialDetail{MaterialID: "MATERIAL-1", MaterialType: "Nickel", Status: "RECYCLED"}, lot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "NEW" {
+		t.Errorf("status %s, want NEW", got.Status)
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 질량 단위
+const (
+	unitGram     = "g"
+	unitKilogram = "kg"
+	unitTonne    = "t"
+)
+
+// 단위 도입 이전 문서의 수량(정수)은 kg으로 간주
+const legacyQuantityUnit = unitKilogram
+
+// quantityScale : 고정 소수점 자릿수 (Milli 1 = 단위의 1/1000)
+const quantityScale = 3
+
+// massUnitMilligrams : 단위별로 Milli 1이 나타내는 질량 (mg)
+// g ↔ kg ↔ t 환산은 모두 mg 정수를 거치므로 오차가 없다.
+var massUnitMilligrams = map[string]int64{
+	unitGram:     1,
+	unitKilogram: 1000,
+	unitTonne:    1000000,
+}
+
+// Quantity : 단위가 있는 고정 소수점 수량
+// Milli는 단위의 1/1000을 1로 하는 정수이다 (12.5kg → {"milli": 12500, "unit": "kg"}).
+// 입력 JSON은 객체 외에 "12.5 kg" 형식의 문자열도 받는다.
+// 숫자만 있는 값(단위 도입 이전 문서)은 단위가 빈 값으로 읽히며, 원장 문서를 읽을 때 kg으로 간주한다.
+type Quantity struct {
+	Milli int64  `json:"milli"`
+	Unit  string `json:"unit"`
+}
+
+// UnmarshalJSON : 객체, "12.5 kg" 문자열, 단위 없는 숫자를 모두 받음
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		*q = Quantity{}
+		return nil
+	}
+
+	switch trimmed[0] {
+	case '{':
+		type plainQuantity Quantity
+		var plain plainQuantity
+		if err := json.Unmarshal(trimmed, &plain); err != nil {
+			return err
+		}
+		*q = Quantity(plain)
+		return nil
+	case '"':
+		var text string
+		if err := json.Unmarshal(trimmed, &text); err != nil {
+			return err
+		}
+		parsed, err := parseQuantity(text)
+		if err != nil {
+			return err
+		}
+		*q = parsed
+		return nil
+	default:
+		milli, err := parseFixedPoint(string(trimmed))
+		if err != nil {
+			return err
+		}
+		*q = Quantity{Milli: milli}
+		return nil
+	}
+}
+
+// String : "12.5 kg" 형식
+func (q Quantity) String() string {
+	integer := q.Milli / 1000
+	fraction := q.Milli % 1000
+	if fraction < 0 {
+		fraction = -fraction
+	}
+
+	text := strconv.FormatInt(integer, 10)
+	if q.Milli < 0 && integer == 0 {
+		text = "-0"
+	}
+	if fraction != 0 {
+		text += "." + strings.TrimRight(fmt.Sprintf("%03d", fraction), "0")
+	}
+	if q.Unit == "" {
+		return text
+	}
+	return text + " " + q.Unit
+}
+
+// parseFixedPoint : 소수점 셋째 자리까지의 음이 아닌 십진수를 Milli 정수로 변환
+func parseFixedPoint(text string) (int64, error) {
+	text = strings.TrimSpace(text)
+	integerPart, fractionPart := text, ""
+	if dot := strings.IndexByte(text, '.'); dot >= 0 {
+		integerPart, fractionPart = text[:dot], text[dot+1:]
+	}
+	if integerPart == "" && fractionPart == "" {
+		return 0, fmt.Errorf("invalid quantity %q", text)
+	}
+	if integerPart == "" {
+		integerPart = "0"
+	}
+	if len(fractionPart) > quantityScale {
+		return 0, fmt.Errorf("quantity %q has more than %d decimal places", text, quantityScale)
+	}
+	for _, digits := range []string{integerPart, fractionPart} {
+		for _, digit := range digits {
+			if digit < '0' || digit > '9' {
+				return 0, fmt.Errorf("invalid quantity %q: expected a non-negative decimal", text)
+			}
+		}
+	}
+
+	fraction := int64(0)
+	if fractionPart != "" {
+		fraction, _ = strconv.ParseInt(fractionPart+strings.Repeat("0", quantityScale-len(fractionPart)), 10, 64)
+	}
+	integer, err := strconv.ParseInt(integerPart, 10, 64)
+	if err != nil || integer > (math.MaxInt64-fraction)/1000 {
+		return 0, fmt.Errorf("quantity %q is too large", text)
+	}
+
+	return integer*1000 + fraction, nil
+}
+
+// parseQuantity : "12.5 kg", "12.5kg" 형식의 수량 해석 (단위 필수)
+func parseQuantity(text string) (Quantity, error) {
+	text = strings.TrimSpace(text)
+	split := strings.IndexFunc(text, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if split < 0 {
+		return Quantity{}, fmt.Errorf("quantity %q has no unit: expected one of g, kg, t", text)
+	}
+
+	milli, err := parseFixedPoint(text[:split])
+	if err != nil {
+		return Quantity{}, err
+	}
+	quantity := Quantity{Milli: milli, Unit: strings.TrimSpace(text[split:])}
+	if err := quantity.validate(text); err != nil {
+		return Quantity{}, err
+	}
+
+	return quantity, nil
+}
+
+// wholeQuantity : 정수 값의 수량 (초기 데이터용)
+func wholeQuantity(value int64, unit string) Quantity {
+	return Quantity{Milli: value * 1000, Unit: unit}
+}
+
+// validate : 질량 단위인지, 음수가 아닌지, mg으로 환산할 수 있는 범위인지 확인
+func (q Quantity) validate(subject string) error {
+	if q.Unit == "" {
+		return fmt.Errorf("quantity for %s has no unit: expected one of g, kg, t", subject)
+	}
+	factor, ok := massUnitMilligrams[q.Unit]
+	if !ok {
+		return fmt.Errorf("unit mismatch for %s: %q is not a mass unit (expected one of g, kg, t)", subject, q.Unit)
+	}
+	if q.Milli < 0 {
+		return fmt.Errorf("quantity for %s must not be negative: %s", subject, q)
+	}
+	if q.Milli > math.MaxInt64/factor {
+		return fmt.Errorf("quantity for %s is too large: %s", subject, q)
+	}
+	return nil
+}
+
+// assumeLegacyUnit : 단위 도입 이전 문서의 수량은 kg으로 간주
+func (q *Quantity) assumeLegacyUnit() {
+	if q.Unit == "" {
+		q.Unit = legacyQuantityUnit
+	}
+}
+
+// assumeLegacyDetailUnits : 배터리 BOM 항목 중 단위가 없는 투입량을 kg으로 간주
+func assumeLegacyDetailUnits(details map[string]RawMaterialDetail) {
+	for key, detail := range details {
+		if detail.Quantity.Unit == "" {
+			detail.Quantity.assumeLegacyUnit()
+			details[key] = detail
+		}
+	}
+}
+
+// milligrams : mg 단위 정수 (검증된 수량 기준, 알 수 없는 단위는 0)
+func (q Quantity) milligrams() int64 {
+	return q.Milli * massUnitMilligrams[q.Unit]
+}
+
+// kilograms : kg 단위 값 (보고용)
+func (q Quantity) kilograms() float64 {
+	return float64(q.milligrams()) / 1e6
+}
+
+// milligramsToKilograms : mg 합계를 kg 값으로 (보고용)
+func milligramsToKilograms(milligrams int64) float64 {
+	return float64(milligrams) / 1e6
+}
+
+// quantityFromMilligrams : mg 값을 주어진 단위로 표현 (소수점 셋째 자리로 나누어떨어지지 않으면 오류)
+func quantityFromMilligrams(milligrams int64, unit string) (Quantity, error) {
+	factor, ok := massUnitMilligrams[unit]
+	if !ok {
+		return Quantity{}, fmt.Errorf("%q is not a mass unit (expected one of g, kg, t)", unit)
+	}
+	if milligrams%factor != 0 {
+		return Quantity{}, fmt.Errorf("%s cannot be expressed in %s with %d decimal places", Quantity{Milli: milligrams, Unit: unitGram}, unit, quantityScale)
+	}
+	return Quantity{Milli: milligrams / factor, Unit: unit}, nil
+}
+
+// gramsQuantity : mg 값을 g 단위로 표현 (항상 정확)
+func gramsQuantity(milligrams int64) Quantity {
+	return Quantity{Milli: milligrams, Unit: unitGram}
+}
+
+// convert : 다른 질량 단위로 환산 (정밀도를 잃으면 오류)
+func (q Quantity) convert(unit string) (Quantity, error) {
+	if q.Unit == unit {
+		return q, nil
+	}
+	return quantityFromMilligrams(q.milligrams(), unit)
+}
+
+// sumQuantities : 수량 합계 (단위가 모두 같으면 그 단위, 섞여 있으면 g)
+func sumQuantities(quantities ...Quantity) Quantity {
+	if len(quantities) == 0 {
+		return Quantity{Unit: legacyQuantityUnit}
+	}
+
+	unit := quantities[0].Unit
+	var milligrams int64
+	for _, quantity := range quantities {
+		if quantity.Unit != unit {
+			unit = ""
+		}
+		milligrams += quantity.milligrams()
+	}
+
+	if unit != "" {
+		if sum, err := quantityFromMilligrams(milligrams, unit); err == nil {
+			return sum
+		}
+	}
+	return gramsQuantity(milligrams)
+}
+
+// MigrateQuantities : 단위 도입 이전 문서의 정수 수량을 kg 단위 수량으로 변환
+// 원자재 로트, 배터리 BOM, 계보 간선, 해체 물질 수지 기록을 대상으로 한다.
+func (s *PublicContract) MigrateQuantities(ctx contractapi.TransactionContextInterface) (int, error) {
+	if _, err := authorize(ctx, "MigrateQuantities"); err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+
+	// 원자재 로트 (readMaterialState는 단위를 보정하므로 저장된 값을 직접 확인)
+	materials, err := readLegacyQuantityDocuments(ctx, indexMaterialByStatus)
+	if err != nil {
+		return migrated, err
+	}
+	for _, materialID := range materials {
+		material, err := readStoredMaterial(ctx, materialID)
+		if err != nil {
+			return migrated, err
+		}
+		if material == nil {
+			continue
+		}
+		if err := s.saveMaterial(ctx, material); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	// 배터리 BOM
+	batteries, err := readLegacyQuantityDocuments(ctx, indexBatteryByStatus)
+	if err != nil {
+		return migrated, err
+	}
+	for _, batteryID := range batteries {
+		battery, err := readUpgradedBattery(ctx, batteryID)
+		if err != nil {
+			return migrated, err
+		}
+		if battery == nil {
+			continue
+		}
+		if err := s.saveBattery(ctx, battery); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	// 계보 간선 (하류 키를 기준으로 상류 키도 함께 다시 저장)
+	edges, err := readLineageEdgesByType(ctx, lineageDownObjectType)
+	if err != nil {
+		return migrated, err
+	}
+	for _, edge := range edges {
+		if edge.Quantity.Unit != "" {
+			continue
+		}
+		edge.Quantity.assumeLegacyUnit()
+		if err := putLineageEdge(ctx, edge); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	// 해체 물질 수지 기록
+	records, err := readStoredExtractionRecords(ctx)
+	if err != nil {
+		return migrated, err
+	}
+	for _, record := range records {
+		if !record.hasLegacyQuantities() {
+			continue
+		}
+		record.assumeLegacyUnits()
+		if err := saveExtractionRecord(ctx, record); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	err = emitEvent(ctx, EventMigrationCompleted, eventAssetConfig, "quantities", MigrationCompletedPayload{Migration: "MigrateQuantities", Migrated: migrated})
+	if err != nil {
+		return migrated, err
+	}
+
+	return migrated, nil
+}
+
+// readLegacyQuantityDocuments : 인덱스 파티션의 문서 중 단위 없는 수량이 있는 문서 ID (원자재 quantity 또는 배터리 BOM)
+func readLegacyQuantityDocuments(ctx contractapi.TransactionContextInterface, index string) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index %s: %v", index, err)
+	}
+	defer resultsIterator.Close()
+
+	ids := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split index key: %v", err)
+		}
+		id := keyParts[len(keyParts)-1]
+
+		documentAsBytes, err := ctx.GetStub().GetState(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", id, err)
+		}
+		var stored struct {
+			Quantity *Quantity `json:"quantity"`
+			Details  map[string]struct {
+				Quantity Quantity `json:"quantity"`
+			} `json:"rawMaterials"`
+		}
+		if documentAsBytes == nil || json.Unmarshal(documentAsBytes, &stored) != nil {
+			continue
+		}
+
+		legacy := stored.Quantity != nil && stored.Quantity.Unit == ""
+		for _, detail := range stored.Details {
+			if detail.Quantity.Unit == "" {
+				legacy = true
+			}
+		}
+		if legacy {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
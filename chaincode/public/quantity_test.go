@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    Quantity
+		wantErr string
+	}{
+		{text: "12.5 kg", want: Quantity{Milli: 12500, Unit: unitKilogram}},
+		{text: "12.5kg", want: Quantity{Milli: 12500, Unit: unitKilogram}},
+		{text: " 0.001 t ", want: Quantity{Milli: 1, Unit: unitTonne}},
+		{text: "750 g", want: Quantity{Milli: 750000, Unit: unitGram}},
+		{text: ".5 kg", want: Quantity{Milli: 500, Unit: unitKilogram}},
+		{text: "3. kg", want: Quantity{Milli: 3000, Unit: unitKilogram}},
+		{text: "0 g", want: Quantity{Milli: 0, Unit: unitGram}},
+		{text: "12.5", wantErr: "no unit"},
+		{text: "12.5 lb", wantErr: "not a mass unit"},
+		{text: "12.5 L", wantErr: "not a mass unit"},
+		{text: "kg", wantErr: "invalid quantity"},
+		{text: "-1 kg", wantErr: "invalid quantity"},
+		{text: "1.2.3 kg", wantErr: "invalid quantity"},
+		// 소수점 넷째 자리는 반올림하지 않고 거부
+		{text: "1.2345 kg", wantErr: "more than 3 decimal places"},
+		{text: "0.0001 t", wantErr: "more than 3 decimal places"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseQuantity(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFixedPointOverflow(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    int64
+		wantErr bool
+	}{
+		{text: "9223372036854775.807", want: math.MaxInt64},
+		{text: "9223372036854775", want: 9223372036854775000},
+		{text: "9223372036854775.808", wantErr: true},
+		{text: "9223372036854776", wantErr: true},
+		{text: "99999999999999999999", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseFixedPoint(tt.text)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %d", tt.text, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %d, %v, want %d", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestQuantityValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity Quantity
+		wantErr  string
+	}{
+		{name: "kg", quantity: Quantity{Milli: 1000, Unit: unitKilogram}},
+		{name: "no unit", quantity: Quantity{Milli: 1000}, wantErr: "no unit"},
+		{name: "unknown unit", quantity: Quantity{Milli: 1000, Unit: "lb"}, wantErr: "not a mass unit"},
+		{name: "negative", quantity: Quantity{Milli: -1, Unit: unitGram}, wantErr: "must not be negative"},
+		// mg 환산 시 int64 범위를 넘는 값
+		{name: "largest tonne", quantity: Quantity{Milli: math.MaxInt64 / 1000000, Unit: unitTonne}},
+		{name: "tonne overflow", quantity: Quantity{Milli: math.MaxInt64/1000000 + 1, Unit: unitTonne}, wantErr: "too large"},
+		{name: "kilogram overflow", quantity: Quantity{Milli: math.MaxInt64/1000 + 1, Unit: unitKilogram}, wantErr: "too large"},
+		{name: "largest gram", quantity: Quantity{Milli: math.MaxInt64, Unit: unitGram}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.quantity.validate("lot")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuantityConvert(t *testing.T) {
+	tests := []struct {
+		from    Quantity
+		unit    string
+		want    Quantity
+		wantErr bool
+	}{
+		{from: Quantity{Milli: 1500, Unit: unitKilogram}, unit: unitGram, want: Quantity{Milli: 1500000, Unit: unitGram}},
+		// 1.5 kg = 0.0015 t 는 소수점 셋째 자리로 표현할 수 없음
+		{from: Quantity{Milli: 1500, Unit: unitKilogram}, unit: unitTonne, wantErr: true},
+		{from: Quantity{Milli: 1000, Unit: unitGram}, unit: unitTonne, wantErr: true},
+		{from: Quantity{Milli: 2000, Unit: unitKilogram}, unit: unitTonne, want: Quantity{Milli: 2, Unit: unitTonne}},
+		{from: Quantity{Milli: 1, Unit: unitTonne}, unit: unitKilogram, want: Quantity{Milli: 1000, Unit: unitKilogram}},
+		{from: Quantity{Milli: 1, Unit: unitTonne}, unit: unitGram, want: Quantity{Milli: 1000000, Unit: unitGram}},
+		{from: Quantity{Milli: 250, Unit: unitGram}, unit: unitKilogram, wantErr: true},
+		{from: Quantity{Milli: 250000, Unit: unitGram}, unit: unitKilogram, want: Quantity{Milli: 250, Unit: unitKilogram}},
+		{from: Quantity{Milli: 42, Unit: unitGram}, unit: unitGram, want: Quantity{Milli: 42, Unit: unitGram}},
+		{from: Quantity{Milli: 1000, Unit: unitKilogram}, unit: "lb", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.from.convert(tt.unit)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s → %s: expected error, got %s", tt.from, tt.unit, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s → %s: %v", tt.from, tt.unit, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s → %s: got %+v, want %+v", tt.from, tt.unit, got, tt.want)
+		}
+		// 정확한 환산은 되돌려도 같은 질량이어야 함
+		if got.milligrams() != tt.from.milligrams() {
+			t.Errorf("%s → %s: %d mg, want %d mg", tt.from, tt.unit, got.milligrams(), tt.from.milligrams())
+		}
+	}
+}
+
+func TestQuantityString(t *testing.T) {
+	tests := []struct {
+		quantity Quantity
+		want     string
+	}{
+		{Quantity{Milli: 12500, Unit: unitKilogram}, "12.5 kg"},
+		{Quantity{Milli: 12000, Unit: unitKilogram}, "12 kg"},
+		{Quantity{Milli: 1, Unit: unitTonne}, "0.001 t"},
+		{Quantity{Milli: 1010, Unit: unitGram}, "1.01 g"},
+		{Quantity{Milli: 90000}, "90"},
+		{Quantity{Milli: -500, Unit: unitGram}, "-0.5 g"},
+	}
+	for _, tt := range tests {
+		if got := tt.quantity.String(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.quantity, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    Quantity
+		wantErr bool
+	}{
+		{data: `{"milli":12500,"unit":"kg"}`, want: Quantity{Milli: 12500, Unit: unitKilogram}},
+		{data: `"12.5 kg"`, want: Quantity{Milli: 12500, Unit: unitKilogram}},
+		// 단위 도입 이전 문서의 정수 수량은 단위 없이 읽힘
+		{data: `90`, want: Quantity{Milli: 90000}},
+		{data: `2.5`, want: Quantity{Milli: 2500}},
+		{data: `null`, want: Quantity{}},
+		{data: `"12.5"`, wantErr: true},
+		{data: `-3`, wantErr: true},
+		{data: `1.2345`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Quantity
+		err := json.Unmarshal([]byte(tt.data), &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %+v", tt.data, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %+v, %v, want %+v", tt.data, got, err, tt.want)
+		}
+	}
+}
+
+func TestAssumeLegacyUnit(t *testing.T) {
+	var material struct {
+		Quantity Quantity                     `json:"quantity"`
+		Details  map[string]RawMaterialDetail `json:"rawMaterials"`
+	}
+	data := `{"quantity":90,"rawMaterials":{"material1":{"quantity":10},"material2":{"quantity":"500 g"}}}`
+	if err := json.Unmarshal([]byte(data), &material); err != nil {
+		t.Fatal(err)
+	}
+
+	material.Quantity.assumeLegacyUnit()
+	if want := (Quantity{Milli: 90000, Unit: unitKilogram}); material.Quantity != want {
+		t.Errorf("lot quantity: got %+v, want %+v", material.Quantity, want)
+	}
+	if material.Quantity.milligrams() != 90000000 {
+		t.Errorf("lot quantity: %d mg, want 90 kg", material.Quantity.milligrams())
+	}
+
+	assumeLegacyDetailUnits(material.Details)
+	if want := (Quantity{Milli: 10000, Unit: unitKilogram}); material.Details["material1"].Quantity != want {
+		t.Errorf("legacy detail: got %+v, want %+v", material.Details["material1"].Quantity, want)
+	}
+	// 단위가 있는 항목은 그대로 유지
+	if want := (Quantity{Milli: 500000, Unit: unitGram}); material.Details["material2"].Quantity != want {
+		t.Errorf("detail with unit: got %+v, want %+v", material.Details["material2"].Quantity, want)
+	}
+
+	explicit := Quantity{Milli: 7, Unit: unitTonne}
+	explicit.assumeLegacyUnit()
+	if explicit.Unit != unitTonne {
+		t.Errorf("explicit unit changed to %s", explicit.Unit)
+	}
+}
+
+func TestSumQuantities(t *testing.T) {
+	tests := []struct {
+		name       string
+		quantities []Quantity
+		want       Quantity
+	}{
+		{name: "empty", want: Quantity{Unit: unitKilogram}},
+		{name: "same unit", quantities: []Quantity{{Milli: 1500, Unit: unitKilogram}, {Milli: 2500, Unit: unitKilogram}}, want: Quantity{Milli: 4000, Unit: unitKilogram}},
+		{name: "mixed units in grams", quantities: []Quantity{{Milli: 1000, Unit: unitKilogram}, {Milli: 250000, Unit: unitGram}}, want: Quantity{Milli: 1250000, Unit: unitGram}},
+		{name: "tonne and kilogram", quantities: []Quantity{{Milli: 1, Unit: unitTonne}, {Milli: 1, Unit: unitKilogram}}, want: Quantity{Milli: 1001000, Unit: unitGram}},
+	}
+	for _, tt := range tests {
+		if got := sumQuantities(tt.quantities...); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
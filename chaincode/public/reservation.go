@@ -0,0 +1,457 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeReservation = "reservation"
+
+	// 예약 기록 키 (reservation~예약ID)
+	reservationObjectType = "reservation"
+
+	// 예약 보조 인덱스 (값은 indexValue)
+	indexReservationByLot    = "reservation~lot~id"
+	indexReservationByOrder  = "reservation~order~id"
+	indexReservationByStatus = "reservation~status~id"
+)
+
+// 예약 상태 (EXPIRED는 만료 시각이 지난 ACTIVE 예약을 조회 시점에 평가한 값이기도 하다)
+const (
+	reservationActive   = "ACTIVE"
+	reservationConsumed = "CONSUMED"
+	reservationReleased = "RELEASED"
+	reservationExpired  = "EXPIRED"
+)
+
+// Reservation : 생산 오더를 위한 원자재 로트 예약 (수량은 로트 단위)
+type Reservation struct {
+	DocType       string   `json:"docType"`
+	ReservationID string   `json:"reservationID"`
+	OrderID       string   `json:"orderID"`
+	MaterialID    string   `json:"materialID"`
+	Quantity      Quantity `json:"quantity"`
+	Consumed      Quantity `json:"consumed"`
+	Status        string   `json:"status"`
+	ReservedBy    string   `json:"reservedBy"` // 예약 조직 (MSP)
+	ExpiresAt     string   `json:"expiresAt"`
+	CreatedAt     string   `json:"createdAt"`
+	TxID          string   `json:"txID"`
+	ReleasedAt    string   `json:"releasedAt"`
+	ReleaseReason string   `json:"releaseReason"`
+}
+
+// MaterialAvailability : 로트의 보유량, 유효 예약량, 가용량 (가용량 = 보유량 - 유효 예약량)
+type MaterialAvailability struct {
+	MaterialID   string        `json:"materialID"`
+	OnHand       Quantity      `json:"onHand"`
+	Reserved     Quantity      `json:"reserved"`
+	Available    Quantity      `json:"available"`
+	Reservations []Reservation `json:"reservations"`
+}
+
+// remaining : 아직 소비되지 않은 예약 수량
+func (r *Reservation) remaining() Quantity {
+	return Quantity{Milli: r.Quantity.Milli - r.Consumed.Milli, Unit: r.Quantity.Unit}
+}
+
+// isActiveAt : 기준 시각에 가용량을 묶어 두는 예약인지 (ACTIVE이고 만료 전)
+func (r *Reservation) isActiveAt(now time.Time) bool {
+	if r.Status != reservationActive {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, r.ExpiresAt)
+	return err == nil && now.Before(expiresAt)
+}
+
+func reservationIndexEntries(reservation *Reservation) []indexEntry {
+	if reservation == nil {
+		return nil
+	}
+	return []indexEntry{
+		{indexReservationByLot, []string{reservation.MaterialID, reservation.ReservationID}},
+		{indexReservationByOrder, []string{reservation.OrderID, reservation.ReservationID}},
+		{indexReservationByStatus, []string{reservation.Status, reservation.ReservationID}},
+	}
+}
+
+// readStoredReservation : 저장된 그대로의 예약 (없으면 nil)
+func readStoredReservation(ctx contractapi.TransactionContextInterface, reservationID string) (*Reservation, error) {
+	reservationKey, err := ctx.GetStub().CreateCompositeKey(reservationObjectType, []string{reservationID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reservation key: %v", err)
+	}
+
+	reservationAsBytes, err := ctx.GetStub().GetState(reservationKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reservation: %v", err)
+	}
+	if reservationAsBytes == nil {
+		return nil, nil
+	}
+
+	reservation := new(Reservation)
+	err = json.Unmarshal(reservationAsBytes, reservation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reservation: %v", err)
+	}
+
+	return reservation, nil
+}
+
+// readReservation : 예약 조회 (만료된 ACTIVE 예약은 EXPIRED로 표시)
+func readReservation(ctx contractapi.TransactionContextInterface, reservationID string) (*Reservation, error) {
+	reservation, err := readStoredReservation(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if reservation == nil {
+		return nil, fmt.Errorf("reservation not found: %s", reservationID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if reservation.Status == reservationActive && !reservation.isActiveAt(now) {
+		reservation.Status = reservationExpired
+	}
+
+	return reservation, nil
+}
+
+// saveReservation : 예약을 저장하고 보조 인덱스를 갱신
+func saveReservation(ctx contractapi.TransactionContextInterface, reservation *Reservation) error {
+	reservation.DocType = docTypeReservation
+
+	previous, err := readStoredReservation(ctx, reservation.ReservationID)
+	if err != nil {
+		return err
+	}
+
+	reservationKey, err := ctx.GetStub().CreateCompositeKey(reservationObjectType, []string{reservation.ReservationID})
+	if err != nil {
+		return fmt.Errorf("failed to create reservation key: %v", err)
+	}
+	reservationAsBytes, err := json.Marshal(reservation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reservation: %v", err)
+	}
+	err = ctx.GetStub().PutState(reservationKey, reservationAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store reservation: %v", err)
+	}
+
+	return updateIndexes(ctx, reservationIndexEntries(previous), reservationIndexEntries(reservation))
+}
+
+// queryReservationsByIndex : 인덱스 파티션에 속한 예약 조회 (만료된 ACTIVE 예약은 EXPIRED로 표시)
+func queryReservationsByIndex(ctx contractapi.TransactionContextInterface, index string, attributes ...string) ([]Reservation, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index %s: %v", index, err)
+	}
+	defer resultsIterator.Close()
+
+	reservations := []Reservation{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split index key: %v", err)
+		}
+
+		reservation, err := readReservation(ctx, keyParts[len(keyParts)-1])
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, *reservation)
+	}
+
+	return reservations, nil
+}
+
+// materialAvailability : 로트의 가용량 계산 (만료 전 ACTIVE 예약의 남은 수량만 예약량에 포함)
+func materialAvailability(ctx contractapi.TransactionContextInterface, material *RawMaterial) (*MaterialAvailability, error) {
+	reservations, err := queryReservationsByIndex(ctx, indexReservationByLot, material.MaterialID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reservedMg int64
+	for i := range reservations {
+		reservation := &reservations[i]
+		if reservation.Status != reservationActive {
+			continue
+		}
+		reservedMg += reservation.remaining().milligrams()
+	}
+
+	reserved, err := quantityFromMilligrams(reservedMg, material.Quantity.Unit)
+	if err != nil {
+		return nil, err
+	}
+	available := Quantity{Milli: material.Quantity.Milli - reserved.Milli, Unit: material.Quantity.Unit}
+	if available.Milli < 0 {
+		available.Milli = 0
+	}
+
+	return &MaterialAvailability{
+		MaterialID:   material.MaterialID,
+		OnHand:       material.Quantity,
+		Reserved:     reserved,
+		Available:    available,
+		Reservations: reservations,
+	}, nil
+}
+
+// consumeReservation : CreateBattery에서 BOM 항목의 예약을 소비 (예약한 조직만, 유효한 예약의 남은 수량 이내)
+func consumeReservation(ctx contractapi.TransactionContextInterface, caller *Caller, reservationID string, materialID string, consumed Quantity) (*Reservation, error) {
+	reservation, err := readReservation(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if reservation.MaterialID != materialID {
+		return nil, fmt.Errorf("reservation %s is for lot %s, not %s", reservationID, reservation.MaterialID, materialID)
+	}
+	if reservation.Status != reservationActive {
+		return nil, fmt.Errorf("reservation %s is not active: %s", reservationID, reservation.Status)
+	}
+	if reservation.ReservedBy != caller.MSPID {
+		return nil, fmt.Errorf("reservation %s belongs to %s", reservationID, reservation.ReservedBy)
+	}
+	if consumed.Milli > reservation.remaining().Milli {
+		return nil, fmt.Errorf("consumed quantity %s exceeds reservation %s (remaining: %s)", consumed, reservationID, reservation.remaining())
+	}
+
+	reservation.Consumed.Milli += consumed.Milli
+	if reservation.remaining().Milli == 0 {
+		reservation.Status = reservationConsumed
+	}
+	if err := saveReservation(ctx, reservation); err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// ReserveMaterials : 생산 오더를 위해 로트별 수량을 만료 시각까지 예약
+// reservationsJSON은 로트별 수량 (예: {"MATERIAL-...": "20 kg"}), 예약 수량은 로트의 가용량을 넘을 수 없다.
+func (s *PublicContract) ReserveMaterials(ctx contractapi.TransactionContextInterface, orderID string, reservationsJSON string, expiresAt string) ([]Reservation, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "ReserveMaterials")
+	if err != nil {
+		return nil, err
+	}
+
+	orderID = strings.TrimSpace(orderID)
+	if orderID == "" {
+		return nil, fmt.Errorf("production order ID must not be empty")
+	}
+
+	var requested map[string]Quantity
+	err = json.Unmarshal([]byte(reservationsJSON), &requested)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reservations: %v", err)
+	}
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("reservation requires at least one lot")
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	expiry, err := parseRecordDate(expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !expiry.After(now) {
+		return nil, fmt.Errorf("reservation expiry must be later than the transaction time")
+	}
+
+	// 같은 오더에서 이미 유효한 예약이 있는 로트는 다시 예약할 수 없음
+	existing, err := queryReservationsByIndex(ctx, indexReservationByOrder, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 모든 피어에서 같은 순서로 예약 ID가 만들어지도록 로트 ID를 정렬하여 순회
+	materialIDs := make([]string, 0, len(requested))
+	for materialID := range requested {
+		materialIDs = append(materialIDs, materialID)
+	}
+	sort.Strings(materialIDs)
+
+	reservations := []Reservation{}
+	for _, materialID := range materialIDs {
+		for _, reservation := range existing {
+			if reservation.MaterialID == materialID && reservation.Status == reservationActive {
+				return nil, fmt.Errorf("order %s already has an active reservation %s for lot %s", orderID, reservation.ReservationID, materialID)
+			}
+		}
+
+		material, err := s.QueryMaterial(ctx, materialID)
+		if err != nil {
+			return nil, err
+		}
+		if err := requireLotOwner(caller, material); err != nil {
+			return nil, err
+		}
+		if material.Availability != availabilityAvailable {
+			return nil, fmt.Errorf("lot %s is not available: %s", materialID, material.Availability)
+		}
+
+		quantity := requested[materialID]
+		if err := quantity.validate(materialID); err != nil {
+			return nil, err
+		}
+		quantity, err = quantity.convert(material.Quantity.Unit)
+		if err != nil {
+			return nil, fmt.Errorf("unit mismatch for %s: %v", materialID, err)
+		}
+		if quantity.Milli == 0 {
+			return nil, fmt.Errorf("reserved quantity for %s must be greater than 0", materialID)
+		}
+
+		availability, err := materialAvailability(ctx, material)
+		if err != nil {
+			return nil, err
+		}
+		if quantity.Milli > availability.Available.Milli {
+			return nil, fmt.Errorf("not enough available quantity for material %s (requested: %s, available: %s)", materialID, quantity, availability.Available)
+		}
+
+		reservationID, err := newID(ctx, "RESERVATION")
+		if err != nil {
+			return nil, err
+		}
+		reservation := Reservation{
+			ReservationID: reservationID,
+			OrderID:       orderID,
+			MaterialID:    materialID,
+			Quantity:      quantity,
+			Consumed:      Quantity{Unit: quantity.Unit},
+			Status:        reservationActive,
+			ReservedBy:    caller.MSPID,
+			ExpiresAt:     expiry.UTC().Format(time.RFC3339),
+			CreatedAt:     now.Format(time.RFC3339),
+			TxID:          ctx.GetStub().GetTxID(),
+		}
+		if err := saveReservation(ctx, &reservation); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	err = emitEvent(ctx, EventMaterialsReserved, eventAssetMaterial, orderID, ReservationEventPayload{OrderID: orderID, Reservations: reservations})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// releaseReservation : 예약을 해제 상태로 저장 (status는 RELEASED 또는 EXPIRED)
+func releaseReservation(ctx contractapi.TransactionContextInterface, reservation *Reservation, status string, reason string) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	reservation.Status = status
+	reservation.ReleasedAt = now.Format(time.RFC3339)
+	reservation.ReleaseReason = reason
+	return saveReservation(ctx, reservation)
+}
+
+// ReleaseReservation : 예약을 명시적으로 해제 (예약한 조직만)
+func (s *PublicContract) ReleaseReservation(ctx contractapi.TransactionContextInterface, reservationID string, reason string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "ReleaseReservation")
+	if err != nil {
+		return err
+	}
+
+	reservation, err := readReservation(ctx, reservationID)
+	if err != nil {
+		return err
+	}
+	if reservation.ReservedBy != caller.MSPID {
+		return fmt.Errorf("reservation %s belongs to %s", reservationID, reservation.ReservedBy)
+	}
+	if reservation.Status != reservationActive {
+		return fmt.Errorf("reservation %s is not active: %s", reservationID, reservation.Status)
+	}
+
+	err = releaseReservation(ctx, reservation, reservationReleased, strings.TrimSpace(reason))
+	if err != nil {
+		return err
+	}
+
+	return emitEvent(ctx, EventReservationReleased, eventAssetMaterial, reservation.MaterialID, ReservationEventPayload{OrderID: reservation.OrderID, Reservations: []Reservation{*reservation}})
+}
+
+// ReleaseExpiredReservations : 만료 시각이 지난 ACTIVE 예약을 EXPIRED로 정리하고 해제한 건수를 반환
+// 만료된 예약은 이 함수 호출 전에도 가용량 계산에서 제외된다.
+func (s *PublicContract) ReleaseExpiredReservations(ctx contractapi.TransactionContextInterface) (int, error) {
+	if _, err := authorize(ctx, "ReleaseExpiredReservations"); err != nil {
+		return 0, err
+	}
+
+	reservations, err := queryReservationsByIndex(ctx, indexReservationByStatus, reservationActive)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for i := range reservations {
+		reservation := &reservations[i]
+		if reservation.Status != reservationExpired {
+			continue
+		}
+
+		err = releaseReservation(ctx, reservation, reservationExpired, "expired")
+		if err != nil {
+			return released, err
+		}
+		err = emitEvent(ctx, EventReservationReleased, eventAssetMaterial, reservation.MaterialID, ReservationEventPayload{OrderID: reservation.OrderID, Reservations: []Reservation{*reservation}})
+		if err != nil {
+			return released, err
+		}
+		released++
+	}
+
+	return released, nil
+}
+
+// QueryReservation : 예약 조회
+func (s *PublicContract) QueryReservation(ctx contractapi.TransactionContextInterface, reservationID string) (*Reservation, error) {
+	return readReservation(ctx, reservationID)
+}
+
+// QueryReservationsByOrder : 생산 오더의 예약 목록
+func (s *PublicContract) QueryReservationsByOrder(ctx contractapi.TransactionContextInterface, orderID string) ([]Reservation, error) {
+	return queryReservationsByIndex(ctx, indexReservationByOrder, orderID)
+}
+
+// QueryMaterialAvailability : 로트의 보유량, 유효 예약량, 가용량과 예약 목록
+func (s *PublicContract) QueryMaterialAvailability(ctx contractapi.TransactionContextInterface, materialID string) (*MaterialAvailability, error) {
+	material, err := s.QueryMaterial(ctx, materialID)
+	if err != nil {
+		return nil, err
+	}
+
+	return materialAvailability(ctx, material)
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeGrade     = "grade"
+	docTypeRepurpose = "repurpose"
+
+	// 등급 평가 기록 키 (grade~배터리ID~시각~등급ID)
+	gradeObjectType = "grade"
+	// 재사용 전환 기록 키 (repurpose~배터리ID~시각~전환ID)
+	repurposeObjectType = "repurpose"
+
+	gradingPolicyConfigName = "gradingPolicy"
+)
+
+// 2차 사용 등급 (기준을 하나도 만족하지 못하면 C)
+const (
+	gradeA = "A"
+	gradeB = "B"
+	gradeC = "C"
+)
+
+// GradeCriteria : 등급을 받기 위한 최소 SOH, 최대 용량 감소율(%), 허용되는 최대 사고 심각도 (0이면 사고 이력이 없어야 함)
+type GradeCriteria struct {
+	Grade               string  `json:"grade"`
+	MinSOH              float64 `json:"minSOH"`
+	MaxCapacityFade     float64 `json:"maxCapacityFade"`
+	MaxAccidentSeverity int     `json:"maxAccidentSeverity"`
+}
+
+// GradingPolicy : 등급 기준 (높은 등급부터 순서대로 평가)과 2차 사용이 가능한 등급
+type GradingPolicy struct {
+	Criteria           []GradeCriteria `json:"criteria"`
+	RepurposableGrades []string        `json:"repurposableGrades"`
+}
+
+// defaultGradingPolicy : 설정이 없을 때 사용하는 기본 등급 기준 (C 등급은 재활용 대상)
+func defaultGradingPolicy() *GradingPolicy {
+	return &GradingPolicy{
+		Criteria: []GradeCriteria{
+			{Grade: gradeA, MinSOH: 80, MaxCapacityFade: 20, MaxAccidentSeverity: 1},
+			{Grade: gradeB, MinSOH: 65, MaxCapacityFade: 35, MaxAccidentSeverity: 3},
+		},
+		RepurposableGrades: []string{gradeA, gradeB},
+	}
+}
+
+func (p *GradingPolicy) validate() error {
+	if len(p.Criteria) == 0 {
+		return fmt.Errorf("grading policy must define at least one grade")
+	}
+	seen := make(map[string]bool)
+	for _, criteria := range p.Criteria {
+		if criteria.Grade != gradeA && criteria.Grade != gradeB {
+			return fmt.Errorf("grade criteria must be defined for %s or %s: %s", gradeA, gradeB, criteria.Grade)
+		}
+		if seen[criteria.Grade] {
+			return fmt.Errorf("duplicate grade criteria: %s", criteria.Grade)
+		}
+		if criteria.MinSOH < 0 || criteria.MinSOH > 100 {
+			return fmt.Errorf("minimum SOH must be between 0 and 100: %v", criteria.MinSOH)
+		}
+		if criteria.MaxCapacityFade < 0 || criteria.MaxCapacityFade > 100 {
+			return fmt.Errorf("maximum capacity fade must be between 0 and 100: %v", criteria.MaxCapacityFade)
+		}
+		if criteria.MaxAccidentSeverity < 0 {
+			return fmt.Errorf("maximum accident severity must not be negative: %d", criteria.MaxAccidentSeverity)
+		}
+		seen[criteria.Grade] = true
+	}
+	for _, grade := range p.RepurposableGrades {
+		if grade != gradeA && grade != gradeB && grade != gradeC {
+			return fmt.Errorf("unknown grade: %s", grade)
+		}
+	}
+	return nil
+}
+
+// grade : 측정값이 만족하는 가장 높은 등급
+func (p *GradingPolicy) grade(soh float64, capacityFade float64, maxAccidentSeverity int) string {
+	for _, criteria := range p.Criteria {
+		if soh >= criteria.MinSOH && capacityFade <= criteria.MaxCapacityFade && maxAccidentSeverity <= criteria.MaxAccidentSeverity {
+			return criteria.Grade
+		}
+	}
+	return gradeC
+}
+
+func (p *GradingPolicy) repurposable(grade string) bool {
+	return grade != "" && containsString(p.RepurposableGrades, grade)
+}
+
+// BatteryGrade : 분석 조직의 2차 사용 등급 평가 기록
+type BatteryGrade struct {
+	DocType             string  `json:"docType"`
+	GradeID             string  `json:"gradeID"`
+	BatteryID           string  `json:"batteryID"`
+	Grade               string  `json:"grade"`
+	SOH                 float64 `json:"soh"`
+	RatedCapacity       float64 `json:"ratedCapacity"`
+	MeasuredCapacity    float64 `json:"measuredCapacity"`
+	CapacityFade        float64 `json:"capacityFade"` // %
+	AccidentCount       int     `json:"accidentCount"`
+	MaxAccidentSeverity int     `json:"maxAccidentSeverity"`
+	Repurposable        bool    `json:"repurposable"`
+	GradedBy            string  `json:"gradedBy"`
+	TxID                string  `json:"txID"`
+	GradedAt            string  `json:"gradedAt"`
+}
+
+// RepurposeRecord : 2차 사용 전환 기록 (전환 이전 용도, 여권, 수명 정보를 보존)
+type RepurposeRecord struct {
+	DocType                    string  `json:"docType"`
+	RepurposeID                string  `json:"repurposeID"`
+	BatteryID                  string  `json:"batteryID"`
+	GradeID                    string  `json:"gradeID"`
+	Grade                      string  `json:"grade"`
+	PreviousCategory           string  `json:"previousCategory"`
+	Application                string  `json:"application"`
+	PredecessorPassportID      string  `json:"predecessorPassportID"`
+	SuccessorPassportID        string  `json:"successorPassportID"`
+	PreviousTotalLifeCycle     int     `json:"previousTotalLifeCycle"`
+	PreviousRemainingLifeCycle int     `json:"previousRemainingLifeCycle"`
+	TotalLifeCycle             int     `json:"totalLifeCycle"`
+	SOH                        float64 `json:"soh"`
+	Operator                   string  `json:"operator"` // 전환한 조직 (MSP)
+	TxID                       string  `json:"txID"`
+	RepurposedAt               string  `json:"repurposedAt"`
+}
+
+// readGradingPolicy : 원장에 저장된 등급 기준 (없으면 기본 기준)
+func readGradingPolicy(ctx contractapi.TransactionContextInterface) (*GradingPolicy, error) {
+	policyKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{gradingPolicyConfigName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	policyAsBytes, err := ctx.GetStub().GetState(policyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grading policy: %v", err)
+	}
+	if policyAsBytes == nil {
+		return defaultGradingPolicy(), nil
+	}
+
+	policy := new(GradingPolicy)
+	err = json.Unmarshal(policyAsBytes, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal grading policy: %v", err)
+	}
+
+	return policy, nil
+}
+
+// SetGradingPolicy : 2차 사용 등급 기준과 재사용 가능 등급을 설정
+func (s *PublicContract) SetGradingPolicy(ctx contractapi.TransactionContextInterface, policyJSON string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	_, err := authorize(ctx, "SetGradingPolicy")
+	if err != nil {
+		return err
+	}
+
+	var policy GradingPolicy
+	err = json.Unmarshal([]byte(policyJSON), &policy)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal grading policy: %v", err)
+	}
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	if policy.RepurposableGrades == nil {
+		policy.RepurposableGrades = []string{}
+	}
+
+	policyKey, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{gradingPolicyConfigName})
+	if err != nil {
+		return fmt.Errorf("failed to create config key: %v", err)
+	}
+
+	policyAsBytes, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal grading policy: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(policyKey, policyAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put grading policy: %v", err)
+	}
+
+	return emitEvent(ctx, EventPolicyUpdated, eventAssetConfig, gradingPolicyConfigName, PolicyUpdatedPayload{Policy: gradingPolicyConfigName, Value: policy})
+}
+
+// QueryGradingPolicy : 현재 적용 중인 등급 기준 조회
+func (s *PublicContract) QueryGradingPolicy(ctx contractapi.TransactionContextInterface) (*GradingPolicy, error) {
+	return readGradingPolicy(ctx)
+}
+
+// putBatteryRecord : 배터리별 기록을 시간 순으로 정렬되는 키에 저장 (objectType~배터리ID~시각~기록ID)
+func putBatteryRecord(ctx contractapi.TransactionContextInterface, objectType string, batteryID string, recordID string, record interface{}) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 키에 고정 길이 시각을 넣어 부분 키 조회 결과가 시간 순으로 정렬되도록 함
+	recordKey, err := ctx.GetStub().CreateCompositeKey(objectType, []string{batteryID, fmt.Sprintf("%020d", now.UnixNano()), recordID})
+	if err != nil {
+		return fmt.Errorf("failed to create %s key: %v", objectType, err)
+	}
+
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s record: %v", objectType, err)
+	}
+
+	err = ctx.GetStub().PutState(recordKey, recordAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store %s record: %v", objectType, err)
+	}
+
+	return nil
+}
+
+// GradeBattery : 분석 중인 배터리를 SOH, 측정 용량의 감소율, 사고 이력으로 A/B/C 등급 평가 (Analysis ORG)
+// 등급은 배터리에 기록되며, 다시 분석 상태가 되면 새로 평가해야 한다.
+func (s *PublicContract) GradeBattery(ctx contractapi.TransactionContextInterface, batteryID string, measuredCapacity float64) (*BatteryGrade, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "GradeBattery")
+	if err != nil {
+		return nil, err
+	}
+
+	battery, err := loadBattery(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireBatteryStatus(battery, "GRADE", statusUnderAnalysis)
+	if err != nil {
+		return nil, err
+	}
+
+	if measuredCapacity <= 0 {
+		return nil, fmt.Errorf("measured capacity must be greater than 0: %v", measuredCapacity)
+	}
+	if battery.Capacity <= 0 {
+		return nil, fmt.Errorf("battery %s has no rated capacity", batteryID)
+	}
+
+	// 정격 용량 대비 감소율 (측정 용량이 정격 이상이면 0)
+	capacityFade := math.Max(0, (battery.Capacity-measuredCapacity)/battery.Capacity*100)
+	capacityFade = math.Round(capacityFade*100) / 100
+
+	accidents, err := s.QueryAccidentRecords(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	maxSeverity := 0
+	for _, accident := range accidents {
+		if accident.Severity > maxSeverity {
+			maxSeverity = accident.Severity
+		}
+	}
+
+	// 사고 기록 도입 이전에 AccidentLogs에만 남은 사고도 이력에 포함
+	accidentCount := len(accidents) + len(battery.AccidentLogs)
+	if len(battery.AccidentLogs) > 0 {
+		accidentPolicy, err := readAccidentPolicy(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if legacySeverity := accidentPolicy.legacySeverity(); legacySeverity > maxSeverity {
+			maxSeverity = legacySeverity
+		}
+	}
+
+	policy, err := readGradingPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	gradeID, err := newID(ctx, "GRADE")
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	grade := &BatteryGrade{
+		DocType:             docTypeGrade,
+		GradeID:             gradeID,
+		BatteryID:           batteryID,
+		Grade:               policy.grade(battery.SOH, capacityFade, maxSeverity),
+		SOH:                 battery.SOH,
+		RatedCapacity:       battery.Capacity,
+		MeasuredCapacity:    measuredCapacity,
+		CapacityFade:        capacityFade,
+		AccidentCount:       accidentCount,
+		MaxAccidentSeverity: maxSeverity,
+		GradedBy:            caller.MSPID,
+		TxID:                ctx.GetStub().GetTxID(),
+		GradedAt:            now.Format(time.RFC3339),
+	}
+	grade.Repurposable = policy.repurposable(grade.Grade)
+
+	err = putBatteryRecord(ctx, gradeObjectType, batteryID, gradeID, grade)
+	if err != nil {
+		return nil, err
+	}
+
+	battery.Grade = grade.Grade
+	battery.GradeID = gradeID
+	err = s.saveBattery(ctx, battery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update battery: %v", err)
+	}
+
+	err = emitEvent(ctx, EventBatteryGraded, eventAssetBattery, batteryID, BatteryGradedPayload{Grade: grade, Battery: battery})
+	if err != nil {
+		return nil, err
+	}
+
+	return grade, nil
+}
+
+// RepurposeBattery : 2차 사용 승인된 배터리를 새 용도로 전환하여 다시 운행 상태로 (2차 사용 운영 조직, 소유자만)
+// repurposeJSON은 {"application": "STATIONARY_STORAGE", "totalLifeCycle": 3000} 형식이며,
+// 새 여권 ID를 발급하고 수명 주기를 새 용도의 기대 수명으로 초기화한다. 이전 기록은 같은 배터리 ID에 그대로 남는다.
+func (s *PublicContract) RepurposeBattery(ctx contractapi.TransactionContextInterface, batteryID string, repurposeJSON string) (*RepurposeRecord, error) {
+	var repurpose struct {
+		Application    string `json:"application"`
+		TotalLifeCycle int    `json:"totalLifeCycle"`
+	}
+	err := json.Unmarshal([]byte(repurposeJSON), &repurpose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal repurpose data: %v", err)
+	}
+	repurpose.Application = strings.TrimSpace(repurpose.Application)
+	if repurpose.Application == "" {
+		return nil, fmt.Errorf("application category is required")
+	}
+	if repurpose.TotalLifeCycle <= 0 {
+		return nil, fmt.Errorf("total life cycle for the new application must be greater than 0: %d", repurpose.TotalLifeCycle)
+	}
+
+	battery, err := loadBattery(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := readGradingPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.repurposable(battery.Grade) {
+		return nil, fmt.Errorf("battery %s is not graded for second life (grade: %s, repurposable grades: %s)", batteryID, battery.Grade, strings.Join(policy.RepurposableGrades, ", "))
+	}
+
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	repurposeID, err := newID(ctx, "REPURPOSE")
+	if err != nil {
+		return nil, err
+	}
+	successorPassportID, err := newID(ctx, "PASSPORT")
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &RepurposeRecord{
+		DocType:                    docTypeRepurpose,
+		RepurposeID:                repurposeID,
+		BatteryID:                  batteryID,
+		GradeID:                    battery.GradeID,
+		Grade:                      battery.Grade,
+		PreviousCategory:           battery.Category,
+		Application:                repurpose.Application,
+		PredecessorPassportID:      battery.PassportID,
+		SuccessorPassportID:        successorPassportID,
+		PreviousTotalLifeCycle:     battery.TotalLifeCycle,
+		PreviousRemainingLifeCycle: battery.RemainingLifeCycle,
+		TotalLifeCycle:             repurpose.TotalLifeCycle,
+		SOH:                        battery.SOH,
+		Operator:                   clientMSPID,
+		TxID:                       ctx.GetStub().GetTxID(),
+		RepurposedAt:               now.Format(time.RFC3339),
+	}
+
+	// 2차 사용 상태에서 운행 상태로 전이 (역할과 소유자 확인 포함)
+	from := battery.Status
+	err = transitionBattery(ctx, battery, actionRepurpose)
+	if err != nil {
+		return nil, err
+	}
+
+	battery.Category = repurpose.Application
+	battery.PredecessorPassportID = battery.PassportID
+	battery.PassportID = successorPassportID
+	battery.TotalLifeCycle = repurpose.TotalLifeCycle
+	battery.RemainingLifeCycle = repurpose.TotalLifeCycle
+
+	err = putBatteryRecord(ctx, repurposeObjectType, batteryID, repurposeID, record)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.saveBattery(ctx, battery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update battery: %v", err)
+	}
+
+	err = emitEvent(ctx, EventBatteryRepurposed, eventAssetBattery, batteryID, LifecycleEventPayload{
+		Action:  actionRepurpose,
+		From:    from,
+		To:      battery.Status,
+		Battery: battery,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// QueryBatteryGrades : 배터리의 등급 평가 기록을 시간 순으로 조회
+func (s *PublicContract) QueryBatteryGrades(ctx contractapi.TransactionContextInterface, batteryID string) ([]BatteryGrade, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(gradeObjectType, []string{batteryID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query battery grades: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	grades := []BatteryGrade{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var grade BatteryGrade
+		err = json.Unmarshal(queryResponse.Value, &grade)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal battery grade: %v", err)
+		}
+
+		grades = append(grades, grade)
+	}
+
+	return grades, nil
+}
+
+// QueryRepurposeHistory : 배터리의 2차 사용 전환 기록 (이전 여권과 후속 여권의 연결)을 시간 순으로 조회
+func (s *PublicContract) QueryRepurposeHistory(ctx contractapi.TransactionContextInterface, batteryID string) ([]RepurposeRecord, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(repurposeObjectType, []string{batteryID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repurpose history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	records := []RepurposeRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record RepurposeRecord
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal repurpose record: %v", err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
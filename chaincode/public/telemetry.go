@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"public/merkle"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeTelemetryAnchor = "telemetryAnchor"
+
+	// 텔레메트리 배치 고정 기록 키 (telemetryAnchor~배터리ID~고정ID)
+	telemetryAnchorObjectType = "telemetryAnchor"
+)
+
+// TelemetryStats : 배치 안에서 측정 항목 하나의 요약 통계
+type TelemetryStats struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// TelemetryAnchor : 오프체인 BMS 텔레메트리 배치의 Merkle 루트와 측정 구간, 레코드 수, 요약 통계
+type TelemetryAnchor struct {
+	DocType     string                    `json:"docType"`
+	AnchorID    string                    `json:"anchorID"`
+	BatteryID   string                    `json:"batteryID"`
+	Source      string                    `json:"source"` // 측정 장치 (BMS, 진단 장비 등, 생략 시 고정 조직)
+	MerkleRoot  string                    `json:"merkleRoot"`
+	WindowStart string                    `json:"windowStart"` // 구간 시작 (포함, RFC3339Nano)
+	WindowEnd   string                    `json:"windowEnd"`   // 구간 끝 (제외, RFC3339Nano)
+	RecordCount int                       `json:"recordCount"`
+	Stats       map[string]TelemetryStats `json:"stats"` // 측정 항목별 (soc, soh, voltage 등)
+	AnchoredBy  string                    `json:"anchoredBy"`
+	TxID        string                    `json:"txID"`
+	AnchoredAt  string                    `json:"anchoredAt"`
+}
+
+// TelemetryRecordVerification : 오프체인 레코드 한 건의 포함 증명 검증 결과
+type TelemetryRecordVerification struct {
+	BatteryID    string `json:"batteryID"`
+	AnchorID     string `json:"anchorID"`
+	MerkleRoot   string `json:"merkleRoot"`
+	ComputedRoot string `json:"computedRoot"`
+	RecordedAt   string `json:"recordedAt"`
+	InWindow     bool   `json:"inWindow"` // 레코드의 측정 시각이 고정된 구간 [windowStart, windowEnd) 안에 있는지
+	Matches      bool   `json:"matches"`  // 루트가 일치하고 구간 안의 레코드인지
+}
+
+func telemetryAnchorKey(ctx contractapi.TransactionContextInterface, batteryID string, anchorID string) (string, error) {
+	anchorKey, err := ctx.GetStub().CreateCompositeKey(telemetryAnchorObjectType, []string{batteryID, anchorID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create telemetry anchor key: %v", err)
+	}
+	return anchorKey, nil
+}
+
+// validateTelemetryStats : 항목 이름이 있고 min <= mean <= max인 유한한 값인지 확인
+func validateTelemetryStats(stats map[string]TelemetryStats) error {
+	for metric, stat := range stats {
+		if strings.TrimSpace(metric) == "" {
+			return fmt.Errorf("telemetry stats metric name must not be empty")
+		}
+		for _, value := range []float64{stat.Min, stat.Max, stat.Mean} {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return fmt.Errorf("telemetry stats for %s must be finite", metric)
+			}
+		}
+		if stat.Min > stat.Mean || stat.Mean > stat.Max {
+			return fmt.Errorf("telemetry stats for %s must satisfy min <= mean <= max: %v, %v, %v", metric, stat.Min, stat.Mean, stat.Max)
+		}
+	}
+	return nil
+}
+
+// AnchorTelemetryBatch : 오프체인에 보관하는 텔레메트리 배치의 Merkle 루트를 원장에 고정 (EV ORG는 소유 배터리, 정비 ORG는 정비 중인 배터리)
+// 루트는 merkle 패키지로 계산하며, 측정 구간은 [windowStart, windowEnd)로 보고 같은 측정 장치의 구간은 이미 고정된 구간과 겹칠 수 없다.
+func (s *PublicContract) AnchorTelemetryBatch(ctx contractapi.TransactionContextInterface, batteryID string, batchJSON string) (*TelemetryAnchor, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "AnchorTelemetryBatch")
+	if err != nil {
+		return nil, err
+	}
+
+	battery, err := readBatteryState(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if battery == nil {
+		return nil, fmt.Errorf("battery not found: %s", batteryID)
+	}
+	if battery.Status == statusDisassembled {
+		return nil, fmt.Errorf("battery %s is disassembled and cannot receive telemetry", batteryID)
+	}
+	if !(caller.hasAnyRole(roleTechnician) && battery.Status == statusUnderMaintenance) {
+		if err := requireBatteryOwner(caller, battery); err != nil {
+			return nil, err
+		}
+	}
+
+	var batch struct {
+		Source      string                    `json:"source"`
+		MerkleRoot  string                    `json:"merkleRoot"`
+		WindowStart string                    `json:"windowStart"`
+		WindowEnd   string                    `json:"windowEnd"`
+		RecordCount int                       `json:"recordCount"`
+		Stats       map[string]TelemetryStats `json:"stats"`
+	}
+	err = json.Unmarshal([]byte(batchJSON), &batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal telemetry batch: %v", err)
+	}
+
+	merkleRoot, err := hex.DecodeString(strings.ToLower(batch.MerkleRoot))
+	if err != nil || len(merkleRoot) != 32 {
+		return nil, fmt.Errorf("invalid merkle root %q: expected hex-encoded SHA-256", batch.MerkleRoot)
+	}
+	if batch.RecordCount <= 0 {
+		return nil, fmt.Errorf("record count must be greater than 0: %d", batch.RecordCount)
+	}
+	if err := validateTelemetryStats(batch.Stats); err != nil {
+		return nil, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	windowStart, err := time.Parse(time.RFC3339, batch.WindowStart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid windowStart %q: expected RFC3339", batch.WindowStart)
+	}
+	windowEnd, err := time.Parse(time.RFC3339, batch.WindowEnd)
+	if err != nil {
+		return nil, fmt.Errorf("invalid windowEnd %q: expected RFC3339", batch.WindowEnd)
+	}
+	if !windowEnd.After(windowStart) {
+		return nil, fmt.Errorf("windowEnd %s must be after windowStart %s", batch.WindowEnd, batch.WindowStart)
+	}
+	if windowEnd.After(now) {
+		return nil, fmt.Errorf("windowEnd %s is in the future", batch.WindowEnd)
+	}
+
+	source := strings.TrimSpace(batch.Source)
+	if source == "" {
+		source = caller.MSPID
+	}
+
+	// 같은 루트의 재고정과 같은 측정 장치의 구간 중복 거부
+	anchors, err := s.QueryTelemetryAnchors(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	for _, anchor := range anchors {
+		if anchor.MerkleRoot == hex.EncodeToString(merkleRoot) {
+			return nil, fmt.Errorf("merkle root is already anchored for battery %s: %s", batteryID, anchor.AnchorID)
+		}
+		if anchor.Source != source {
+			continue
+		}
+		// 반열린 구간이므로 이전 구간이 끝난 시각에 바로 이어지는 구간은 겹치지 않음
+		anchorStart, _ := time.Parse(time.RFC3339, anchor.WindowStart)
+		anchorEnd, _ := time.Parse(time.RFC3339, anchor.WindowEnd)
+		if windowStart.Before(anchorEnd) && anchorStart.Before(windowEnd) {
+			return nil, fmt.Errorf("telemetry window overlaps anchor %s of source %s (%s - %s)", anchor.AnchorID, source, anchor.WindowStart, anchor.WindowEnd)
+		}
+	}
+
+	anchorID, err := newID(ctx, "TELEMETRYBATCH")
+	if err != nil {
+		return nil, err
+	}
+	if batch.Stats == nil {
+		batch.Stats = map[string]TelemetryStats{}
+	}
+
+	anchor := &TelemetryAnchor{
+		DocType:     docTypeTelemetryAnchor,
+		AnchorID:    anchorID,
+		BatteryID:   batteryID,
+		Source:      source,
+		MerkleRoot:  hex.EncodeToString(merkleRoot),
+		WindowStart: windowStart.UTC().Format(time.RFC3339Nano),
+		WindowEnd:   windowEnd.UTC().Format(time.RFC3339Nano),
+		RecordCount: batch.RecordCount,
+		Stats:       batch.Stats,
+		AnchoredBy:  caller.MSPID,
+		TxID:        ctx.GetStub().GetTxID(),
+		AnchoredAt:  now.Format(time.RFC3339),
+	}
+
+	anchorKey, err := telemetryAnchorKey(ctx, batteryID, anchorID)
+	if err != nil {
+		return nil, err
+	}
+	anchorAsBytes, err := json.Marshal(anchor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal telemetry anchor: %v", err)
+	}
+	err = ctx.GetStub().PutState(anchorKey, anchorAsBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store telemetry anchor: %v", err)
+	}
+
+	err = emitEvent(ctx, EventTelemetryAnchored, eventAssetBattery, batteryID, TelemetryAnchoredPayload{Anchor: anchor})
+	if err != nil {
+		return nil, err
+	}
+
+	return anchor, nil
+}
+
+// QueryTelemetryAnchors : 배터리의 텔레메트리 배치 고정 기록을 측정 구간 순으로 조회
+func (s *PublicContract) QueryTelemetryAnchors(ctx contractapi.TransactionContextInterface, batteryID string) ([]TelemetryAnchor, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(telemetryAnchorObjectType, []string{batteryID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetry anchors: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	anchors := []TelemetryAnchor{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var anchor TelemetryAnchor
+		err = json.Unmarshal(queryResponse.Value, &anchor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal telemetry anchor: %v", err)
+		}
+
+		anchors = append(anchors, anchor)
+	}
+
+	// 소수 초 자릿수가 달라 문자열 순서가 시간 순이 아니므로 시각으로 비교
+	sort.Slice(anchors, func(i, j int) bool {
+		startI, _ := time.Parse(time.RFC3339, anchors[i].WindowStart)
+		startJ, _ := time.Parse(time.RFC3339, anchors[j].WindowStart)
+		if !startI.Equal(startJ) {
+			return startI.Before(startJ)
+		}
+		return anchors[i].AnchorID < anchors[j].AnchorID
+	})
+
+	return anchors, nil
+}
+
+// VerifyTelemetryRecord : 오프체인 레코드 한 건(보관한 바이트 그대로)이 고정된 배치에 포함되는지 포함 증명으로 검증
+// 증명의 트리 크기는 고정된 레코드 수와 같아야 하며, 레코드의 측정 시각(recordedAt)이 구간 안에 있어야 일치로 본다.
+func (s *PublicContract) VerifyTelemetryRecord(ctx contractapi.TransactionContextInterface, batteryID string, anchorID string, record string, proofJSON string) (*TelemetryRecordVerification, error) {
+	anchorKey, err := telemetryAnchorKey(ctx, batteryID, anchorID)
+	if err != nil {
+		return nil, err
+	}
+	anchorAsBytes, err := ctx.GetStub().GetState(anchorKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry anchor: %v", err)
+	}
+	if anchorAsBytes == nil {
+		return nil, fmt.Errorf("telemetry anchor not found for battery %s: %s", batteryID, anchorID)
+	}
+
+	var anchor TelemetryAnchor
+	err = json.Unmarshal(anchorAsBytes, &anchor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal telemetry anchor: %v", err)
+	}
+
+	var proof merkle.Proof
+	err = json.Unmarshal([]byte(proofJSON), &proof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal merkle proof: %v", err)
+	}
+	if proof.TreeSize != anchor.RecordCount {
+		return nil, fmt.Errorf("proof tree size %d does not match anchored record count %d", proof.TreeSize, anchor.RecordCount)
+	}
+
+	var reading TelemetryReading
+	err = json.Unmarshal([]byte(record), &reading)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal telemetry record: %v", err)
+	}
+	if reading.BatteryID != "" && reading.BatteryID != batteryID {
+		return nil, fmt.Errorf("telemetry record belongs to battery %s, not %s", reading.BatteryID, batteryID)
+	}
+	recordedAt, err := time.Parse(time.RFC3339, reading.RecordedAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recordedAt %q: expected RFC3339", reading.RecordedAt)
+	}
+
+	computedRoot, err := proof.ComputeRoot([]byte(record))
+	if err != nil {
+		return nil, err
+	}
+
+	windowStart, _ := time.Parse(time.RFC3339, anchor.WindowStart)
+	windowEnd, _ := time.Parse(time.RFC3339, anchor.WindowEnd)
+
+	verification := &TelemetryRecordVerification{
+		BatteryID:    batteryID,
+		AnchorID:     anchorID,
+		MerkleRoot:   anchor.MerkleRoot,
+		ComputedRoot: hex.EncodeToString(computedRoot),
+		RecordedAt:   reading.RecordedAt,
+		InWindow:     !recordedAt.Before(windowStart) && recordedAt.Before(windowEnd),
+	}
+	verification.Matches = verification.ComputedRoot == verification.MerkleRoot && verification.InWindow
+
+	return verification, nil
+}
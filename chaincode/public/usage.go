@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeUsage = "usage"
+
+	// 사용량 보고 기록 키 (usage~배터리ID~시각~보고ID)
+	usageObjectType = "usage"
+)
+
+// endOfLifeSOCE : 정격 수명 주기(TotalLifeCycle)를 모두 사용했을 때의 SOCE (%)
+// 사용한 주기에 비례해 100%에서 이 값까지 선형으로 감소한다고 본다.
+const endOfLifeSOCE = 80.0
+
+// UsageReport : 운행 조직이 보고한 누적 사용량 (주기 수, 에너지 처리량, 주행 거리)과 반영 결과
+type UsageReport struct {
+	DocType             string  `json:"docType"`
+	ReportID            string  `json:"reportID"`
+	BatteryID           string  `json:"batteryID"`
+	CycleCount          int     `json:"cycleCount"`          // 누적 충방전 주기 수
+	EnergyThroughputKWh float64 `json:"energyThroughputKWh"` // 누적 에너지 처리량
+	OdometerKm          float64 `json:"odometerKm"`          // 누적 주행 거리
+	ReportedAt          string  `json:"reportedAt"`          // 측정 시각 (BMS 기준)
+	CycleDelta          int     `json:"cycleDelta"`          // 직전 보고 이후 사용한 주기 수
+	RemainingLifeCycle  int     `json:"remainingLifeCycle"`  // 반영 후 잔여 수명
+	SOCE                float64 `json:"soce"`                // 반영 후 SOCE
+	ReportedBy          string  `json:"reportedBy"`          // 보고 조직 (MSP)
+	TxID                string  `json:"txID"`
+	RecordedAt          string  `json:"recordedAt"`
+}
+
+// ReportUsage : 운행 중인 배터리의 누적 사용량을 보고하여 잔여 수명과 SOCE를 갱신 (EV ORG, 소유자만)
+// 누적 값은 직전 보고보다 작아질 수 없으며, 생략한 에너지 처리량과 주행 거리는 직전 값을 유지한다.
+func (s *PublicContract) ReportUsage(ctx contractapi.TransactionContextInterface, batteryID string, usageJSON string) (*UsageReport, error) {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "ReportUsage")
+	if err != nil {
+		return nil, err
+	}
+
+	var usage struct {
+		CycleCount          *int     `json:"cycleCount"`
+		EnergyThroughputKWh *float64 `json:"energyThroughputKWh"`
+		OdometerKm          *float64 `json:"odometerKm"`
+		ReportedAt          string   `json:"reportedAt"`
+	}
+	err = json.Unmarshal([]byte(usageJSON), &usage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal usage report: %v", err)
+	}
+	if usage.CycleCount == nil {
+		return nil, fmt.Errorf("cycle count must be provided")
+	}
+
+	battery, err := loadBattery(ctx, batteryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireBatteryOwner(caller, battery); err != nil {
+		return nil, err
+	}
+	err = requireBatteryStatus(battery, "REPORT_USAGE", statusInService, statusUnderMaintenance)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 측정 시각이 없으면 트랜잭션 시각을 사용하며, 직전 보고보다 늦어야 함
+	reportedAt := now
+	if usage.ReportedAt != "" {
+		reportedAt, err = time.Parse(time.RFC3339, usage.ReportedAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reportedAt %q: expected RFC3339", usage.ReportedAt)
+		}
+		reportedAt = reportedAt.UTC()
+		if reportedAt.After(now) {
+			return nil, fmt.Errorf("reportedAt %s is in the future", usage.ReportedAt)
+		}
+	}
+	if battery.LastUsageReportedAt != "" {
+		lastReportedAt, err := time.Parse(time.RFC3339, battery.LastUsageReportedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse last usage report time: %v", err)
+		}
+		if !reportedAt.After(lastReportedAt) {
+			return nil, fmt.Errorf("usage report for battery %s must be later than the last report at %s", batteryID, battery.LastUsageReportedAt)
+		}
+	}
+
+	// 누적 값의 되돌림 거부
+	cycleCount := *usage.CycleCount
+	if cycleCount < battery.CycleCount {
+		return nil, fmt.Errorf("cycle count of battery %s cannot roll back: %d is less than the last reported %d", batteryID, cycleCount, battery.CycleCount)
+	}
+	energyThroughput := battery.EnergyThroughputKWh
+	if usage.EnergyThroughputKWh != nil {
+		if *usage.EnergyThroughputKWh < battery.EnergyThroughputKWh {
+			return nil, fmt.Errorf("energy throughput of battery %s cannot roll back: %v is less than the last reported %v", batteryID, *usage.EnergyThroughputKWh, battery.EnergyThroughputKWh)
+		}
+		energyThroughput = *usage.EnergyThroughputKWh
+	}
+	odometer := battery.OdometerKm
+	if usage.OdometerKm != nil {
+		if *usage.OdometerKm < battery.OdometerKm {
+			return nil, fmt.Errorf("odometer of battery %s cannot roll back: %v is less than the last reported %v", batteryID, *usage.OdometerKm, battery.OdometerKm)
+		}
+		odometer = *usage.OdometerKm
+	}
+
+	// 직전 보고 이후 사용한 주기만큼 잔여 수명과 SOCE를 차감
+	// (정비 입력이나 2차 사용 전환으로 바뀐 잔여 수명을 기준으로 이어서 차감한다)
+	cycleDelta := cycleCount - battery.CycleCount
+	battery.RemainingLifeCycle -= cycleDelta
+	if battery.RemainingLifeCycle < 0 {
+		battery.RemainingLifeCycle = 0
+	}
+	if battery.TotalLifeCycle > 0 {
+		fade := float64(cycleDelta) / float64(battery.TotalLifeCycle) * (100 - endOfLifeSOCE)
+		battery.SOCE = math.Max(0, math.Round((battery.SOCE-fade)*100)/100)
+	}
+
+	battery.CycleCount = cycleCount
+	battery.EnergyThroughputKWh = energyThroughput
+	battery.OdometerKm = odometer
+	battery.LastUsageReportedAt = reportedAt.Format(time.RFC3339)
+
+	reportID, err := newID(ctx, "USAGE")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UsageReport{
+		DocType:             docTypeUsage,
+		ReportID:            reportID,
+		BatteryID:           batteryID,
+		CycleCount:          cycleCount,
+		EnergyThroughputKWh: energyThroughput,
+		OdometerKm:          odometer,
+		ReportedAt:          battery.LastUsageReportedAt,
+		CycleDelta:          cycleDelta,
+		RemainingLifeCycle:  battery.RemainingLifeCycle,
+		SOCE:                battery.SOCE,
+		ReportedBy:          caller.MSPID,
+		TxID:                ctx.GetStub().GetTxID(),
+		RecordedAt:          now.Format(time.RFC3339),
+	}
+
+	err = putBatteryRecord(ctx, usageObjectType, batteryID, reportID, report)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.saveBattery(ctx, battery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update battery: %v", err)
+	}
+
+	err = emitEvent(ctx, EventUsageReported, eventAssetBattery, batteryID, UsageReportedPayload{Report: report, Battery: battery})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// QueryUsageSeries : 배터리의 사용량 보고 기록 전체를 시간 순으로 조회
+func (s *PublicContract) QueryUsageSeries(ctx contractapi.TransactionContextInterface, batteryID string) ([]UsageReport, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(usageObjectType, []string{batteryID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage series: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	reports := []UsageReport{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var report UsageReport
+		err = json.Unmarshal(queryResponse.Value, &report)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal usage report: %v", err)
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	docTypeVerification = "verification"
+
+	// 검증 기록 키 (verification~자산ID~검증ID) : 자산별 검증 이력 조회용
+	verificationObjectType = "verification"
+	// 검증 ID → 자산 ID 인덱스 (verification~id~검증ID~자산ID) : 폐기 시 검증 ID만으로 기록을 찾기 위함
+	indexVerificationByID = "verification~id"
+)
+
+// 검증 결과
+const (
+	verificationOutcomeVerified = "VERIFIED"
+	verificationOutcomeRejected = "REJECTED"
+)
+
+// 조회 시점의 검증 상태
+const (
+	verificationStatusValid   = "VALID"
+	verificationStatusExpired = "EXPIRED"
+	verificationStatusRevoked = "REVOKED"
+)
+
+// 자산의 검증 표시 (Verified 필드)
+const (
+	verifiedLabel    = "VERIFIED"
+	notVerifiedLabel = "NOT VERIFIED"
+	rejectedLabel    = "REJECTED"
+)
+
+// VerificationRequest : 검증 기관이 제출하는 검증 내용
+type VerificationRequest struct {
+	Method         string   `json:"method"`
+	EvidenceHashes []string `json:"evidenceHashes"`
+	Scope          string   `json:"scope"`
+	ValidFrom      string   `json:"validFrom"` // 생략하면 트랜잭션 시각
+	ValidUntil     string   `json:"validUntil"`
+}
+
+// Verification : 자산(원자재 로트, 배터리) 검증 기록
+// Status는 저장 값이 아니라 조회 시점에 유효기간과 폐기 여부로 평가한 값이다.
+type Verification struct {
+	DocType          string   `json:"docType"`
+	VerificationID   string   `json:"verificationID"`
+	AssetType        string   `json:"assetType"`
+	AssetID          string   `json:"assetID"`
+	Outcome          string   `json:"outcome"`
+	VerifierMSP      string   `json:"verifierMSP"`
+	VerifierID       string   `json:"verifierID"`   // 클라이언트 인증서 식별자
+	VerifierName     string   `json:"verifierName"` // 클라이언트 인증서 CN
+	Method           string   `json:"method"`
+	EvidenceHashes   []string `json:"evidenceHashes"`
+	Scope            string   `json:"scope"`
+	ValidFrom        string   `json:"validFrom"`
+	ValidUntil       string   `json:"validUntil"`
+	Revoked          bool     `json:"revoked"`
+	RevokedBy        string   `json:"revokedBy"`
+	RevokedAt        string   `json:"revokedAt"`
+	RevocationReason string   `json:"revocationReason"`
+	Status           string   `json:"status"`
+	TxID             string   `json:"txID"`
+	CreatedAt        string   `json:"createdAt"`
+}
+
+// statusAt : 기준 시각의 검증 상태
+func (v *Verification) statusAt(now time.Time) string {
+	if v.Revoked {
+		return verificationStatusRevoked
+	}
+	if until, err := time.Parse(time.RFC3339, v.ValidUntil); err == nil && !now.Before(until) {
+		return verificationStatusExpired
+	}
+	return verificationStatusValid
+}
+
+// effectiveVerified : 자산에 저장된 검증 표시를 기준 시각으로 평가 (유효기간이 지나면 미검증)
+// 검증 기록 없이 남은 표시(verifiedUntil이 빈 값, 검증 기록 도입 이전 문서)는 미검증으로 본다.
+func effectiveVerified(verified string, verifiedUntil string, now time.Time) string {
+	if verifiedUntil == "" || verified == notVerifiedLabel {
+		return notVerifiedLabel
+	}
+	if until, err := time.Parse(time.RFC3339, verifiedUntil); err == nil && !now.Before(until) {
+		return notVerifiedLabel
+	}
+	return verified
+}
+
+// newVerification : 검증 요청을 해석하고 호출자 인증서로 검증자 정보를 채움
+// attachedHashes는 요청과 별개로 검증에 포함되는 증빙 (원자재의 원산지 인증서 해시 등)
+func newVerification(ctx contractapi.TransactionContextInterface, assetType string, assetID string, outcome string, requestJSON string, attachedHashes ...string) (*Verification, error) {
+	var request VerificationRequest
+	err := json.Unmarshal([]byte(requestJSON), &request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal verification: %v", err)
+	}
+	if strings.TrimSpace(request.Method) == "" {
+		return nil, fmt.Errorf("verification method must not be empty")
+	}
+	if strings.TrimSpace(request.Scope) == "" {
+		return nil, fmt.Errorf("verification scope must not be empty")
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	validFrom := now
+	if request.ValidFrom != "" {
+		validFrom, err = parseRecordDate(request.ValidFrom)
+		if err != nil {
+			return nil, err
+		}
+		if validFrom.After(now) {
+			return nil, fmt.Errorf("verification validFrom must not be in the future")
+		}
+	}
+	validUntil, err := parseRecordDate(request.ValidUntil)
+	if err != nil {
+		return nil, fmt.Errorf("verification validUntil is required: %v", err)
+	}
+	if !validUntil.After(now) {
+		return nil, fmt.Errorf("verification validUntil must be later than the transaction time")
+	}
+
+	evidenceHashes := []string{}
+	for _, hash := range append(attachedHashes, request.EvidenceHashes...) {
+		if hash == "" {
+			continue
+		}
+		normalized, err := validateEvidenceHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		if !containsString(evidenceHashes, normalized) {
+			evidenceHashes = append(evidenceHashes, normalized)
+		}
+	}
+	if len(evidenceHashes) == 0 {
+		return nil, fmt.Errorf("verification requires at least one evidence hash")
+	}
+
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client certificate: %v", err)
+	}
+
+	verificationID, err := newID(ctx, "VERIFICATION")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verification{
+		DocType:        docTypeVerification,
+		VerificationID: verificationID,
+		AssetType:      assetType,
+		AssetID:        assetID,
+		Outcome:        outcome,
+		VerifierMSP:    clientMSPID,
+		VerifierID:     clientID,
+		VerifierName:   cert.Subject.CommonName,
+		Method:         strings.TrimSpace(request.Method),
+		EvidenceHashes: evidenceHashes,
+		Scope:          strings.TrimSpace(request.Scope),
+		ValidFrom:      validFrom.UTC().Format(time.RFC3339),
+		ValidUntil:     validUntil.UTC().Format(time.RFC3339),
+		Status:         verificationStatusValid,
+		TxID:           ctx.GetStub().GetTxID(),
+		CreatedAt:      now.Format(time.RFC3339),
+	}, nil
+}
+
+// saveVerification : 검증 기록과 검증 ID 인덱스 저장
+func saveVerification(ctx contractapi.TransactionContextInterface, verification *Verification) error {
+	recordKey, err := ctx.GetStub().CreateCompositeKey(verificationObjectType, []string{verification.AssetID, verification.VerificationID})
+	if err != nil {
+		return fmt.Errorf("failed to create verification key: %v", err)
+	}
+	recordAsBytes, err := json.Marshal(verification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal verification: %v", err)
+	}
+	err = ctx.GetStub().PutState(recordKey, recordAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put verification: %v", err)
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(indexVerificationByID, []string{verification.VerificationID, verification.AssetID})
+	if err != nil {
+		return fmt.Errorf("failed to create verification index key: %v", err)
+	}
+	return ctx.GetStub().PutState(indexKey, indexValue)
+}
+
+// readVerification : 검증 ID로 검증 기록 조회 (상태는 트랜잭션 시각 기준으로 평가)
+func readVerification(ctx contractapi.TransactionContextInterface, verificationID string) (*Verification, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(indexVerificationByID, []string{verificationID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query verification index: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	if !resultsIterator.HasNext() {
+		return nil, fmt.Errorf("verification not found: %s", verificationID)
+	}
+	queryResponse, err := resultsIterator.Next()
+	if err != nil {
+		return nil, err
+	}
+	_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split verification index key: %v", err)
+	}
+
+	recordKey, err := ctx.GetStub().CreateCompositeKey(verificationObjectType, []string{keyParts[1], verificationID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verification key: %v", err)
+	}
+	recordAsBytes, err := ctx.GetStub().GetState(recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read verification: %v", err)
+	}
+	if recordAsBytes == nil {
+		return nil, fmt.Errorf("verification not found: %s", verificationID)
+	}
+
+	verification := new(Verification)
+	err = json.Unmarshal(recordAsBytes, verification)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal verification: %v", err)
+	}
+	if verification.EvidenceHashes == nil {
+		verification.EvidenceHashes = []string{}
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	verification.Status = verification.statusAt(now)
+
+	return verification, nil
+}
+
+// RevokeVerification : 검증을 사유와 함께 폐기 (자산의 현재 검증이면 자산은 미검증으로 표시)
+func (s *PublicContract) RevokeVerification(ctx contractapi.TransactionContextInterface, verificationID string, reason string) error {
+
+	// 함수별 역할 표에 따라 호출 권한 확인
+	caller, err := authorize(ctx, "RevokeVerification")
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("revocation reason must not be empty")
+	}
+
+	verification, err := readVerification(ctx, verificationID)
+	if err != nil {
+		return err
+	}
+	if verification.Revoked {
+		return fmt.Errorf("verification %s has already been revoked", verificationID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	verification.Revoked = true
+	verification.RevokedBy = caller.MSPID
+	verification.RevokedAt = now.Format(time.RFC3339)
+	verification.RevocationReason = strings.TrimSpace(reason)
+	verification.Status = verificationStatusRevoked
+
+	err = saveVerification(ctx, verification)
+	if err != nil {
+		return err
+	}
+
+	switch verification.AssetType {
+	case eventAssetMaterial:
+		// 분할·병합으로 검증을 이어받은 하위 로트도 함께 미검증으로 표시
+		derived, err := derivedLots(ctx, verification.AssetID)
+		if err != nil {
+			return err
+		}
+		for _, materialID := range append([]string{verification.AssetID}, derived...) {
+			material, err := readStoredMaterial(ctx, materialID)
+			if err != nil {
+				return err
+			}
+			if material != nil && material.VerificationID == verificationID {
+				material.Verified = notVerifiedLabel
+				if err := s.saveMaterial(ctx, material); err != nil {
+					return fmt.Errorf("failed to update material: %v", err)
+				}
+			}
+		}
+	case eventAssetBattery:
+		battery, err := readUpgradedBattery(ctx, verification.AssetID)
+		if err != nil {
+			return err
+		}
+		if battery != nil && battery.VerificationID == verificationID {
+			battery.Verified = notVerifiedLabel
+			if err := s.saveBattery(ctx, battery); err != nil {
+				return fmt.Errorf("failed to update battery: %v", err)
+			}
+		}
+	}
+
+	return emitEvent(ctx, EventVerificationRevoked, verification.AssetType, verification.AssetID, VerificationEventPayload{Verification: verification})
+}
+
+// QueryVerification : 검증 기록 조회 (조회 시점 기준 상태 포함)
+func (s *PublicContract) QueryVerification(ctx contractapi.TransactionContextInterface, verificationID string) (*Verification, error) {
+	return readVerification(ctx, verificationID)
+}
+
+// QueryVerificationHistory : 자산의 검증 이력 조회 (생성 시각 순, 조회 시점 기준 상태 포함)
+func (s *PublicContract) QueryVerificationHistory(ctx contractapi.TransactionContextInterface, assetID string) ([]Verification, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(verificationObjectType, []string{assetID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query verifications: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	verifications := []Verification{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var verification Verification
+		err = json.Unmarshal(queryResponse.Value, &verification)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal verification: %v", err)
+		}
+		if verification.EvidenceHashes == nil {
+			verification.EvidenceHashes = []string{}
+		}
+		verification.Status = verification.statusAt(now)
+		verifications = append(verifications, verification)
+	}
+
+	sort.SliceStable(verifications, func(i, j int) bool {
+		if verifications[i].CreatedAt != verifications[j].CreatedAt {
+			return verifications[i].CreatedAt < verifications[j].CreatedAt
+		}
+		return verifications[i].VerificationID < verifications[j].VerificationID
+	})
+
+	return verifications, nil
+}